@@ -0,0 +1,110 @@
+package redlog
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// AccessLog returns a middleware that logs the requests handled by next,
+// such as:
+//
+//	"GET /metrics" 200 1.2ms 10.0.0.1:5000
+//
+// Requests are logged as verbose, and those with an error status as
+// notices. The "method", "path", "status", "duration", and "remote" keys
+// are passed to the Encoder and Formatter options.
+func (l *Logger) AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := l.now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw.wrap(), r)
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := LevelVerbose
+		if status >= 400 {
+			level = LevelNotice
+		}
+		if !l.enabled(level) || !l.sampled(level, "") {
+			return
+		}
+		d := l.now().Sub(start)
+		path := r.URL.RequestURI()
+		msg := strconv.Quote(r.Method+" "+path) + " " +
+			strconv.Itoa(status) + " " + d.String() + " " + r.RemoteAddr
+		write(false, l, l.App(), level, "", []interface{}{msg},
+			[]interface{}{"method", r.Method, "path", path, "status", status,
+				"duration", d, "remote", r.RemoteAddr})
+	})
+}
+
+// statusWriter records the status of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// wrap returns the writer with the http.Flusher and http.Hijacker
+// interfaces of the response writer, so handlers that check for them see
+// the same ones.
+func (w *statusWriter) wrap() http.ResponseWriter {
+	_, flusher := w.ResponseWriter.(http.Flusher)
+	_, hijacker := w.ResponseWriter.(http.Hijacker)
+	switch {
+	case flusher && hijacker:
+		return flushHijackWriter{w}
+	case flusher:
+		return flushWriter{w}
+	case hijacker:
+		return hijackWriter{w}
+	}
+	return w
+}
+
+type flushWriter struct{ *statusWriter }
+
+func (w flushWriter) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+type hijackWriter struct{ *statusWriter }
+
+// Hijack takes over the connection, which is logged with the status 101
+// unless a status was written.
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+type flushHijackWriter struct{ *statusWriter }
+
+func (w flushHijackWriter) Flush() { flushWriter(w).Flush() }
+
+func (w flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijackWriter(w).Hijack()
+}
+
+// Unwrap returns the response writer, for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,93 @@
+package redlog
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLog(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	l, lines := capture(&Options{Level: LevelVerbose,
+		Now: func() time.Time { return now }})
+	h := l.AccessLog(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		now = now.Add(1500 * time.Microsecond)
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/stream":
+			w.(http.Flusher).Flush()
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	for _, path := range []string{"/metrics?x=1", "/missing", "/stream"} {
+		r := httptest.NewRequest("GET", path, nil)
+		r.RemoteAddr = "10.0.0.1:5000"
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	got := lines()
+	want := []string{
+		`- "GET /metrics?x=1" 200 1.5ms 10.0.0.1:5000`,
+		`* "GET /missing" 404 1.5ms 10.0.0.1:5000`,
+		`- "GET /stream" 200 1.5ms 10.0.0.1:5000`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", strings.Join(got, "\n"))
+	}
+
+	// requests below the level are not logged
+	l.SetLevel(LevelNotice)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got := lines(); len(got) != 0 {
+		t.Fatalf("unexpected output %q", got)
+	}
+}
+
+// hijackRecorder is a response writer that can be hijacked, but not
+// flushed.
+type hijackRecorder struct {
+	http.ResponseWriter
+}
+
+func (hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c1, c2 := net.Pipe()
+	c2.Close()
+	return c1, nil, nil
+}
+
+func TestAccessLogInterfaces(t *testing.T) {
+	l, lines := capture(&Options{Level: LevelVerbose})
+	var flusher, hijacker bool
+	h := l.AccessLog(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		_, flusher = w.(http.Flusher)
+		hj, ok := w.(http.Hijacker)
+		if hijacker = ok; ok {
+			conn, _, err := hj.Hijack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Close()
+		}
+	}))
+	h.ServeHTTP(hijackRecorder{httptest.NewRecorder()},
+		httptest.NewRequest("GET", "/ws", nil))
+	if flusher || !hijacker {
+		t.Fatalf("unexpected interfaces %v %v", flusher, hijacker)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !flusher || hijacker {
+		t.Fatalf("unexpected interfaces %v %v", flusher, hijacker)
+	}
+	got := lines()
+	if len(got) != 2 || !strings.HasPrefix(got[0], `- "GET /ws" 101 `) {
+		t.Fatalf("unexpected output %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+package redlog
+
+// App characters of Redis, for the App option, SetApp, and As.
+const (
+	AppMaster   = 'M'
+	AppReplica  = 'S'
+	AppChild    = 'C' // a forked process, such as for saving
+	AppSentinel = 'X'
+)
+
+// As returns a view of the logger that logs with the app character, such
+// as AppChild, instead of the logger's own. Views are cached, so As does not
+// allocate after the first call for an app. Characters other than
+// printable ASCII, and the space, return the logger itself.
+func (l *Logger) As(app byte) *Logger {
+	if app <= ' ' || app > '~' {
+		return l
+	}
+	if v, ok := l.views.Load(app); ok {
+		return v.(*Logger)
+	}
+	view := l.clone()
+	view.app = app
+	v, _ := l.views.LoadOrStore(app, view)
+	return v.(*Logger)
+}
@@ -0,0 +1,52 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, nil)
+	l.pid = 1234
+	c := l.As('C')
+	if c != l.As('C') || c == l.As('S') {
+		t.Fatal("expected the view to be cached")
+	}
+	for _, app := range []byte{0, ' ', '\n', 0x7f, 0xff} {
+		if l.As(app) != l {
+			t.Fatalf("expected the logger for %q", app)
+		}
+	}
+	l.Noticef("main")
+	c.Noticef("child")
+	c.WithModule("m").WithLabel("x").Warningf("labeled")
+	l.Noticef("main again")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "1234:M ") ||
+		!strings.HasPrefix(lines[1], "1234:C ") ||
+		!strings.HasPrefix(lines[2], "1234:C[x] ") ||
+		!strings.HasPrefix(lines[3], "1234:M ") {
+		t.Fatalf("unexpected output %q", lines)
+	}
+	if c.App() != 'C' || l.App() != 'M' {
+		t.Fatal("unexpected app")
+	}
+	if n := testing.AllocsPerRun(100, func() { l.As('C') }); n != 0 {
+		t.Fatalf("expected no allocations, got %v", n)
+	}
+}
+
+func TestAppSentinel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{App: AppSentinel, Color: ColorAlways})
+	l.pid = 1234
+	l.Noticef("+monitor master mymaster 127.0.0.1 6379 quorum 2")
+	l.As(AppChild).Noticef("child")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "\x1b[34m1234:X") ||
+		!strings.HasPrefix(lines[1], "\x1b[36m1234:C") {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+}
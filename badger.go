@@ -0,0 +1,36 @@
+package redlog
+
+// BadgerLogger logs the messages of the badger database. It implements
+// badger.Logger, without depending on badger, for the Logger option:
+//
+//	opts := badger.DefaultOptions(dir).WithLogger(redlog.NewBadgerLogger(l))
+//
+// The levels are those of BadgerFilter, with errors at the error level.
+type BadgerLogger struct {
+	l *Logger
+}
+
+// NewBadgerLogger returns a BadgerLogger that logs to l.
+func NewBadgerLogger(l *Logger) *BadgerLogger {
+	return &BadgerLogger{l}
+}
+
+// Errorf logs at the error level.
+func (b *BadgerLogger) Errorf(format string, args ...interface{}) {
+	b.l.Errorf(format, args...)
+}
+
+// Warningf logs at the warning level.
+func (b *BadgerLogger) Warningf(format string, args ...interface{}) {
+	b.l.Warningf(format, args...)
+}
+
+// Infof logs at the notice level.
+func (b *BadgerLogger) Infof(format string, args ...interface{}) {
+	b.l.Noticef(format, args...)
+}
+
+// Debugf logs at the debug level.
+func (b *BadgerLogger) Debugf(format string, args ...interface{}) {
+	b.l.Debugf(format, args...)
+}
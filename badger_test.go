@@ -0,0 +1,36 @@
+package redlog
+
+import (
+	"strings"
+	"testing"
+)
+
+// badgerLogger is badger.Logger.
+type badgerLogger interface {
+	Errorf(string, ...interface{})
+	Warningf(string, ...interface{})
+	Infof(string, ...interface{})
+	Debugf(string, ...interface{})
+}
+
+func TestBadgerLogger(t *testing.T) {
+	l, lines := capture(&Options{Level: LevelNotice})
+	var b badgerLogger = NewBadgerLogger(l)
+	b.Debugf("hidden\n")
+	b.Infof("All %d tables opened in %s\n", 0, "0s")
+	b.Warningf("Truncate %s\n", "needed")
+	b.Errorf("Failed: %v\n", "eof")
+
+	got := lines()
+	want := []string{
+		`* All 0 tables opened in 0s`,
+		`# Truncate needed`,
+		`# Failed: eof`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", strings.Join(got, "\n"))
+	}
+	if l.Stats().Error != 1 {
+		t.Fatalf("expected one error, got %+v", l.Stats())
+	}
+}
@@ -0,0 +1,62 @@
+package redlog
+
+import "sync/atomic"
+
+// queue writes a complete line, or several, to the primary writer. When
+// another goroutine is already writing, the lines are left for it to
+// write with its next call, and the caller waits only while more than
+// MaxBatchBytes are queued. It's called with the mutex held, which is
+// released while writing.
+func (l *Logger) queue(p []byte) {
+	l.pend = append(l.pend, p...)
+	l.ends = append(l.ends, len(l.pend))
+	if l.flushing {
+		for l.flushing && len(l.pend) > l.maxBatch {
+			l.flushed.Wait()
+		}
+		return
+	}
+	l.flushing = true
+	for len(l.pend) > 0 {
+		// take the lines that fit, or at least one
+		lines := 1
+		for lines < len(l.ends) && l.ends[lines] <= l.maxBatch {
+			lines++
+		}
+		n := l.ends[lines-1]
+		batch := l.pend[:n]
+		l.pend, l.spare = append(l.spare[:0], l.pend[n:]...), l.pend
+		k := copy(l.ends, l.ends[lines:])
+		l.ends = l.ends[:k]
+		for i := range l.ends {
+			l.ends[i] -= n
+		}
+		l.flushed.Broadcast()
+		l.mu.Unlock()
+		err := l.writeBatch(batch)
+		l.mu.Lock()
+		atomic.AddUint64(&l.writes, 1)
+		atomic.AddUint64(&l.written, uint64(lines))
+		if err != nil {
+			atomic.AddUint64(&l.dropped, uint64(lines))
+		}
+	}
+	l.flushing = false
+	l.flushed.Broadcast()
+}
+
+// Flush waits for the lines that are being written by other goroutines,
+// such as for the Sync method of other logging libraries.
+func (l *Logger) Flush() {
+	l.mu.Lock()
+	l.waitFlush()
+	l.mu.Unlock()
+}
+
+// waitFlush waits for the queued lines to be written. It's called with
+// the mutex held.
+func (l *Logger) waitFlush() {
+	for l.flushing {
+		l.flushed.Wait()
+	}
+}
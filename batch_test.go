@@ -0,0 +1,109 @@
+package redlog
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowWriter records the calls to Write, which take a while.
+type slowWriter struct {
+	mu    sync.Mutex
+	delay time.Duration
+	calls [][]byte
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	w.mu.Lock()
+	w.calls = append(w.calls, append([]byte(nil), p...))
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+func TestBatching(t *testing.T) {
+	w := &slowWriter{delay: time.Millisecond}
+	l := New(w, &Options{Level: LevelNotice, MaxBatchBytes: 500})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				l.Noticef("goroutine %d line %02d", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var out bytes.Buffer
+	for _, p := range w.calls {
+		if len(p) > 500 && bytes.Count(p, []byte("\n")) > 1 {
+			t.Fatalf("batch of %d bytes", len(p))
+		}
+		if p[len(p)-1] != '\n' {
+			t.Fatalf("split line in %q", p)
+		}
+		out.Write(p)
+	}
+	// each goroutine's lines are in order, and none are missing
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	next := make(map[int]int)
+	for _, line := range lines {
+		var i, j int
+		msg := line[strings.Index(line, " * ")+3:]
+		if _, err := fmt.Sscanf(msg, "goroutine %d line %d", &i, &j); err != nil {
+			t.Fatalf("unexpected line %q", line)
+		}
+		if j != next[i] {
+			t.Fatalf("expected line %d of goroutine %d, got %d", next[i], i, j)
+		}
+		next[i]++
+	}
+	if len(lines) != 400 {
+		t.Fatalf("expected 400 lines, got %d", len(lines))
+	}
+	st := l.Stats()
+	if st.WrittenLines != 400 || st.Writes != uint64(len(w.calls)) ||
+		st.Writes >= 400 || st.LinesPerWrite() <= 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestBatchingIdentical(t *testing.T) {
+	// the batched output matches unbatched output, line for line
+	run := func(maxBatch int, delay time.Duration) []string {
+		w := &slowWriter{delay: delay}
+		l := New(w, &Options{
+			Level:         LevelNotice,
+			MaxBatchBytes: maxBatch,
+			Now:           func() time.Time { return benchTime },
+		})
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					l.Noticef("%d %s", i, strings.Repeat("x", j))
+				}
+			}(i)
+		}
+		wg.Wait()
+		var lines []string
+		for _, p := range w.calls {
+			lines = append(lines, strings.SplitAfter(string(p), "\n")...)
+		}
+		sort.Strings(lines)
+		return lines
+	}
+	a := run(1, 0)
+	b := run(64*1024, time.Millisecond)
+	if strings.Join(a, "") != strings.Join(b, "") {
+		t.Fatal("batched output differs")
+	}
+}
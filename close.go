@@ -0,0 +1,43 @@
+package redlog
+
+import "sync/atomic"
+
+// Close shuts down the logger. It waits for queued lines to be written,
+// and closes the writers that the logger owns, such as the file opened by
+// OpenFile. Writers passed to New are not closed.
+//
+// Lines logged after Close are discarded. Close may be called more than
+// once, and concurrently with logging.
+func (l *Logger) Close() error {
+	if r := l.repeats; r != nil && !l.isClosed() {
+		r.mu.Lock()
+		l.writeRepeats()
+		r.mu.Unlock()
+	}
+	l.mu.Lock()
+	if l.isClosed() {
+		l.mu.Unlock()
+		return nil
+	}
+	atomic.StoreUint32(&l.closed, 1)
+	l.waitFlush()
+	owned := l.owned
+	l.owned = nil
+	tw := l.tw
+	l.mu.Unlock()
+
+	var err error
+	for _, c := range owned {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if tw != nil {
+		tw.close()
+	}
+	return err
+}
+
+func (c *core) isClosed() bool {
+	return atomic.LoadUint32(&c.closed) == 1
+}
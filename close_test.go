@@ -0,0 +1,82 @@
+package redlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+func TestClose(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{WriteTimeout: time.Second})
+	gl := l.GoLogger()
+	gl.Printf("from go")
+	l.Noticef("before")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	l.Noticef("after")
+	gl.Printf("after")
+	l.GoLogger().Printf("after")
+	if out := buf.String(); !strings.Contains(out, " * before\n") ||
+		strings.Contains(out, "after") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestCloseFile(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, err := OpenFile(path, &Options{
+		Level:           LevelNotice,
+		MaxSize:         100,
+		MaxBackups:      2,
+		CompressBackups: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		l.Noticef("line %d", i)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	l.Noticef("after")
+	if strings.Contains(readFile(t, path), "after") {
+		t.Fatal("expected no output after Close")
+	}
+}
+
+func TestCloseConcurrent(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	l := New(ioutil.Discard, &Options{WriteTimeout: time.Second})
+	gl := l.GoLogger()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				l.Noticef("hello")
+				gl.Printf("hello")
+			}
+		}()
+	}
+	l.Close()
+	l.Close()
+	wg.Wait()
+}
@@ -0,0 +1,59 @@
+package redlog
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+)
+
+// contextKey is the type of the keys of the values of a context.
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	fieldsKey
+)
+
+var (
+	discardOnce sync.Once
+	discard     *Logger
+)
+
+// NewContext returns a copy of ctx that carries the logger, for
+// FromContext.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the logger of NewContext with the pairs of
+// ContextWith, as with WithContext. A context without a logger returns a
+// logger that discards its lines, so it's always safe to use.
+func FromContext(ctx context.Context) *Logger {
+	l, _ := ctx.Value(loggerKey).(*Logger)
+	if l == nil {
+		discardOnce.Do(func() {
+			discard = New(ioutil.Discard, &Options{Level: LevelWarning})
+		})
+		l = discard
+	}
+	return l.WithContext(ctx)
+}
+
+// ContextWith returns a copy of ctx that carries the key value pairs,
+// after those already in ctx, such as the id of a request. They are
+// added to the lines of WithContext and FromContext.
+func ContextWith(ctx context.Context, kvs ...interface{}) context.Context {
+	fields, _ := ctx.Value(fieldsKey).([]interface{})
+	fields = append(fields[:len(fields):len(fields)], kvs...)
+	return context.WithValue(ctx, fieldsKey, fields)
+}
+
+// WithContext returns a logger with the key value pairs of ContextWith,
+// as with With, or the logger itself when ctx has none.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	fields, _ := ctx.Value(fieldsKey).([]interface{})
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
@@ -0,0 +1,35 @@
+package redlog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	l, lines := capture(&Options{Level: LevelNotice})
+	ctx := context.Background()
+	if FromContext(ctx) == nil || l.WithContext(ctx) != l {
+		t.Fatal("unexpected loggers")
+	}
+	FromContext(ctx).Warningf("discarded")
+	ctx = NewContext(ctx, l)
+	if FromContext(ctx) != l {
+		t.Fatal("expected the logger of the context")
+	}
+	req := ContextWith(ctx, "request", 7)
+	user := ContextWith(req, "user", "ann")
+	FromContext(req).Noticef("started")
+	FromContext(user).Noticef("authorized")
+	l.WithContext(user).With("status", 200).Noticef("done")
+
+	got := lines()
+	want := []string{
+		`* started request=7`,
+		`* authorized request=7 user=ann`,
+		`* done request=7 user=ann status=200`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,190 @@
+package redlog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record is a single log entry. Errors have the level LevelError.
+type Record struct {
+	Pid   int
+	App   byte
+	Time  time.Time
+	Level int
+	Msg   string
+	KVs   []interface{} // alternating keys and values
+	// TimeFormat is the layout of the timestamps of the logger, set for
+	// its Formatter.
+	TimeFormat string
+}
+
+// Encoder writes records to w in a wire format. An Encoder set in the
+// Options replaces the text format of the logger.
+type Encoder interface {
+	Encode(w io.Writer, rec Record) error
+}
+
+// Decoder reads records that were written by an Encoder.
+type Decoder interface {
+	Decode(r *bufio.Reader) (Record, error)
+}
+
+// ErrMalformed is returned by a Decoder when a record cannot be parsed.
+var ErrMalformed = errors.New("malformed record")
+
+// TextCodec encodes and decodes records in the text format, one per line.
+// It's the format of a logger without an Encoder, except that colors are
+// never used. The key value pairs are appended to the message, and they
+// are not separated from the message when decoding.
+type TextCodec struct {
+	// TimeFormat is the layout of the timestamps. Defaults to the
+	// TimeFormat of the DefaultOptions.
+	TimeFormat string
+}
+
+func (c TextCodec) layout() string {
+	if c.TimeFormat == "" {
+		return DefaultOptions.TimeFormat
+	}
+	return c.TimeFormat
+}
+
+// Encode writes the record as a line.
+func (c TextCodec) Encode(w io.Writer, rec Record) error {
+	b := bufferPool.Get().(*buffer)
+	line, _, _ := appendPrefix((*b)[:0], rec.Pid, rec.App, nil, rec.Time,
+		c.layout(), rec.Level)
+	line = append(line, rec.Msg...)
+	line = appendKVs(line, rec.KVs)
+	line = append(line, '\n')
+	_, err := w.Write(line)
+	*b = line
+	putBuffer(b)
+	return err
+}
+
+// Decode reads the next line. It returns ErrMalformed for a line that is
+// not in the text format, and reading can continue with the next line.
+func (c TextCodec) Decode(r *bufio.Reader) (Record, error) {
+	line, err := r.ReadString('\n')
+	if line == "" {
+		return Record{}, err
+	}
+	pl, ok := parseLine(strings.TrimRight(line, "\r\n"), c.layout())
+	if !ok {
+		return Record{}, ErrMalformed
+	}
+	return pl.record(), nil
+}
+
+// record returns the parsed line as a record. Timestamps without a year,
+// such as TimeFormatRedisNumeric, are taken to be in the current year.
+func (pl parsedLine) record() Record {
+	t, _ := time.ParseInLocation(pl.layout, pl.time, time.Local)
+	if t.Year() == 0 {
+		t = t.AddDate(time.Now().Year(), 0, 0)
+	}
+	return Record{Pid: pl.pid, App: pl.app, Time: t, Level: pl.level,
+		Msg: pl.msg}
+}
+
+// appendPrefix appends the pid, app, tags, timestamp, and level of a line,
+// and returns the position of the timestamp.
+func appendPrefix(dst []byte, pid int, app byte, tags []byte, t time.Time,
+	layout string, level int) (line []byte, ts, te int) {
+	level = clampLevel(level)
+	dst = strconv.AppendInt(dst, int64(pid), 10)
+	dst = append(dst, ':', app)
+	dst = append(dst, tags...)
+	dst = append(dst, ' ')
+	ts = len(dst)
+	dst = t.AppendFormat(dst, layout)
+	te = len(dst)
+	dst = append(dst, ' ', levelChars[level-LevelTrace], ' ')
+	return dst, ts, te
+}
+
+// Formatter appends a record as a line, without the trailing newline, and
+// with colors when tty is set. Custom encodings are set as the Formatter
+// option, and DefaultFormatter is the Redis format.
+type Formatter func(dst []byte, rec Record, tty bool) []byte
+
+// DefaultFormatter appends the record in the Redis format, using the
+// TimeFormat of the record, or TimeFormatRedis without one, with colors
+// when tty is set. Custom Formatters may delegate to it.
+func DefaultFormatter(dst []byte, rec Record, tty bool) []byte {
+	start := len(dst)
+	layout := rec.TimeFormat
+	if layout == "" {
+		layout = TimeFormatRedis
+	}
+	dst, _, te := appendPrefix(dst, rec.Pid, rec.App, nil, rec.Time, layout,
+		rec.Level)
+	if idx := clampLevel(rec.Level) - LevelTrace; tty && levelColors[idx] != "" {
+		dst = append(dst[:te+1], "\x1b["...)
+		dst = append(dst, levelColors[idx]...)
+		dst = append(dst, 'm', levelChars[idx])
+		dst = append(dst, "\x1b[0m "...)
+	}
+	dst = append(dst, rec.Msg...)
+	dst = appendKVs(dst, rec.KVs)
+	if tty {
+		s := logPostFilter(string(dst[start:]))
+		dst = append(dst[:start], s...)
+	}
+	return dst
+}
+
+// clampLevel returns the level limited to the range from trace to error.
+func clampLevel(level int) int {
+	if level < LevelTrace {
+		return LevelTrace
+	} else if level > levelError {
+		return levelError
+	}
+	return level
+}
+
+// appendKVs appends key value pairs as " key=value". Values with spaces
+// are quoted.
+func appendKVs(dst []byte, kvs []interface{}) []byte {
+	for i := 0; i < len(kvs); i += 2 {
+		dst = append(dst, ' ')
+		dst = append(dst, fmt.Sprint(kvs[i])...)
+		dst = append(dst, '=')
+		if i+1 < len(kvs) {
+			v := fmt.Sprint(kvs[i+1])
+			if v == "" || strings.ContainsAny(v, " \t\r\n\"=") {
+				dst = strconv.AppendQuote(dst, v)
+			} else {
+				dst = append(dst, v...)
+			}
+		}
+	}
+	return dst
+}
+
+// Reader reads the records of a log.
+type Reader struct {
+	rd  *bufio.Reader
+	dec Decoder
+}
+
+// NewReader returns a reader of the records in r. A nil dec reads the
+// text format with the default TimeFormat.
+func NewReader(r io.Reader, dec Decoder) *Reader {
+	if dec == nil {
+		dec = TextCodec{}
+	}
+	return &Reader{rd: bufio.NewReader(r), dec: dec}
+}
+
+// Read returns the next record, or io.EOF at the end of the log.
+func (r *Reader) Read() (Record, error) {
+	return r.dec.Decode(r.rd)
+}
@@ -0,0 +1,166 @@
+package redlog
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextCodec(t *testing.T) {
+	tm := time.Date(2024, 6, 1, 15, 4, 5, 0, time.Local)
+	buf := &bytes.Buffer{}
+	var c TextCodec
+	c.Encode(buf, Record{Pid: 1234, App: 'C', Time: tm, Level: LevelWarning,
+		Msg: "hello", KVs: []interface{}{"shard", 3, "node", "a 1", "odd"}})
+	c.Encode(buf, Record{Pid: 1, App: 'M', Time: tm, Level: LevelError,
+		Msg: "error"})
+	want := "1234:C 01 Jun 2024 15:04:05.000 # hello shard=3 node=\"a 1\" odd=\n" +
+		"1:M 01 Jun 2024 15:04:05.000 # error\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.WriteString("not a record\n")
+	buf.WriteString("1:M 01 Jun 2024 15:04:05.000 . last")
+	r := NewReader(buf, nil)
+	var recs []Record
+	var malformed int
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err == ErrMalformed {
+			malformed++
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	if malformed != 1 || len(recs) != 3 {
+		t.Fatalf("unexpected records %d %+v", malformed, recs)
+	}
+	if rec := recs[0]; rec.Pid != 1234 || rec.App != 'C' ||
+		!rec.Time.Equal(tm) || rec.Level != LevelWarning ||
+		rec.Msg != `hello shard=3 node="a 1" odd=` {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+	if rec := recs[2]; rec.Level != LevelDebug || rec.Msg != "last" {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+}
+
+// testEncoder writes records as "level|msg;".
+type testEncoder struct{}
+
+func (testEncoder) Encode(w io.Writer, rec Record) error {
+	_, err := io.WriteString(w, levelNames[rec.Level-LevelTrace]+"|"+
+		rec.Msg+";")
+	return err
+}
+
+func TestEncoderOption(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{
+		Level:                LevelDebug,
+		Color:                ColorAlways,
+		Encoder:              testEncoder{},
+		PassthroughFormatted: true,
+		CrashRing:            4,
+	})
+	l.Tracef("trace")
+	l.Debugf("debug")
+	l.Noticef("notice\n")
+	l.Write([]byte("99:C 01 Jun 2024 15:04:05.000 # formatted\n"))
+	if want := "debug|debug;notice|notice;warning|formatted;"; buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+
+	// the crash ring is in the text format
+	buf.Reset()
+	l.DumpRing(buf)
+	if lines := strings.Split(buf.String(), "\n"); len(lines) != 7 ||
+		!strings.HasSuffix(lines[1], " , trace") ||
+		lines[4] != "99:C 01 Jun 2024 15:04:05.000 # formatted" {
+		t.Fatalf("unexpected ring %q", buf.String())
+	}
+}
+
+// DefaultFormatter is a Formatter.
+var _ Formatter = DefaultFormatter
+
+func TestFormatter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{
+		Level: LevelNotice,
+		Now:   func() time.Time { return now },
+		Formatter: func(dst []byte, rec Record, tty bool) []byte {
+			dst = append(dst, "host1 "...)
+			dst = append(dst, levelNames[rec.Level-LevelTrace]...)
+			dst = append(dst, ' ')
+			return append(dst, rec.Msg...)
+		},
+		Filter: func(line string, tty bool) (string, byte, int) {
+			return strings.ToUpper(line), 0, LevelWarning
+		},
+	})
+	l.Noticef("hello")
+	l.Debugf("hidden")
+	l.Write([]byte("filtered\n"))
+	if buf.String() != "host1 notice hello\nhost1 warning FILTERED\n" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	// delegating to the default formatter, then appending
+	buf.Reset()
+	l = New(buf, &Options{
+		Now: func() time.Time { return now },
+		Formatter: func(dst []byte, rec Record, tty bool) []byte {
+			dst = DefaultFormatter(dst, rec, tty)
+			return append(dst, " host=host1"...)
+		},
+	})
+	l.pid = 1234
+	l.Warningf("hello")
+	if buf.String() != "1234:M 01 Jun 2024 15:04:05.000 # hello host=host1\n" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	// with the TimeFormat of the logger
+	buf.Reset()
+	l = New(buf, &Options{
+		Now:        func() time.Time { return now },
+		TimeFormat: TimeFormatRedisNumeric,
+		Formatter: func(dst []byte, rec Record, tty bool) []byte {
+			return DefaultFormatter(dst, rec, tty)
+		},
+	})
+	l.pid = 1234
+	l.Warningf("hello")
+	if buf.String() != "1234:M 01-06 15:04:05.000 # hello\n" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	out := string(DefaultFormatter(nil, Record{Pid: 1, App: 'M', Time: now,
+		Level: LevelError, Msg: "x", KVs: []interface{}{"k", 1}}, true))
+	if out != "\x1b[35m1:M\x1b[0m\x1b[2m 01 Jun 2024 15:04:05.000\x1b[0m "+
+		"\x1b[31m#\x1b[0m x k=1" {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	// the Encoder takes precedence
+	buf.Reset()
+	l = New(buf, &Options{
+		Formatter: func(dst []byte, rec Record, tty bool) []byte {
+			return append(dst, "formatted"...)
+		},
+		Encoder: TextCodec{},
+	})
+	l.Noticef("hello")
+	if strings.Contains(buf.String(), "formatted") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
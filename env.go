@@ -0,0 +1,108 @@
+package redlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Environment variables read by OptionsFromEnv and MergeEnv.
+//
+//	REDLOG_LEVEL        trace, debug, verbose, notice, or warning
+//	REDLOG_FORMAT       redis, plain, json, or logfmt
+//	REDLOG_COLOR        auto, always, or never
+//	REDLOG_TIME_FORMAT  a time.Format layout
+//	REDLOG_MODULES      module levels, such as raft=debug,store=notice
+const (
+	EnvLevel      = "REDLOG_LEVEL"
+	EnvFormat     = "REDLOG_FORMAT"
+	EnvColor      = "REDLOG_COLOR"
+	EnvTimeFormat = "REDLOG_TIME_FORMAT"
+	EnvModules    = "REDLOG_MODULES"
+)
+
+// OptionsFromEnv returns the DefaultOptions overridden by the REDLOG_*
+// environment variables.
+func OptionsFromEnv() (*Options, error) {
+	return MergeEnv(nil)
+}
+
+// MergeEnv returns a copy of opts where only the settings that have a
+// matching REDLOG_* environment variable are overridden. A nil opts merges
+// with DefaultOptions. Invalid variables are skipped and the first parsing
+// error is returned along with the merged options.
+func MergeEnv(opts *Options) (*Options, error) {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	merged := *opts
+	var firstErr error
+	setErr := func(name string, err error) {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	if s, ok := os.LookupEnv(EnvLevel); ok {
+		level, err := parseLevel(s)
+		if err != nil {
+			setErr(EnvLevel, err)
+		} else {
+			merged.Level = level
+		}
+	}
+	if s, ok := os.LookupEnv(EnvFormat); ok {
+		switch strings.ToLower(s) {
+		case "redis", "text":
+			merged.Format = FormatRedis
+		case "plain":
+			merged.Format = FormatPlain
+		case "json":
+			merged.Format = FormatJSON
+		case "logfmt":
+			merged.Format = FormatLogfmt
+		default:
+			setErr(EnvFormat, fmt.Errorf("unsupported format %q", s))
+		}
+	}
+	if s, ok := os.LookupEnv(EnvColor); ok {
+		switch strings.ToLower(s) {
+		case "auto":
+			merged.Color = ColorAuto
+		case "always":
+			merged.Color = ColorAlways
+		case "never":
+			merged.Color = ColorNever
+		default:
+			setErr(EnvColor, fmt.Errorf("invalid color mode %q", s))
+		}
+	}
+	if s, ok := os.LookupEnv(EnvTimeFormat); ok {
+		if s == "" {
+			setErr(EnvTimeFormat, fmt.Errorf("empty time format"))
+		} else {
+			merged.TimeFormat = s
+		}
+	}
+	if s, ok := os.LookupEnv(EnvModules); ok {
+		levels, err := ParseModuleLevels(s)
+		if err != nil {
+			setErr(EnvModules, err)
+		} else {
+			merged.ModuleLevels = levels
+		}
+	}
+	return &merged, firstErr
+}
+
+// NewFromEnv returns a new logger configured by the REDLOG_* environment
+// variables. Invalid variables fall back to DefaultOptions and are reported
+// as a warning to the new logger.
+func NewFromEnv(wr io.Writer) *Logger {
+	opts, err := OptionsFromEnv()
+	l := New(wr, opts)
+	if err != nil {
+		l.Warningf("%v", err)
+	}
+	return l
+}
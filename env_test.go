@@ -0,0 +1,119 @@
+package redlog
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMergeEnv(t *testing.T) {
+	base := &Options{Level: LevelWarning, TimeFormat: "15:04", App: 'C'}
+	tests := []struct {
+		env    map[string]string
+		level  int
+		color  int
+		tfmt   string
+		format int
+		err    string
+	}{
+		{nil, LevelWarning, ColorAuto, "15:04", FormatRedis, ""},
+		{map[string]string{EnvLevel: "debug"}, LevelDebug, ColorAuto,
+			"15:04", FormatRedis, ""},
+		{map[string]string{EnvLevel: "VERBOSE", EnvColor: "never"},
+			LevelVerbose, ColorNever, "15:04", FormatRedis, ""},
+		{map[string]string{EnvLevel: "1"}, LevelVerbose, ColorAuto,
+			"15:04", FormatRedis, ""},
+		{map[string]string{EnvColor: "always", EnvTimeFormat: "15:04:05"},
+			LevelWarning, ColorAlways, "15:04:05", FormatRedis, ""},
+		{map[string]string{EnvFormat: "redis"}, LevelWarning, ColorAuto,
+			"15:04", FormatRedis, ""},
+		{map[string]string{EnvFormat: "Plain"}, LevelWarning, ColorAuto,
+			"15:04", FormatPlain, ""},
+		{map[string]string{EnvFormat: "json"}, LevelWarning, ColorAuto,
+			"15:04", FormatJSON, ""},
+		{map[string]string{EnvFormat: "logfmt"}, LevelWarning, ColorAuto,
+			"15:04", FormatLogfmt, ""},
+		{map[string]string{EnvLevel: "loud", EnvColor: "never"},
+			LevelWarning, ColorNever, "15:04", FormatRedis, EnvLevel},
+		{map[string]string{EnvColor: "sometimes"}, LevelWarning,
+			ColorAuto, "15:04", FormatRedis, EnvColor},
+		{map[string]string{EnvFormat: "xml"}, LevelWarning, ColorAuto,
+			"15:04", FormatRedis, EnvFormat},
+		{map[string]string{EnvTimeFormat: ""}, LevelWarning, ColorAuto,
+			"15:04", FormatRedis, EnvTimeFormat},
+	}
+	for i, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			for k, v := range tt.env {
+				setenv(t, k, v)
+			}
+			opts, err := MergeEnv(base)
+			if tt.err == "" && err != nil {
+				t.Fatalf("%d: unexpected error: %v", i, err)
+			}
+			if tt.err != "" && (err == nil ||
+				!strings.HasPrefix(err.Error(), tt.err)) {
+				t.Fatalf("%d: expected %s error, got %v", i, tt.err, err)
+			}
+			if opts.Level != tt.level || opts.Color != tt.color ||
+				opts.TimeFormat != tt.tfmt || opts.App != 'C' ||
+				opts.Format != tt.format {
+				t.Fatalf("%d: unexpected options %+v", i, opts)
+			}
+		})
+	}
+	if base.Level != LevelWarning || base.Color != ColorAuto {
+		t.Fatal("base options were modified")
+	}
+}
+
+func TestMergeEnvModules(t *testing.T) {
+	setenv(t, EnvModules, "raft=debug")
+	opts, err := MergeEnv(nil)
+	if err != nil || len(opts.ModuleLevels) != 1 ||
+		opts.ModuleLevels["raft"] != LevelDebug {
+		t.Fatalf("unexpected options %+v %v", opts, err)
+	}
+	setenv(t, EnvModules, "raft")
+	if _, err := MergeEnv(nil); err == nil ||
+		!strings.HasPrefix(err.Error(), EnvModules) {
+		t.Fatalf("expected %s error, got %v", EnvModules, err)
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	setenv(t, EnvLevel, "verbose")
+	setenv(t, EnvColor, "never")
+	buf := &bytes.Buffer{}
+	l := NewFromEnv(buf)
+	l.Debugf("debug")
+	l.Verbf("verbose")
+	if out := buf.String(); strings.Contains(out, "debug") ||
+		!strings.Contains(out, " - verbose") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	setenv(t, EnvLevel, "loud")
+	buf.Reset()
+	l = NewFromEnv(buf)
+	l.Verbf("verbose")
+	if out := buf.String(); !strings.Contains(out, "# REDLOG_LEVEL") ||
+		strings.Contains(out, "verbose") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+// setenv sets an environment variable until the end of the test, like
+// t.Setenv of Go 1.17.
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
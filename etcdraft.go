@@ -0,0 +1,70 @@
+package redlog
+
+import "fmt"
+
+// EtcdRaftLogger logs the messages of the etcd raft package. It implements
+// raft.Logger, without depending on raft, for raft.SetLogger or the Logger
+// of a raft.Config. Info is logged as notices, and Error, Fatal, and Panic
+// at the error level. Fatal exits like Logger.Fatal, and Panic panics with
+// the message after dumping the crash ring.
+type EtcdRaftLogger struct {
+	l *Logger
+}
+
+// NewEtcdRaftLogger returns an EtcdRaftLogger that logs to l.
+func NewEtcdRaftLogger(l *Logger) *EtcdRaftLogger {
+	return &EtcdRaftLogger{l}
+}
+
+// Debug logs at the debug level.
+func (r *EtcdRaftLogger) Debug(v ...interface{}) { r.l.Debug(v...) }
+
+// Debugf logs at the debug level.
+func (r *EtcdRaftLogger) Debugf(format string, v ...interface{}) {
+	r.l.Debugf(format, v...)
+}
+
+// Info logs at the notice level.
+func (r *EtcdRaftLogger) Info(v ...interface{}) { r.l.Notice(v...) }
+
+// Infof logs at the notice level.
+func (r *EtcdRaftLogger) Infof(format string, v ...interface{}) {
+	r.l.Noticef(format, v...)
+}
+
+// Warning logs at the warning level.
+func (r *EtcdRaftLogger) Warning(v ...interface{}) { r.l.Warning(v...) }
+
+// Warningf logs at the warning level.
+func (r *EtcdRaftLogger) Warningf(format string, v ...interface{}) {
+	r.l.Warningf(format, v...)
+}
+
+// Error logs at the error level.
+func (r *EtcdRaftLogger) Error(v ...interface{}) { r.l.Error(v...) }
+
+// Errorf logs at the error level.
+func (r *EtcdRaftLogger) Errorf(format string, v ...interface{}) {
+	r.l.Errorf(format, v...)
+}
+
+// Fatal logs at the error level and exits.
+func (r *EtcdRaftLogger) Fatal(v ...interface{}) { r.l.Fatal(v...) }
+
+// Fatalf logs at the error level and exits.
+func (r *EtcdRaftLogger) Fatalf(format string, v ...interface{}) {
+	r.l.Fatalf(format, v...)
+}
+
+// Panic logs at the error level and panics with the message.
+func (r *EtcdRaftLogger) Panic(v ...interface{}) { r.panic(fmt.Sprint(v...)) }
+
+// Panicf logs at the error level and panics with the message.
+func (r *EtcdRaftLogger) Panicf(format string, v ...interface{}) {
+	r.panic(fmt.Sprintf(format, v...))
+}
+
+func (r *EtcdRaftLogger) panic(msg string) {
+	r.l.Error(msg)
+	r.l.panic(msg)
+}
@@ -0,0 +1,62 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// etcdRaftLogger is raft.Logger of etcd.
+type etcdRaftLogger interface {
+	Debug(v ...interface{})
+	Debugf(format string, v ...interface{})
+	Error(v ...interface{})
+	Errorf(format string, v ...interface{})
+	Info(v ...interface{})
+	Infof(format string, v ...interface{})
+	Warning(v ...interface{})
+	Warningf(format string, v ...interface{})
+	Fatal(v ...interface{})
+	Fatalf(format string, v ...interface{})
+	Panic(v ...interface{})
+	Panicf(format string, v ...interface{})
+}
+
+func TestEtcdRaftLogger(t *testing.T) {
+	var buf bytes.Buffer
+	var exits int
+	l := New(&buf, &Options{Level: LevelNotice, CrashRing: 8,
+		ExitFunc: func(int) { exits++ }})
+	l.pid = 1234
+	var r etcdRaftLogger = NewEtcdRaftLogger(l)
+	r.Debugf("hidden")
+	r.Infof("%x became follower at term %d", 1, 2)
+	r.Warning("dropped ", 3)
+	r.Errorf("failed")
+	r.Fatalf("fatal %d", 1)
+	if exits != 1 {
+		t.Fatalf("expected an exit, got %d", exits)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != "tocommit(5) is out of range" {
+				t.Fatalf("unexpected panic %v", r)
+			}
+		}()
+		r.Panicf("tocommit(%d) is out of range", 5)
+	}()
+
+	out := buf.String()
+	for _, s := range []string{
+		"* 1 became follower at term 2\n",
+		"# dropped 3\n",
+		"# failed\n",
+		"# fatal 1\n",
+		"# tocommit(5) is out of range\n",
+		"hidden", // from the crash ring
+	} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("expected %q in %q", s, out)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package redlog
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// failoverProbe is how often the primary writer is retried after a
+// failover.
+const failoverProbe = time.Second
+
+// failover is the state of the Fallback option. It's only used by the
+// goroutine that is writing the queued lines.
+type failover struct {
+	wr        io.Writer
+	threshold int
+	errs      int       // consecutive errors of the primary writer
+	active    bool      // writing to the fallback
+	probe     time.Time // next retry of the primary writer
+}
+
+// writeBatch writes queued lines to the primary writer, or to the Fallback
+// writer when the primary is failing. Lines that fail to write to the
+// primary are written to the fallback instead.
+func (l *Logger) writeBatch(p []byte) error {
+	fo := l.fo
+	if fo == nil {
+		_, err := l.writeLine(l.output(), p)
+		return err
+	}
+	now := l.now()
+	if fo.active {
+		if now.Before(fo.probe) {
+			_, err := fo.wr.Write(p)
+			return err
+		}
+		// the recovery notice is the probe
+		note := l.notice(nil, now, "log output recovered")
+		if _, err := l.writeLine(l.output(), note); err != nil {
+			fo.probe = now.Add(failoverProbe)
+			_, err := fo.wr.Write(p)
+			return err
+		}
+		fo.active = false
+		fo.errs = 0
+		atomic.AddUint64(&l.recoveries, 1)
+		fo.wr.Write(note)
+	}
+	_, err := l.writeLine(l.output(), p)
+	if err == nil {
+		fo.errs = 0
+		return nil
+	}
+	fo.errs++
+	var b []byte
+	if fo.errs >= fo.threshold {
+		fo.active = true
+		fo.probe = now.Add(failoverProbe)
+		atomic.AddUint64(&l.failovers, 1)
+		b = l.notice(nil, now, fmt.Sprintf(
+			"log output failed: %v, switching to fallback", err))
+	}
+	_, err = fo.wr.Write(append(b, p...))
+	return err
+}
+
+// notice appends a notice line that is written outside of the queue.
+func (l *Logger) notice(dst []byte, now time.Time, msg string) []byte {
+	var tb [64]byte
+	tags := append(append(tb[:0], l.loadHostTag()...), l.label...)
+	dst, _, _ = appendPrefix(dst, l.pid, l.App(), tags, now, l.TimeFormat(),
+		LevelNotice)
+	dst = append(dst, msg...)
+	return append(dst, '\n')
+}
@@ -0,0 +1,116 @@
+package redlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyWriter fails while fail is set, or every failEvery writes.
+type flakyWriter struct {
+	bytes.Buffer
+	fail      bool
+	failEvery int
+	n         int
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	w.n++
+	if w.fail || w.failEvery > 0 && w.n%w.failEvery == 0 {
+		return 0, errors.New("disk full")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestFailover(t *testing.T) {
+	var primary flakyWriter
+	var fallback bytes.Buffer
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	l := New(&primary, &Options{
+		Level:             LevelNotice,
+		Fallback:          &fallback,
+		FailoverThreshold: 2,
+		Now:               func() time.Time { return now },
+	})
+	l.Noticef("line 1")
+	primary.fail = true
+	l.Noticef("line 2")
+	l.Noticef("line 3")
+	l.Noticef("line 4")
+	now = now.Add(time.Second)
+	l.Noticef("line 5") // the probe fails
+	primary.fail = false
+	l.Noticef("line 6") // still waiting to probe
+	now = now.Add(time.Second)
+	l.Noticef("line 7")
+	l.Noticef("line 8")
+
+	lines := func(s string) string {
+		var msgs []string
+		for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+			msgs = append(msgs, line[strings.Index(line, " * ")+3:])
+		}
+		return strings.Join(msgs, "|")
+	}
+	if s := lines(primary.String()); s != "line 1|log output recovered|"+
+		"line 7|line 8" {
+		t.Fatalf("unexpected primary output %q", s)
+	}
+	if s := lines(fallback.String()); s != "line 2|"+
+		"log output failed: disk full, switching to fallback|"+
+		"line 3|line 4|line 5|line 6|log output recovered" {
+		t.Fatalf("unexpected fallback output %q", s)
+	}
+	st := l.Stats()
+	if st.Failovers != 1 || st.Recoveries != 1 || st.Dropped != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestFailoverConcurrent(t *testing.T) {
+	primary := flakyWriter{failEvery: 3}
+	var fallback bytes.Buffer
+	var mu sync.Mutex
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	l := New(&primary, &Options{
+		Level:             LevelNotice,
+		Fallback:          &fallback,
+		FailoverThreshold: 1,
+		Now: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			now = now.Add(100 * time.Millisecond)
+			return now
+		},
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Noticef("%d/%d", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	// every line is written once, to either writer
+	out := primary.String() + fallback.String()
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 100; j++ {
+			if n := strings.Count(out, fmt.Sprintf(" * %d/%d\n", i, j)); n != 1 {
+				t.Fatalf("line %d/%d written %d times", i, j, n)
+			}
+		}
+	}
+	st := l.Stats()
+	if st.Failovers == 0 || st.Failovers != uint64(strings.Count(out,
+		"switching to fallback")) || st.Recoveries != uint64(strings.Count(
+		primary.String(), "log output recovered")) {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
@@ -0,0 +1,23 @@
+package redlog
+
+// With returns a logger that appends the key value pairs to its messages,
+// such as "started shard=3 node=a1", after the pairs of the call. The
+// Encoder and Formatter options get them as pairs of the record instead.
+// It shares the writer and settings of its parent.
+func (l *Logger) With(kvs ...interface{}) *Logger {
+	child := l.clone()
+	child.fields = make([]interface{}, 0, len(l.fields)+len(kvs))
+	child.fields = append(append(child.fields, l.fields...), kvs...)
+	return child
+}
+
+// Log logs msg at the level, followed by the key value pairs as
+// " key=value", like With. Levels above LevelError are logged as errors.
+// It's meant for adapters of other logging libraries, such as those in the
+// redzap and redhclog packages.
+func (l *Logger) Log(level int, msg string, kvs ...interface{}) {
+	level = clampLevel(level)
+	if l.enabled(level) && l.sampled(level, msg) {
+		writeKVs(l, level, msg, kvs)
+	}
+}
@@ -0,0 +1,59 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWith(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice})
+	l.pid = 1234
+	shard := l.With("shard", 3)
+	node := shard.With("node", "a 1")
+	shard.Noticef("started")
+	node.Warningf("slow")
+	node.Notice("")
+	node.WithLabel("w1").Named("raft").Noticef("elected")
+	writeKVs(node, LevelNotice, "joined", []interface{}{"peers", 2})
+	l.Noticef("plain")
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		got = append(got, line[strings.IndexByte(line, '.')+5:])
+	}
+	want := []string{
+		`* started shard=3`,
+		`# slow shard=3 node="a 1"`,
+		`* shard=3 node="a 1"`,
+		`* [raft] elected shard=3 node="a 1"`,
+		`* joined peers=2 shard=3 node="a 1"`,
+		`* plain`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", strings.Join(got, "\n"))
+	}
+
+	// the pairs of the record
+	buf.Reset()
+	l.SetFormat(FormatJSON)
+	writeKVs(node, LevelNotice, "joined", []interface{}{"peers", 2})
+	if out := buf.String(); !strings.Contains(out,
+		`"msg":"joined","peers":2,"shard":3,"node":"a 1"}`) {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestLogPairs(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice, Format: FormatPlain})
+	l.Log(LevelDebug, "hidden")
+	l.Log(LevelNotice, "started", "port", 6379)
+	l.With("node", 1).Log(LevelError+1, "failed", "err", "eof")
+	l.Flush()
+	exp := "started port=6379\n" + "error: failed err=eof node=1\n"
+	if buf.String() != exp || l.Stats().Error != 1 {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
@@ -0,0 +1,466 @@
+package redlog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenFile returns a new logger that appends to the file at path. The file
+// is rotated according to the MaxSize and RotateInterval options, and the
+// rotated files are pruned according to MaxBackups and MaxAge.
+//
+// Rotated files are named after the active file with a timestamp inserted
+// before the extension, such as "app-2024-06-01.log" for daily files.
+func OpenFile(path string, opts *Options) (*Logger, error) {
+	if opts == nil {
+		opts = DefaultOptions
+	}
+	w := newFileWriter(path, opts)
+	l := New(w, opts)
+	l.owned = append(l.owned, w)
+	l.file = w
+	if err := w.attach(l); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// newFileWriter returns the writer of the file at path, which is opened by
+// attach.
+func newFileWriter(path string, opts *Options) *fileWriter {
+	w := &fileWriter{
+		path:       path,
+		maxSize:    opts.MaxSize,
+		maxBackups: opts.MaxBackups,
+		interval:   opts.RotateInterval,
+		maxAge:     opts.MaxAge,
+		compress:   opts.CompressBackups,
+		lock:       opts.LockFile,
+		mode:       opts.FileMode,
+		dirMode:    opts.DirMode,
+	}
+	if w.mode == 0 {
+		w.mode = 0644
+	}
+	if w.dirMode == 0 {
+		w.dirMode = 0755
+	}
+	return w
+}
+
+// attach opens the file for the logger, and starts pruning and compressing
+// the backups.
+func (w *fileWriter) attach(l *Logger) error {
+	w.now = l.now
+	w.warn = l.Warningf
+	w.header = l.rotationHeader
+	w.mu.Lock()
+	err := w.open(w.now())
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if w.compress {
+		// compress the backups left over from a previous run
+		backups, _ := w.backups()
+		for i := len(backups) - 1; i >= 0; i-- {
+			if !strings.HasSuffix(backups[i].Name(), ".gz") {
+				w.queueCompress(w.dir(backups[i].Name()))
+			}
+		}
+	}
+	w.background(w.prune)
+	return nil
+}
+
+// setFile switches the output to the file w, and closes the previous file
+// of the logger, if any.
+func (l *Logger) setFile(w *fileWriter) {
+	l.SetOutput(w)
+	l.mu.Lock()
+	if l.isClosed() {
+		l.mu.Unlock()
+		w.Close()
+		return
+	}
+	prev := l.file
+	l.file = w
+	owned := l.owned[:0]
+	for _, c := range l.owned {
+		if c != io.Closer(prev) {
+			owned = append(owned, c)
+		}
+	}
+	l.owned = append(owned, w)
+	l.mu.Unlock()
+	if prev != nil {
+		prev.Close()
+	}
+}
+
+// filePath returns the path of the file of the logger, or empty.
+func (l *Logger) filePath() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return ""
+	}
+	return l.file.path
+}
+
+// fileWriter is a rotating log file.
+type fileWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	interval   time.Duration
+	maxAge     time.Duration
+	compress   bool
+	lock       bool
+	sync       bool // after every write
+	mode       os.FileMode
+	dirMode    os.FileMode
+	now        func() time.Time
+	warn       func(format string, args ...interface{})
+	header     func() []byte // written at the top of rotated files
+
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	start    time.Time // start of the current period
+	boundary time.Time // next time-based rotation, or zero
+
+	pruneMu sync.Mutex
+
+	compressMu      sync.Mutex
+	compressQueue   []string
+	compressRunning bool
+
+	wg sync.WaitGroup // background goroutines
+}
+
+// background runs f in a goroutine that Close waits for.
+func (w *fileWriter) background(f func()) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		f()
+	}()
+}
+
+// Close closes the active file and waits for background pruning and
+// compression to finish.
+func (w *fileWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	if w.f != nil {
+		err = w.f.Close()
+		w.f = nil
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+	return err
+}
+
+// open opens the active file and computes the next rotation boundary.
+// The period of a non-empty file is taken from its modification time so
+// that a file left over from a previous period is rotated on first write.
+func (w *fileWriter) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(w.path), w.dirMode); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND,
+		w.mode)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f = f
+	w.size = fi.Size()
+	if w.interval > 0 {
+		t := now
+		if w.size > 0 {
+			t = fi.ModTime().In(now.Location())
+		}
+		w.start = periodStart(t, w.interval)
+		w.boundary = periodEnd(w.start, w.interval)
+	}
+	return nil
+}
+
+// periodStart returns the start of the rotation period containing t.
+// Periods are aligned to midnight in the location of t.
+func periodStart(t time.Time, interval time.Duration) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if interval >= 24*time.Hour {
+		return day
+	}
+	return day.Add(t.Sub(day) / interval * interval)
+}
+
+// periodEnd returns the end of the rotation period starting at start.
+func periodEnd(start time.Time, interval time.Duration) time.Time {
+	if interval >= 24*time.Hour {
+		return start.AddDate(0, 0, int(interval/(24*time.Hour)))
+	}
+	return start.Add(interval)
+}
+
+// Write writes p to the active file, rotating it first when p crosses a
+// time boundary or would exceed MaxSize.
+func (w *fileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	if w.f == nil {
+		if err := w.open(now); err != nil {
+			return 0, err
+		}
+	}
+	var stamp string
+	if !w.boundary.IsZero() && !now.Before(w.boundary) {
+		if w.interval%(24*time.Hour) == 0 {
+			stamp = w.start.Format("2006-01-02")
+		} else {
+			stamp = w.start.Format("2006-01-02T15-04-05")
+		}
+	} else if w.maxSize > 0 && w.size > 0 &&
+		w.size+int64(len(p)) > w.maxSize {
+		stamp = now.Format("2006-01-02T15-04-05.000")
+	}
+	if stamp != "" {
+		if err := w.rotate(now, stamp); err != nil {
+			w.background(func() { w.warn("log rotation failed: %v", err) })
+			if w.f == nil {
+				return 0, err
+			}
+		}
+	}
+	if w.lock {
+		if err := lockFile(w.f); err != nil {
+			return 0, err
+		}
+		defer unlockFile(w.f)
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	if err == nil && w.sync {
+		err = syncFile(w.f)
+	}
+	return n, err
+}
+
+// rotate renames the active file to a backup named with stamp and opens a
+// new active file, with the same mode as the old one.
+func (w *fileWriter) rotate(now time.Time, stamp string) error {
+	fi, serr := w.f.Stat()
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	w.f = nil
+	name := w.backupName(stamp)
+	err := os.Rename(w.path, name)
+	if oerr := w.open(now); oerr != nil {
+		return oerr
+	}
+	if err != nil {
+		return err
+	}
+	if w.header != nil {
+		n, err := w.f.Write(w.header())
+		w.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	if serr == nil && fi.Mode().Perm() != w.mode.Perm() {
+		// the mode is set explicitly as the umask may have changed it
+		if err := w.f.Chmod(fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if w.compress {
+		w.queueCompress(name)
+	} else {
+		w.background(w.prune)
+	}
+	return nil
+}
+
+// backupName returns an unused backup file name for stamp.
+func (w *fileWriter) backupName(stamp string) string {
+	dir, prefix, ext := w.nameParts()
+	name := filepath.Join(dir, prefix+stamp+ext)
+	for i := 1; ; i++ {
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+		name = filepath.Join(dir, prefix+stamp+"."+strconv.Itoa(i)+ext)
+	}
+}
+
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return !os.IsNotExist(err)
+}
+
+// dir returns name joined to the directory of the active file.
+func (w *fileWriter) dir(name string) string {
+	return filepath.Join(filepath.Dir(w.path), name)
+}
+
+// nameParts splits the active path into the directory, the prefix shared
+// by all backups, and the extension.
+func (w *fileWriter) nameParts() (dir, prefix, ext string) {
+	dir, base := filepath.Split(w.path)
+	ext = filepath.Ext(base)
+	return dir, base[:len(base)-len(ext)] + "-", ext
+}
+
+// backups returns the rotated files, newest first. Compressed and plain
+// backups are treated alike, and a backup that is in the middle of being
+// compressed is only returned once.
+func (w *fileWriter) backups() ([]os.FileInfo, error) {
+	dir, prefix, ext := w.nameParts()
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := f.Readdir(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, fi := range infos {
+		names[fi.Name()] = true
+	}
+	var backups []os.FileInfo
+	for _, fi := range infos {
+		name := strings.TrimSuffix(fi.Name(), ".gz")
+		if fi.Mode().IsRegular() && len(name) > len(prefix) &&
+			strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ext) &&
+			name[len(prefix)] >= '0' && name[len(prefix)] <= '9' &&
+			(name != fi.Name() || !names[name+".gz"]) {
+			backups = append(backups, fi)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ModTime().After(backups[j].ModTime())
+	})
+	return backups, nil
+}
+
+// prune removes the backups exceeding MaxBackups or MaxAge. Failures are
+// reported as warnings.
+func (w *fileWriter) prune() {
+	if w.maxBackups <= 0 && w.maxAge <= 0 {
+		return
+	}
+	w.pruneMu.Lock()
+	defer w.pruneMu.Unlock()
+	backups, err := w.backups()
+	if err != nil {
+		w.warn("log pruning failed: %v", err)
+		return
+	}
+	now := w.now()
+	for i, fi := range backups {
+		if (w.maxBackups > 0 && i >= w.maxBackups) ||
+			(w.maxAge > 0 && now.Sub(fi.ModTime()) > w.maxAge) {
+			if err := os.Remove(w.dir(fi.Name())); err != nil {
+				w.warn("log pruning failed: %v", err)
+			}
+		}
+	}
+}
+
+// queueCompress queues a backup for compression. Backups are compressed
+// one at a time by a single background goroutine.
+func (w *fileWriter) queueCompress(name string) {
+	w.compressMu.Lock()
+	defer w.compressMu.Unlock()
+	w.compressQueue = append(w.compressQueue, name)
+	if !w.compressRunning {
+		w.compressRunning = true
+		w.background(w.compressLoop)
+	}
+}
+
+func (w *fileWriter) compressLoop() {
+	for {
+		w.compressMu.Lock()
+		if len(w.compressQueue) == 0 {
+			w.compressRunning = false
+			w.compressMu.Unlock()
+			return
+		}
+		name := w.compressQueue[0]
+		w.compressQueue = w.compressQueue[1:]
+		w.compressMu.Unlock()
+		// the backup may have been pruned while it was queued
+		if err := compressFile(name); err != nil && !os.IsNotExist(err) {
+			w.warn("log compression failed: %v", err)
+		}
+		w.prune()
+	}
+}
+
+// compressFile gzips name to name.gz. The compressed data is written to a
+// temporary file that is renamed only after it is complete, and the
+// original is removed last, so a crash never loses the backup.
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	tmp := name + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC,
+		fi.Mode())
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+	}
+	if err == nil {
+		err = os.Rename(tmp, name+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	src.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,327 @@
+package redlog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock is a settable clock for tests.
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *testClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.t = t
+	c.mu.Unlock()
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// waitFor polls cond until it returns true or the test times out.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for start := time.Now(); !cond(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("timeout")
+		}
+	}
+}
+
+func TestFileDailyRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &testClock{t: time.Date(2024, 6, 1, 23, 59, 59, 0, time.UTC)}
+	l, err := OpenFile(path, &Options{
+		Now:            clock.Now,
+		UTC:            true,
+		RotateInterval: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Noticef("line 1")
+	l.Noticef("line 2")
+	clock.Set(time.Date(2024, 6, 2, 0, 0, 1, 0, time.UTC))
+	l.Noticef("line 3")
+	clock.Set(time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC))
+	l.Noticef("line 4")
+
+	names := listDir(t, dir)
+	if strings.Join(names, ",") !=
+		"app-2024-06-01.log,app-2024-06-02.log,app.log" {
+		t.Fatalf("unexpected files %v", names)
+	}
+	day1 := readFile(t, filepath.Join(dir, "app-2024-06-01.log"))
+	day2 := readFile(t, filepath.Join(dir, "app-2024-06-02.log"))
+	day3 := readFile(t, path)
+	if strings.Count(day1, "\n") != 2 || !strings.Contains(day1, "line 1") ||
+		!strings.Contains(day1, "line 2") {
+		t.Fatalf("unexpected day 1 %q", day1)
+	}
+	if strings.Count(day2, "\n") != 1 ||
+		!strings.Contains(day2, "02 Jun 2024 00:00:01.000 * line 3") {
+		t.Fatalf("unexpected day 2 %q", day2)
+	}
+	if strings.Count(day3, "\n") != 1 || !strings.Contains(day3, "line 4") {
+		t.Fatalf("unexpected day 3 %q", day3)
+	}
+}
+
+func TestFileSizeRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &testClock{t: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	l, err := OpenFile(path, &Options{
+		Now:        clock.Now,
+		MaxSize:    100,
+		MaxBackups: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		l.Noticef("line %d", i)
+		clock.Set(clock.Now().Add(time.Second))
+	}
+	waitFor(t, func() bool { return len(listDir(t, dir)) == 3 })
+	for _, name := range listDir(t, dir) {
+		data := readFile(t, filepath.Join(dir, name))
+		if len(data) > 100 || len(data) == 0 {
+			t.Fatalf("unexpected size %d for %s", len(data), name)
+		}
+	}
+	if data := readFile(t, path); !strings.Contains(data, "line 9") {
+		t.Fatalf("unexpected active file %q", data)
+	}
+}
+
+func TestFileMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	seed := map[string]time.Time{
+		"app-2024-06-01.log": now.AddDate(0, 0, -9),
+		"app-2024-06-05.log": now.AddDate(0, 0, -5),
+		"app-2024-06-09.log": now.AddDate(0, 0, -1),
+		"other.log":          now.AddDate(0, 0, -9),
+		"app-notes.log":      now.AddDate(0, 0, -9),
+	}
+	for name, mtime := range seed {
+		name = filepath.Join(dir, name)
+		if err := ioutil.WriteFile(name, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock := &testClock{t: now}
+	_, err := OpenFile(path, &Options{
+		Now:    clock.Now,
+		MaxAge: 3 * 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(listDir(t, dir)) == 4 })
+	names := listDir(t, dir)
+	if strings.Join(names, ",") !=
+		"app-2024-06-09.log,app-notes.log,app.log,other.log" {
+		t.Fatalf("unexpected files %v", names)
+	}
+}
+
+func TestFilePruneWarning(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	l, err := OpenFile(path, &Options{MaxBackups: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := l.wr.(*fileWriter)
+	w.pruneMu.Lock()
+	w.path = filepath.Join(dir, "missing", "app.log")
+	w.pruneMu.Unlock()
+	w.prune()
+	if data := readFile(t, path); !strings.Contains(data,
+		"# log pruning failed") {
+		t.Fatalf("expected warning, got %q", data)
+	}
+}
+
+func TestFileCompressBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &testClock{t: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	l, err := OpenFile(path, &Options{
+		Now:             clock.Now,
+		MaxSize:         100,
+		MaxBackups:      3,
+		CompressBackups: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		l.Noticef("line %d", i)
+		clock.Set(clock.Now().Add(time.Second))
+	}
+	waitFor(t, func() bool {
+		names := listDir(t, dir)
+		if len(names) != 4 {
+			return false
+		}
+		for _, name := range names[:3] {
+			if !strings.HasSuffix(name, ".log.gz") {
+				return false
+			}
+		}
+		return true
+	})
+	var lines []string
+	for _, name := range listDir(t, dir)[:3] {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(zr)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.Split(strings.TrimSpace(string(data)),
+			"\n")...)
+	}
+	lines = append(lines, strings.Split(strings.TrimSpace(readFile(t, path)),
+		"\n")...)
+	// the newest lines are kept, in order, without gaps
+	first := 20 - len(lines)
+	for i, line := range lines {
+		if !strings.HasSuffix(line, fmt.Sprintf("* line %d", first+i)) {
+			t.Fatalf("unexpected line %d: %q", i, line)
+		}
+	}
+}
+
+func TestFileBackupsUniform(t *testing.T) {
+	dir := t.TempDir()
+	w := &fileWriter{path: filepath.Join(dir, "app.log")}
+	for _, name := range []string{"app-1.log", "app-2.log.gz", "app-3.log",
+		"app-3.log.gz", "app-4.log.gz.tmp", "app.log"} {
+		err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fi := range backups {
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "app-1.log,app-2.log.gz,app-3.log.gz" {
+		t.Fatalf("unexpected backups %v", names)
+	}
+}
+
+func TestFileModes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	path := filepath.Join(dir, "app.log")
+	clock := &testClock{t: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	l, err := OpenFile(path, &Options{
+		Now:      clock.Now,
+		MaxSize:  100,
+		FileMode: 0640,
+		DirMode:  0750,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.Noticef("line 1")
+	if runtime.GOOS == "windows" {
+		return
+	}
+	mode := func(name string) os.FileMode {
+		t.Helper()
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Mode().Perm()
+	}
+	if m := mode(dir); m != 0750 {
+		t.Fatalf("expected dir mode 0750, got %o", m)
+	}
+	if m := mode(path); m != 0640 {
+		t.Fatalf("expected file mode 0640, got %o", m)
+	}
+
+	// rotation keeps the mode of the original file
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	l.Noticef("%s", strings.Repeat("x", 100))
+	if names := listDir(t, dir); len(names) != 2 {
+		t.Fatalf("expected a rotation, got %v", names)
+	}
+	if m := mode(path); m != 0600 {
+		t.Fatalf("expected file mode 0600, got %o", m)
+	}
+}
+
+func TestFileDirError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := OpenFile(filepath.Join(file, "logs", "app.log"), nil)
+	if err == nil || l != nil {
+		t.Fatalf("expected an error, got %v", err)
+	}
+}
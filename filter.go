@@ -0,0 +1,294 @@
+package redlog
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Filter converts a line written to the logger by another library into a
+// message, app, and level. The app is zero for the logger's app, and the
+// level LevelDrop drops the line.
+type Filter func(line string, tty bool) (msg string, app byte, level int)
+
+// ChainFilters returns a filter that applies the filters in order, each to
+// the message of the one before it, for the Filters option. The line is
+// dropped when one of them returns LevelDrop. The app and level are those
+// of the last filter that returns a nonzero app, or a level other than
+// LevelKeep. Nil filters are skipped.
+func ChainFilters(filters ...Filter) Filter {
+	return func(line string, tty bool) (msg string, app byte, level int) {
+		msg, level = line, LevelNotice
+		for _, f := range filters {
+			if f == nil {
+				continue
+			}
+			m, a, lv := f(msg, tty)
+			if lv == LevelDrop {
+				return m, a, LevelDrop
+			}
+			msg = m
+			if a != 0 {
+				app = a
+			}
+			if lv != LevelKeep {
+				level = lv
+			}
+		}
+		return msg, app, level
+	}
+}
+
+// DropFilter returns a filter that drops the lines containing any of the
+// patterns, for the Filters option, such as after a filter that sets the
+// level. Other lines are passed as they are, with LevelKeep.
+func DropFilter(patterns ...string) Filter {
+	return func(line string, tty bool) (msg string, app byte, level int) {
+		for _, pattern := range patterns {
+			if strings.Contains(line, pattern) {
+				return line, 0, LevelDrop
+			}
+		}
+		return line, 0, LevelKeep
+	}
+}
+
+// FilterFor returns the built-in filter with the name, such as from a
+// configuration file, or nil. The names are "raft" for HashicorpRaftFilter,
+// "etcd", "grpc", "http", "badger", "hclog", "memberlist" or "serf", and
+// "log" for StdLogFilter.
+func FilterFor(name string) Filter {
+	switch strings.ToLower(name) {
+	case "raft", "hashicorp-raft":
+		return HashicorpRaftFilter
+	case "etcd", "etcd-raft":
+		return EtcdRaftFilter
+	case "grpc":
+		return GRPCFilter
+	case "http":
+		return HTTPServerFilter
+	case "badger":
+		return BadgerFilter
+	case "hclog":
+		return HclogFilter
+	case "memberlist", "serf":
+		return MemberlistFilter
+	case "log", "stdlog":
+		return StdLogFilter
+	}
+	return nil
+}
+
+// EtcdRaftFilter converts the lines of etcd and its raft package, in the
+// zap console format "TIME\tLEVEL\tCALLER\tMSG", the capnslog format
+// "DATE TIME L | pkg: msg", or that of the default raft logger.
+func EtcdRaftFilter(line string, tty bool) (msg string, app byte,
+	level int) {
+	line = strings.TrimRight(line, "\r\n")
+	if parts := strings.Split(line, "\t"); len(parts) >= 4 {
+		// zap console, with the JSON fields kept after the message
+		if level, ok := wordLevel(parts[1]); ok {
+			return strings.Join(parts[3:], " "), 0, level
+		}
+	}
+	if i := strings.Index(line, " | "); i >= 1 && (i == 1 || line[i-2] == ' ') {
+		// capnslog, with a single character level
+		switch line[i-1] {
+		case 'T':
+			return line[i+3:], 0, LevelTrace
+		case 'D':
+			return line[i+3:], 0, LevelDebug
+		case 'I', 'N':
+			return line[i+3:], 0, LevelNotice
+		case 'W', 'E', 'C':
+			return line[i+3:], 0, LevelWarning
+		}
+	}
+	// the default logger of the raft package, "raft2024/06/01 ..."
+	if strings.HasPrefix(line, "raft") && len(line) > 4 &&
+		line[4] >= '0' && line[4] <= '9' {
+		line = line[4:]
+	}
+	return prefixedLevel(stripStdTime(line))
+}
+
+// GRPCFilter converts the lines of the grpclog package, such as
+// "2024/06/01 15:04:05 INFO: [transport] transport: closing".
+func GRPCFilter(line string, tty bool) (msg string, app byte, level int) {
+	return prefixedLevel(stripStdTime(strings.TrimRight(line, "\r\n")))
+}
+
+// BadgerFilter converts the lines of the badger database, such as
+// "badger 2024/06/01 15:04:05 INFO: All 0 tables opened in 0s".
+func BadgerFilter(line string, tty bool) (msg string, app byte, level int) {
+	line = strings.TrimRight(line, "\r\n")
+	return prefixedLevel(stripStdTime(strings.TrimPrefix(line, "badger ")))
+}
+
+// HTTPServerFilter converts the lines of the ErrorLog of a net/http server,
+// such as "http: TLS handshake error from 10.0.0.1:5000: EOF". The server
+// only logs errors, which are logged as warnings. Other lines have
+// LevelKeep.
+func HTTPServerFilter(line string, tty bool) (msg string, app byte,
+	level int) {
+	msg = stripStdTime(strings.TrimRight(line, "\r\n"))
+	if strings.HasPrefix(msg, "http: ") || strings.HasPrefix(msg, "http2: ") {
+		return msg, 0, LevelWarning
+	}
+	return msg, 0, LevelKeep
+}
+
+// HclogFilter converts the lines of the default text format of hclog, such
+// as from the LogOutput of the hashicorp/raft package, like
+// "2024-06-01T15:04:05.123Z [INFO]  raft: entering follower state:
+// follower=\"Node at 10.0.0.1:8300\" leader-id=". The pairs follow the
+// message, as " key=value". Errors are logged as warnings. See the redhclog
+// package to log through the logger instead.
+func HclogFilter(line string, tty bool) (msg string, app byte, level int) {
+	line = strings.TrimRight(line, "\r\n")
+	if i := strings.Index(line, " ["); i > 0 &&
+		strings.IndexByte(line[:i], ' ') == -1 {
+		line = line[i+1:] // the timestamp
+	}
+	line, _, level = bracketedLevel(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] != ':' {
+			continue
+		}
+		if kvs, ok := hclogPairs(line[i+1:]); ok {
+			return string(appendKVs([]byte(line[:i]), kvs)), 0, level
+		}
+	}
+	return line, 0, level
+}
+
+// hclogPairs parses the " key=value" pairs that hclog writes after the
+// message, with quoted values, or returns false if s is not only pairs.
+func hclogPairs(s string) ([]interface{}, bool) {
+	var kvs []interface{}
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '=')
+		if s[0] != ' ' || i < 2 || strings.IndexByte(s[1:i], ' ') != -1 {
+			return nil, false
+		}
+		key := s[1:i]
+		s = s[i+1:]
+		end := strings.IndexByte(s, ' ')
+		if len(s) > 0 && s[0] == '"' {
+			end = 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++
+		}
+		if end == -1 || end > len(s) {
+			end = len(s)
+		}
+		val := s[:end]
+		if len(val) > 0 && val[0] == '"' {
+			v, err := strconv.Unquote(val)
+			if err != nil {
+				return nil, false
+			}
+			val = v
+		}
+		kvs = append(kvs, key, val)
+		s = s[end:]
+	}
+	return kvs, len(kvs) > 0
+}
+
+// MemberlistFilter converts the lines of the memberlist and serf packages
+// of hashicorp, which use the standard log package, such as
+// "2024/06/01 15:04:05 [WARN] memberlist: Refuting a suspect message". The
+// timestamp and level are removed, keeping the name of the package.
+// Errors are logged as warnings.
+func MemberlistFilter(line string, tty bool) (msg string, app byte,
+	level int) {
+	return bracketedLevel(stripStdTime(strings.TrimRight(line, "\r\n")))
+}
+
+// StdLogFilter converts the lines of a logger of the standard log package,
+// such as one of another library that writes to the logger, by removing
+// the date and time of the Ldate, Ltime, and Lmicroseconds flags, such as
+// "2024/06/01 15:04:05.000123 message". The file of Lshortfile and
+// Llongfile is kept. A prefix set with log.New needs the Lmsgprefix flag
+// to be after the time. Messages prefixed with a level word, such as
+// "WARNING: message", are logged at that level, and others as notices,
+// with LevelKeep.
+func StdLogFilter(line string, tty bool) (msg string, app byte, level int) {
+	return prefixedLevel(stripStdTime(strings.TrimRight(line, "\r\n")))
+}
+
+// stripStdTime removes the timestamp of the standard log package, with
+// any of its date and time flags, from the start of the line.
+func stripStdTime(line string) string {
+	isDigits := func(s string) bool {
+		for i := 0; i < len(s); i++ {
+			if s[i] < '0' || s[i] > '9' {
+				return false
+			}
+		}
+		return len(s) > 0
+	}
+	for k := 0; k < 2; k++ {
+		i := strings.IndexByte(line, ' ')
+		if i == -1 {
+			break
+		}
+		f := line[:i]
+		if len(f) == 10 && f[4] == '/' && f[7] == '/' &&
+			isDigits(f[:4]) && isDigits(f[5:7]) && isDigits(f[8:]) {
+			line = line[i+1:] // 2006/01/02
+		} else if len(f) >= 8 && f[2] == ':' && f[5] == ':' &&
+			isDigits(f[:2]) && isDigits(f[3:5]) && isDigits(f[6:8]) &&
+			(len(f) == 8 || f[8] == '.' && isDigits(f[9:])) {
+			line = line[i+1:] // 15:04:05.000000
+		} else {
+			break
+		}
+	}
+	return line
+}
+
+// prefixedLevel returns the level of a message prefixed with a level word
+// and a colon, such as "WARNING: message", or LevelKeep without one.
+func prefixedLevel(line string) (msg string, app byte, level int) {
+	if i := strings.IndexByte(line, ':'); i > 0 && i+1 < len(line) &&
+		line[i+1] == ' ' {
+		if level, ok := wordLevel(line[:i]); ok {
+			return line[i+2:], 0, level
+		}
+	}
+	return line, 0, LevelKeep
+}
+
+// bracketedLevel returns the level of a message prefixed with a level word
+// in brackets, such as "[WARN]  message", or LevelKeep without one.
+func bracketedLevel(line string) (msg string, app byte, level int) {
+	if i := strings.IndexByte(line, ']'); i > 1 && line[0] == '[' {
+		if level, ok := wordLevel(line[1:i]); ok {
+			return strings.TrimLeft(line[i+1:], " "), 0, level
+		}
+	}
+	return line, 0, LevelKeep
+}
+
+// wordLevel returns the level for a level word, such as "INFO" or "warn".
+// Errors are logged as warnings.
+func wordLevel(word string) (int, bool) {
+	switch strings.ToUpper(word) {
+	case "TRACE":
+		return LevelTrace, true
+	case "DEBUG":
+		return LevelDebug, true
+	case "INFO", "NOTICE":
+		return LevelNotice, true
+	case "WARN", "WARNING", "ERR", "ERROR", "DPANIC", "PANIC", "FATAL",
+		"CRITICAL":
+		return LevelWarning, true
+	}
+	return 0, false
+}
@@ -0,0 +1,235 @@
+package redlog
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		filter Filter
+		line   string
+		msg    string
+		level  int
+	}{
+		// etcd, zap console
+		{EtcdRaftFilter, "2024-06-01T15:04:05.123Z\tINFO\traft/raft.go:1019\t" +
+			"8e9e05c52164694d became leader at term 2\t{\"term\": 2}\n",
+			"8e9e05c52164694d became leader at term 2 {\"term\": 2}",
+			LevelNotice},
+		{EtcdRaftFilter, "2024-06-01T15:04:05.123Z\tWARN\tetcdserver/util.go:170" +
+			"\tapply request took too long",
+			"apply request took too long", LevelWarning},
+		{EtcdRaftFilter, "2024-06-01T15:04:05.123Z\tdebug\tx.go:1\tmsg\t{}",
+			"msg {}", LevelDebug},
+		// etcd, capnslog
+		{EtcdRaftFilter, "2024-06-01 15:04:05.123456 I | raft: 8e9e05c52164694d " +
+			"became follower at term 1", "raft: 8e9e05c52164694d became " +
+			"follower at term 1", LevelNotice},
+		{EtcdRaftFilter, "2024-06-01 15:04:05.123456 W | etcdserver: read-only " +
+			"range request took too long", "etcdserver: read-only range " +
+			"request took too long", LevelWarning},
+		{EtcdRaftFilter, "2024-06-01 15:04:05.123456 D | raft: sent MsgApp",
+			"raft: sent MsgApp", LevelDebug},
+		// etcd, default raft logger
+		{EtcdRaftFilter, "raft2024/06/01 15:04:05 INFO: 1 switched to " +
+			"configuration voters=(1)", "1 switched to configuration voters=(1)",
+			LevelNotice},
+		{EtcdRaftFilter, "raft2024/06/01 15:04:05 ERROR: lost leader",
+			"lost leader", LevelWarning},
+		{EtcdRaftFilter, "not | an etcd line", "not | an etcd line",
+			LevelKeep},
+		{EtcdRaftFilter, "\tINFO", "\tINFO", LevelKeep},
+		// grpc
+		{GRPCFilter, "2024/06/01 15:04:05 INFO: [core] [Channel #1] Channel " +
+			"created", "[core] [Channel #1] Channel created", LevelNotice},
+		{GRPCFilter, "2024/06/01 15:04:05 WARNING: [core] [Channel #1 SubChannel " +
+			"#2] grpc: addrConn.createTransport failed to connect\n",
+			"[core] [Channel #1 SubChannel #2] grpc: addrConn.createTransport " +
+				"failed to connect", LevelWarning},
+		{GRPCFilter, "2024/06/01 15:04:05.000123 ERROR: [transport] " +
+			"transport: loopyWriter.run returning", "[transport] transport: " +
+			"loopyWriter.run returning", LevelWarning},
+		{GRPCFilter, "INFO: [transport] closing", "[transport] closing",
+			LevelNotice},
+		{GRPCFilter, "2024/06/01 garbage: x", "garbage: x", LevelKeep},
+		{GRPCFilter, "INFO:", "INFO:", LevelKeep},
+		// net/http
+		{HTTPServerFilter, "2024/06/01 15:04:05 http: TLS handshake error " +
+			"from 10.0.0.1:51234: EOF\n", "http: TLS handshake error from " +
+			"10.0.0.1:51234: EOF", LevelWarning},
+		{HTTPServerFilter, "http: panic serving 10.0.0.1:51234: runtime error",
+			"http: panic serving 10.0.0.1:51234: runtime error", LevelWarning},
+		{HTTPServerFilter, "2024/06/01 15:04:05 http2: server: error reading " +
+			"preface from client 10.0.0.1:51234: EOF", "http2: server: error " +
+			"reading preface from client 10.0.0.1:51234: EOF", LevelWarning},
+		{HTTPServerFilter, "15:04:05 something else", "something else",
+			LevelKeep},
+		// badger
+		{BadgerFilter, "badger 2024/06/01 15:04:05 INFO: All 0 tables opened " +
+			"in 0s\n", "All 0 tables opened in 0s", LevelNotice},
+		{BadgerFilter, "badger 2024/06/01 15:04:05 WARNING: While forcing " +
+			"compaction on level 0: no tables to compact", "While forcing " +
+			"compaction on level 0: no tables to compact", LevelWarning},
+		{BadgerFilter, "badger 2024/06/01 15:04:05 DEBUG: Value log discard " +
+			"stats empty", "Value log discard stats empty", LevelDebug},
+		{BadgerFilter, "badger 2024-06-01 15:04 x", "2024-06-01 15:04 x",
+			LevelKeep},
+		{BadgerFilter, "badger", "badger", LevelKeep},
+		// hclog
+		{HclogFilter, "2024-06-01T15:04:05.123Z [INFO]  raft: entering " +
+			"follower state: follower=\"Node at 10.0.0.1:8300 [Follower]\" " +
+			"leader-address= leader-id=\n", "raft: entering follower state " +
+			"follower=\"Node at 10.0.0.1:8300 [Follower]\" leader-address=\"\" " +
+			"leader-id=\"\"", LevelNotice},
+		{HclogFilter, "2024-06-01T15:04:05.123+0200 [WARN]  raft: heartbeat " +
+			"timeout reached, starting election: last-leader-addr=10.0.0.2:8300",
+			"raft: heartbeat timeout reached, starting election " +
+				"last-leader-addr=10.0.0.2:8300", LevelWarning},
+		{HclogFilter, "2024-06-01T15:04:05.123Z [ERROR] raft: failed to " +
+			"contact: server-id=b error=\"dial tcp: i/o \\\"timeout\\\"\"",
+			"raft: failed to contact server-id=b error=\"dial tcp: i/o " +
+				"\\\"timeout\\\"\"", LevelWarning},
+		{HclogFilter, "2024-06-01T15:04:05.123Z [DEBUG] raft: time: 15:04",
+			"raft: time: 15:04", LevelDebug},
+		{HclogFilter, "[TRACE] x: a=\"b", "x: a=\"b", LevelTrace},
+		{HclogFilter, "no level: a=b c", "no level: a=b c", LevelKeep},
+		// memberlist and serf
+		{MemberlistFilter, "2024/06/01 15:04:05 [WARN] memberlist: Was able " +
+			"to connect to node-2 but other probes failed\n", "memberlist: Was " +
+			"able to connect to node-2 but other probes failed", LevelWarning},
+		{MemberlistFilter, "2024/06/01 15:04:05 [ERR] memberlist: Failed to " +
+			"send ping: write udp: i/o timeout", "memberlist: Failed to send " +
+			"ping: write udp: i/o timeout", LevelWarning},
+		{MemberlistFilter, "2024/06/01 15:04:05 [INFO] serf: EventMemberJoin: " +
+			"node-2 10.0.0.2", "serf: EventMemberJoin: node-2 10.0.0.2",
+			LevelNotice},
+		{MemberlistFilter, "[DEBUG] memberlist: Stream connection from=" +
+			"10.0.0.2:7946", "memberlist: Stream connection from=10.0.0.2:7946",
+			LevelDebug},
+		{MemberlistFilter, "[x] memberlist", "[x] memberlist", LevelKeep},
+		// standard log package
+		{StdLogFilter, "2024/06/01 15:04:05 connected\n", "connected",
+			LevelKeep},
+		{StdLogFilter, "2024/06/01 15:04:05.123456 main.go:42: retrying",
+			"main.go:42: retrying", LevelKeep},
+		{StdLogFilter, "15:04:05 WARNING: disk full", "disk full",
+			LevelWarning},
+		{StdLogFilter, "2024/06/01 app: error: failed", "app: error: failed",
+			LevelKeep},
+		{StdLogFilter, "plain", "plain", LevelKeep},
+	}
+	for i, tt := range tests {
+		msg, app, level := tt.filter(tt.line, false)
+		if msg != tt.msg || app != 0 || level != tt.level {
+			t.Fatalf("%d: expected %q %d, got %q %d", i, tt.msg, tt.level, msg,
+				level)
+		}
+	}
+}
+
+func TestFilterFor(t *testing.T) {
+	for _, name := range []string{"raft", "etcd", "GRPC", "http", "badger",
+		"hclog", "memberlist", "serf", "log"} {
+		if FilterFor(name) == nil {
+			t.Fatalf("expected a filter for %q", name)
+		}
+	}
+	if FilterFor("zap") != nil {
+		t.Fatal("expected nil")
+	}
+	msg, _, level := FilterFor("grpc")("WARNING: x", false)
+	if msg != "x" || level != LevelWarning {
+		t.Fatalf("unexpected result %q %d", msg, level)
+	}
+}
+
+func TestFilterShortInput(t *testing.T) {
+	for _, line := range []string{
+		"", " ", "[", "\"[", "]", "[]", "x [", "x ]", "x []", "\t", "|", " | ",
+		"I | ", "\t\t\t", "INFO:", ":", "2024/06/01", "15:04:05.", "badger ",
+		"raft1", "\xff[\xfe]", "[]", "x [WARN]", ": a=", ": a=\"", ": a=\"\\",
+	} {
+		for _, filter := range []Filter{HashicorpRaftFilter, EtcdRaftFilter,
+			GRPCFilter, HTTPServerFilter, BadgerFilter, HclogFilter,
+			MemberlistFilter, StdLogFilter} {
+			for _, tty := range []bool{false, true} {
+				filter(line, tty)
+			}
+		}
+		colorizeLine(line)
+		logPostFilter(line)
+	}
+}
+
+func TestChainFilters(t *testing.T) {
+	noise := func(line string, tty bool) (string, byte, int) {
+		if strings.Contains(line, "heartbeat") {
+			return line, 0, LevelDrop
+		}
+		if strings.HasPrefix(line, "raft: ") {
+			return line[6:], 'R', LevelKeep
+		}
+		return line, 0, LevelKeep
+	}
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelDebug, Format: FormatPlain,
+		Filter: GRPCFilter, Filters: []Filter{nil, noise}})
+	fmt.Fprintln(l, "WARNING: raft: failed to contact")
+	fmt.Fprintln(l, "DEBUG: sent heartbeat")
+	fmt.Fprintln(l, "DEBUG: raft: sent append")
+	exp := "warning: failed to contact\n" + "sent append\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+	msg, app, level := ChainFilters(GRPCFilter, noise)("DEBUG: raft: x", false)
+	if msg != "x" || app != 'R' || level != LevelDebug {
+		t.Fatalf("unexpected result %q %c %d", msg, app, level)
+	}
+}
+
+func TestChainFiltersDemote(t *testing.T) {
+	demote := func(line string, tty bool) (string, byte, int) {
+		if strings.Contains(line, "heartbeat") {
+			return line, 0, LevelNotice
+		}
+		return line, 0, LevelKeep
+	}
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Format: FormatPlain, Filter: HclogFilter,
+		Filters: []Filter{demote, DropFilter("ping")}})
+	fmt.Fprintln(l, "[WARN]  raft: heartbeat timeout reached")
+	fmt.Fprintln(l, "[WARN]  raft: failed to contact")
+	fmt.Fprintln(l, "[INFO]  raft: ping")
+	fmt.Fprintln(l, "no level")
+	exp := "raft: heartbeat timeout reached\n" +
+		"warning: raft: failed to contact\n" + "no level\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
+
+func TestDropFilter(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Format: FormatPlain, Filter: GRPCFilter,
+		Filters: []Filter{DropFilter("heartbeat", "ping")}})
+	fmt.Fprintln(l, "WARNING: sent heartbeat")
+	fmt.Fprintln(l, "WARNING: failed")
+	fmt.Fprintln(l, "got ping")
+	if exp := "warning: failed\n"; buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
+
+func TestStdLogFilter(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Format: FormatPlain, Filter: StdLogFilter})
+	log.New(l, "", log.LstdFlags|log.Lmicroseconds).Print("WARNING: slow")
+	log.New(l, "db: ", log.LstdFlags|log.Lmsgprefix).Print("opened")
+	if exp := "warning: slow\n" + "db: opened\n"; buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
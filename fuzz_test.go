@@ -0,0 +1,65 @@
+//go:build go1.18
+// +build go1.18
+
+package redlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzHashicorpRaftFilter(f *testing.F) {
+	for _, s := range []string{
+		"", "[", "\"[", "[]", " [", "x [W]", "15:04:05 [INFO] raft: hello",
+		"15:04:05 [WARN] raft: entering follower state:",
+	} {
+		f.Add(s, false)
+	}
+	f.Fuzz(func(t *testing.T, line string, tty bool) {
+		HashicorpRaftFilter(line, tty)
+		HashicorpRaftFilterDropping("x")(line, tty)
+		for _, filter := range []Filter{EtcdRaftFilter, GRPCFilter,
+			HTTPServerFilter, BadgerFilter} {
+			filter(line, tty)
+		}
+	})
+}
+
+func FuzzRedisLogColorizerLine(f *testing.F) {
+	for _, s := range []string{
+		"", ":", " ", "1:M", "1:M 01 Jun 2024 15:04:05.000 * hello\n",
+		"1:M 01-06 15:04:05.000 # hello\n", "1:M - - - - . x",
+		"1:M[w1] 01 Jun 2024 15:04:05.000 * hello\n",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		colorizeLine(line)
+		logPostFilter(line)
+		parseLine(line, TimeFormatRedis)
+	})
+}
+
+func FuzzWrite(f *testing.F) {
+	for _, s := range []string{
+		"", "\n", "hello\n", "1:M 01 Jun 2024 15:04:05.000 * hello\n",
+		"15:04:05 [TRACE] raft: sent\n", "\xff\xfe\n",
+	} {
+		f.Add([]byte(s))
+	}
+	opts := []*Options{
+		{Level: LevelTrace, Color: ColorAlways, Pretty: true,
+			CondenseTimestamps: true},
+		{Level: LevelTrace, PassthroughFormatted: true, Color: ColorAlways},
+		{Level: LevelTrace, Filter: HashicorpRaftFilter, Format: FormatPlain,
+			Color: ColorAlways},
+		{Level: LevelTrace, Encoder: TextCodec{}},
+	}
+	f.Fuzz(func(t *testing.T, p []byte) {
+		for _, o := range opts {
+			l := New(nopWriter{}, o)
+			l.Write(p)
+			l.ReadFrom(bytes.NewReader(p))
+		}
+	})
+}
@@ -2,4 +2,9 @@ module github.com/tidwall/redlog/v2
 
 go 1.15
 
-require golang.org/x/crypto v0.0.0-20201116153603-4be66e5b6582
+require (
+	github.com/stretchr/testify v1.8.0 // indirect
+	go.uber.org/goleak v1.1.12
+	golang.org/x/crypto v0.0.0-20201116153603-4be66e5b6582
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+)
@@ -0,0 +1,55 @@
+package redlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GoKitLogger logs the key value pairs of go-kit. It implements the
+// log.Logger of go-kit, without depending on it. The "level" key sets the
+// level, which is notice without one, and the "msg" key is the message.
+// The other pairs are appended as " key=value".
+type GoKitLogger struct {
+	l *Logger
+}
+
+// NewGoKitLogger returns a GoKitLogger that logs to l.
+func NewGoKitLogger(l *Logger) *GoKitLogger {
+	return &GoKitLogger{l}
+}
+
+// Log logs the key value pairs.
+func (g *GoKitLogger) Log(keyvals ...interface{}) error {
+	level := LevelNotice
+	var msg string
+	kvs := make([]interface{}, 0, len(keyvals))
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{}
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		switch fmt.Sprint(keyvals[i]) {
+		case "level":
+			word := fmt.Sprint(v)
+			if strings.EqualFold(word, "error") {
+				level = levelError
+			} else if lv, ok := wordLevel(word); ok {
+				level = lv
+			}
+			continue
+		case "msg":
+			if msg == "" {
+				msg = fmt.Sprint(v)
+				continue
+			}
+		}
+		kvs = append(kvs, keyvals[i])
+		if i+1 < len(keyvals) {
+			kvs = append(kvs, v)
+		}
+	}
+	if g.l.enabled(level) && g.l.sampled(level, msg) {
+		writeKVs(g.l, level, msg, kvs)
+	}
+	return nil
+}
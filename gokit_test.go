@@ -0,0 +1,35 @@
+package redlog
+
+import (
+	"strings"
+	"testing"
+)
+
+// levelValue is a level of the go-kit level package.
+type levelValue string
+
+func (v levelValue) String() string { return string(v) }
+
+func TestGoKitLogger(t *testing.T) {
+	l, lines := capture(&Options{Level: LevelNotice})
+	g := NewGoKitLogger(l)
+	g.Log("level", levelValue("debug"), "msg", "hidden")
+	g.Log("msg", "started", "port", 6379, "name", "node 1")
+	g.Log("level", levelValue("warn"), "msg", "slow", "msg", "again")
+	g.Log("level", levelValue("error"), "err", "eof", "odd")
+	g.Log("method", "GET")
+
+	got := lines()
+	want := []string{
+		`* started port=6379 name="node 1"`,
+		`# slow msg=again`,
+		`# err=eof odd=`,
+		`* method=GET`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", strings.Join(got, "\n"))
+	}
+	if l.Stats().Error != 1 {
+		t.Fatalf("expected one error, got %+v", l.Stats())
+	}
+}
@@ -0,0 +1,79 @@
+package redlog
+
+import "fmt"
+
+// GRPCLogger logs the messages of gRPC. It implements grpclog.LoggerV2,
+// without depending on gRPC, for grpclog.SetLoggerV2:
+//
+//	grpclog.SetLoggerV2(redlog.NewGRPCLogger(l))
+//
+// Info is logged as verbose, and Error and Fatal at the error level.
+type GRPCLogger struct {
+	l *Logger
+}
+
+// NewGRPCLogger returns a GRPCLogger that logs to l.
+func NewGRPCLogger(l *Logger) *GRPCLogger {
+	return &GRPCLogger{l}
+}
+
+// sprintln formats the args like fmt.Sprintln, without the newline.
+func sprintln(args []interface{}) string {
+	s := fmt.Sprintln(args...)
+	return s[:len(s)-1]
+}
+
+// Info logs at the verbose level.
+func (g *GRPCLogger) Info(args ...interface{}) { g.l.Verb(args...) }
+
+// Infoln logs at the verbose level.
+func (g *GRPCLogger) Infoln(args ...interface{}) { g.l.Verb(sprintln(args)) }
+
+// Infof logs at the verbose level.
+func (g *GRPCLogger) Infof(format string, args ...interface{}) {
+	g.l.Verbf(format, args...)
+}
+
+// Warning logs at the warning level.
+func (g *GRPCLogger) Warning(args ...interface{}) { g.l.Warning(args...) }
+
+// Warningln logs at the warning level.
+func (g *GRPCLogger) Warningln(args ...interface{}) {
+	g.l.Warning(sprintln(args))
+}
+
+// Warningf logs at the warning level.
+func (g *GRPCLogger) Warningf(format string, args ...interface{}) {
+	g.l.Warningf(format, args...)
+}
+
+// Error logs at the error level.
+func (g *GRPCLogger) Error(args ...interface{}) { g.l.Error(args...) }
+
+// Errorln logs at the error level.
+func (g *GRPCLogger) Errorln(args ...interface{}) { g.l.Error(sprintln(args)) }
+
+// Errorf logs at the error level.
+func (g *GRPCLogger) Errorf(format string, args ...interface{}) {
+	g.l.Errorf(format, args...)
+}
+
+// Fatal logs at the error level and exits, like Logger.Fatal.
+func (g *GRPCLogger) Fatal(args ...interface{}) { g.l.Fatal(args...) }
+
+// Fatalln logs at the error level and exits, like Logger.Fatal.
+func (g *GRPCLogger) Fatalln(args ...interface{}) { g.l.Fatal(sprintln(args)) }
+
+// Fatalf logs at the error level and exits, like Logger.Fatal.
+func (g *GRPCLogger) Fatalf(format string, args ...interface{}) {
+	g.l.Fatalf(format, args...)
+}
+
+// V reports whether the verbosity level is logged. Level 0 is logged at
+// the verbose level, and higher levels at the debug level.
+func (g *GRPCLogger) V(level int) bool {
+	if level <= 0 {
+		return g.l.minLevel() <= LevelVerbose
+	}
+	return g.l.minLevel() <= LevelDebug
+}
@@ -0,0 +1,52 @@
+package redlog
+
+import (
+	"strings"
+	"testing"
+)
+
+// grpcLoggerV2 is grpclog.LoggerV2.
+type grpcLoggerV2 interface {
+	Info(args ...interface{})
+	Infoln(args ...interface{})
+	Infof(format string, args ...interface{})
+	Warning(args ...interface{})
+	Warningln(args ...interface{})
+	Warningf(format string, args ...interface{})
+	Error(args ...interface{})
+	Errorln(args ...interface{})
+	Errorf(format string, args ...interface{})
+	Fatal(args ...interface{})
+	Fatalln(args ...interface{})
+	Fatalf(format string, args ...interface{})
+	V(l int) bool
+}
+
+func TestGRPCLogger(t *testing.T) {
+	var exits int
+	l, lines := capture(&Options{Level: LevelVerbose,
+		ExitFunc: func(int) { exits++ }})
+	var g grpcLoggerV2 = NewGRPCLogger(l)
+	g.Info("channel", 1)
+	g.Infoln("channel", 2)
+	g.Infof("channel %d", 3)
+	g.Warningln("reconnecting", "in", 1)
+	g.Errorf("failed: %s", "eof")
+	g.Fatalln("closing", 1)
+	if !g.V(0) || g.V(2) || exits != 1 {
+		t.Fatalf("unexpected verbosity or exits %d", exits)
+	}
+
+	got := lines()
+	want := []string{
+		`- channel1`,
+		`- channel 2`,
+		`- channel 3`,
+		`# reconnecting in 1`,
+		`# failed: eof`,
+		`# closing 1`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", strings.Join(got, "\n"))
+	}
+}
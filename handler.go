@@ -0,0 +1,89 @@
+package redlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Handler returns an http.Handler for viewing and changing the state of
+// the logger at runtime. It may be mounted under any path.
+//
+//	GET            returns the level, counters, and output as JSON
+//	GET ?tail=100  returns the last lines of the crash ring as text
+//	PUT or POST    changes the level using a body like {"level":"debug"}
+func (l *Logger) Handler() http.Handler {
+	return http.HandlerFunc(l.serveHTTP)
+}
+
+type handlerState struct {
+	Level   string            `json:"level"`
+	Counts  map[string]uint64 `json:"counts"`
+	Output  string            `json:"output"`
+	Dropped uint64            `json:"dropped"`
+}
+
+func (l *Logger) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if tail := r.URL.Query().Get("tail"); tail != "" {
+			l.serveTail(w, tail)
+			return
+		}
+	case http.MethodPut, http.MethodPost:
+		var req struct {
+			Level string `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		level, err := parseLevel(req.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		l.SetLevel(level)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed),
+			http.StatusMethodNotAllowed)
+		return
+	}
+	stats := l.Stats()
+	state := handlerState{
+		Level: levelNames[l.minLevel()-LevelTrace],
+		Counts: map[string]uint64{
+			"trace":   stats.Trace,
+			"debug":   stats.Debug,
+			"verbose": stats.Verbose,
+			"notice":  stats.Notice,
+			"warning": stats.Warning,
+			"error":   stats.Error,
+		},
+		Output:  l.outputType(),
+		Dropped: stats.Dropped,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+func (l *Logger) serveTail(w http.ResponseWriter, tail string) {
+	n, err := strconv.Atoi(tail)
+	if err != nil || n < 0 {
+		http.Error(w, "invalid tail", http.StatusBadRequest)
+		return
+	}
+	if l.ring == nil {
+		http.Error(w, "crash ring not enabled", http.StatusNotFound)
+		return
+	}
+	// copied under the lock, so a slow client cannot block logging
+	var buf bytes.Buffer
+	l.mu.Lock()
+	l.ring.writeTo(&buf, n)
+	l.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,156 @@
+package redlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func doRequest(t *testing.T, h http.Handler, method, target,
+	body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target,
+		strings.NewReader(body)))
+	return w
+}
+
+func TestHandlerGet(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, nil)
+	l.Noticef("notice")
+	l.Warningf("warning")
+	l.Warningf("warning")
+	w := doRequest(t, l.Handler(), "GET", "/", "")
+	if w.Code != 200 {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	var state handlerState
+	if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Level != "notice" || state.Counts["notice"] != 1 ||
+		state.Counts["warning"] != 2 || state.Counts["debug"] != 0 ||
+		state.Output != "*bytes.Buffer" || state.Dropped != 0 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+}
+
+func TestHandlerSetLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, nil)
+	h := l.Handler()
+	for _, method := range []string{"PUT", "POST"} {
+		w := doRequest(t, h, method, "/", `{"level":"debug"}`)
+		if w.Code != 200 || !strings.Contains(w.Body.String(),
+			`"level":"debug"`) {
+			t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+		}
+		l.Debugf("debug")
+		if !strings.Contains(buf.String(), ". debug") {
+			t.Fatalf("unexpected output %q", buf.String())
+		}
+		l.SetLevel(LevelNotice)
+	}
+	w := doRequest(t, h, "PUT", "/", `{"level":"loud"}`)
+	if w.Code != 400 || !strings.Contains(w.Body.String(),
+		`invalid level "loud"`) {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	w = doRequest(t, h, "PUT", "/", `level=debug`)
+	if w.Code != 400 {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	w = doRequest(t, h, "DELETE", "/", "")
+	if w.Code != 405 {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+}
+
+func TestHandlerTail(t *testing.T) {
+	l := New(nil, &Options{Level: LevelNotice, CrashRing: 10})
+	for i := 0; i < 5; i++ {
+		l.Debugf("debug %d", i)
+	}
+	w := doRequest(t, l.Handler(), "GET", "/?tail=2", "")
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if w.Code != 200 || len(lines) != 2 ||
+		!strings.HasSuffix(lines[0], "debug 3") ||
+		!strings.HasSuffix(lines[1], "debug 4") {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	w = doRequest(t, New(nil, nil).Handler(), "GET", "/?tail=2", "")
+	if w.Code != 404 {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+	w = doRequest(t, l.Handler(), "GET", "/?tail=x", "")
+	if w.Code != 400 {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+}
+
+// stalledWriter is a ResponseWriter of a client that stops reading.
+type stalledWriter struct {
+	*httptest.ResponseRecorder
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (w *stalledWriter) Write(p []byte) (int, error) {
+	close(w.writing)
+	<-w.release
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestHandlerTailStalled(t *testing.T) {
+	l := New(nil, &Options{Level: LevelNotice, CrashRing: 10})
+	l.Noticef("before")
+	w := &stalledWriter{httptest.NewRecorder(), make(chan struct{}),
+		make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		l.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/?tail=1", nil))
+		close(done)
+	}()
+	<-w.writing
+	l.Noticef("during") // blocks if the lock is held
+	close(w.release)
+	<-done
+	if !strings.HasSuffix(w.Body.String(), "before\n") {
+		t.Fatalf("unexpected response %q", w.Body.String())
+	}
+}
+
+func TestHandlerConcurrent(t *testing.T) {
+	l := New(nopWriter{}, nil)
+	h := l.Handler()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				level := levelNames[(i+j)%len(levelNames)]
+				doRequest(t, h, "PUT", "/", `{"level":"`+level+`"}`)
+				doRequest(t, h, "GET", "/", "")
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Debugf("debug")
+				l.Noticef("notice")
+			}
+		}()
+	}
+	wg.Wait()
+	doRequest(t, h, "PUT", "/", `{"level":"verbose"}`)
+	w := doRequest(t, h, "GET", "/", "")
+	if !strings.Contains(w.Body.String(), `"level":"verbose"`) {
+		t.Fatalf("unexpected response %q", w.Body.String())
+	}
+}
@@ -0,0 +1,23 @@
+package redlog
+
+// AddHook adds a func that is called with the record of every line that
+// is logged, after the level and sampling, such as for forwarding
+// warnings to an alerting system. Hooks are called in the order they were
+// added, by the goroutine that logs, after the line is written, so they
+// should be quick and must not log to the same logger.
+//
+// The pairs of With, Caller, and the like are in the KVs of the record.
+// For the text format, the Msg includes the pairs of the call, such as
+// those of the adapters, which are also in the KVs.
+func (l *Logger) AddHook(hook func(rec Record)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	hooks := l.loadHooks()
+	l.hooks.Store(append(hooks[:len(hooks):len(hooks)], hook))
+}
+
+// loadHooks returns the hooks of AddHook, or nil.
+func (c *core) loadHooks() []func(Record) {
+	hooks, _ := c.hooks.Load().([]func(Record))
+	return hooks
+}
@@ -0,0 +1,46 @@
+package redlog
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddHook(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelVerbose})
+	var recs []Record
+	var warnings int
+	l.AddHook(func(rec Record) { recs = append(recs, rec) })
+	l.AddHook(func(rec Record) {
+		if rec.Level >= LevelWarning {
+			warnings++
+		}
+	})
+	l.Debugf("hidden")
+	l.With("shard", 3).Verbf("loaded")
+	l.Warningf("slow")
+	l.Errorf("failed")
+	sampled := l.SampledEvery(2)
+	sampled.Noticef("sampled %d", 1)
+	sampled.Noticef("sampled %d", 2)
+	if len(recs) != 4 || warnings != 2 {
+		t.Fatalf("unexpected records %+v", recs)
+	}
+	if recs[0].Msg != "loaded" || recs[0].Level != LevelVerbose ||
+		!reflect.DeepEqual(recs[0].KVs, []interface{}{"shard", 3}) ||
+		recs[1].Msg != "slow" || recs[2].Level != levelError ||
+		!strings.HasPrefix(recs[3].Msg, "sampled") {
+		t.Fatalf("unexpected records %+v", recs)
+	}
+
+	// hooks get the records of encoders too
+	recs = nil
+	l.SetFormat(FormatJSON)
+	l.Noticef("json")
+	if len(recs) != 1 || recs[0].Msg != "json" ||
+		!strings.Contains(buf.String(), `"msg":"json"`) {
+		t.Fatalf("unexpected records %+v", recs)
+	}
+}
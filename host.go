@@ -0,0 +1,66 @@
+package redlog
+
+import (
+	"os"
+	"strings"
+)
+
+// SetRole sets the role of the node, such as "leader", which is added to
+// the prefix after the hostname, such as "1234:M@node-3/leader". An empty
+// role removes it. It's cheap enough to call on every role change.
+func (l *Logger) SetRole(role string) {
+	l.hostmu.Lock()
+	defer l.hostmu.Unlock()
+	l.role = sanitizeTag(role)
+	l.storeHostTag()
+}
+
+// Role returns the role set by SetRole or the Role option.
+func (l *Logger) Role() string {
+	l.hostmu.Lock()
+	defer l.hostmu.Unlock()
+	return l.role
+}
+
+// initHost sets the hostname and role from the options.
+func (l *Logger) initHost(opts *Options) {
+	host := opts.Hostname
+	if host == "" && opts.IncludeHostname {
+		host, _ = os.Hostname()
+	}
+	l.hostname = sanitizeTag(host)
+	l.role = sanitizeTag(opts.Role)
+	l.storeHostTag()
+}
+
+// storeHostTag precomputes the "@host/role" fragment of the prefix. It's
+// called with hostmu held.
+func (l *Logger) storeHostTag() {
+	var tag []byte
+	if l.hostname != "" || l.role != "" {
+		tag = append(tag, '@')
+		tag = append(tag, l.hostname...)
+		if l.role != "" {
+			tag = append(tag, '/')
+			tag = append(tag, l.role...)
+		}
+	}
+	l.hostTag.Store(tag)
+}
+
+// loadHostTag returns the "@host/role" fragment, or nil.
+func (l *Logger) loadHostTag() []byte {
+	tag, _ := l.hostTag.Load().([]byte)
+	return tag
+}
+
+// sanitizeTag replaces the characters that would break parsing of the
+// prefix, such as spaces.
+func sanitizeTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '[' || r == ']' || r == '@' {
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,98 @@
+package redlog
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHostnameRole(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Hostname: "node-3", Role: "follower"})
+	l.pid = 1234
+	l.Noticef("one")
+	l.SetRole("leader")
+	l.WithLabel("w1").Noticef("two")
+	l.SetRole("")
+	l.Noticef("three")
+	l.SetRole("candidate role")
+	l.Warningf("four")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, prefix := range []string{
+		"1234:M@node-3/follower ", "1234:M@node-3/leader[w1] ",
+		"1234:M@node-3 ", "1234:M@node-3/candidate_role ",
+	} {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Fatalf("line %d: expected %q, got %q", i, prefix, lines[i])
+		}
+		pl, ok := parseLine(lines[i], DefaultOptions.TimeFormat)
+		if !ok || pl.pid != 1234 || pl.app != 'M' ||
+			pl.tags != prefix[6:len(prefix)-1] {
+			t.Fatalf("line %d: unexpected result %v %+v", i, ok, pl)
+		}
+		if out := colorizeLine(lines[i] + "\n"); !strings.HasPrefix(out,
+			"\x1b[35m"+prefix[:len(prefix)-1]+"\x1b[0m") {
+			t.Fatalf("unexpected colorized line %q", out)
+		}
+	}
+	rd := NewReader(strings.NewReader(buf.String()), nil)
+	for i := 0; i < 4; i++ {
+		if rec, err := rd.Read(); err != nil || rec.Pid != 1234 {
+			t.Fatalf("unexpected record %+v %v", rec, err)
+		}
+	}
+	if l.Role() != "candidate_role" {
+		t.Fatalf("unexpected role %q", l.Role())
+	}
+
+	buf.Reset()
+	l = New(buf, &Options{IncludeHostname: true, Role: "leader"})
+	l.Noticef("hello")
+	host, _ := os.Hostname()
+	if !strings.Contains(buf.String(), ":M@"+sanitizeTag(host)+"/leader ") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestSetRoleConcurrent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Hostname: "n1"})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			l.SetRole([]string{"leader", "follower"}[i%2])
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			l.Noticef("hello")
+		}
+	}()
+	wg.Wait()
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.Contains(line, ":M@n1 ") &&
+			!strings.Contains(line, ":M@n1/leader ") &&
+			!strings.Contains(line, ":M@n1/follower ") {
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
+
+func TestHostnameRecord(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Level: LevelNotice, Hostname: "node-3",
+		Format: FormatLogfmt})
+	l.Noticef("hello")
+	l.SetRole("leader")
+	l.Noticef("elected")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], " host=node-3") ||
+		!strings.HasSuffix(lines[1], " host=node-3/leader") {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+}
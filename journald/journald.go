@@ -0,0 +1,114 @@
+// Package journald sends redlog records to the systemd journal using its
+// native protocol.
+//
+// The Encoder is set as the Encoder option of a logger. When the journal
+// socket is not available, such as in a container, records are written to
+// the logger's writer in the text format instead.
+package journald
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/redlog/v2"
+)
+
+// DefaultSocket is the socket of the journal.
+const DefaultSocket = "/run/systemd/journal/socket"
+
+// Options for NewEncoder.
+type Options struct {
+	// Identifier is sent as SYSLOG_IDENTIFIER, such as the program name.
+	Identifier string
+	// Socket is the journal socket. Defaults to DefaultSocket.
+	Socket string
+	// Fallback encodes the records when the journal is not available.
+	// Defaults to redlog.TextCodec.
+	Fallback redlog.Encoder
+}
+
+// priority maps the levels, from trace to error, to syslog priorities.
+var priority = []string{"7", "7", "6", "5", "4", "3"}
+
+func levelPriority(level int) string {
+	i := level - redlog.LevelTrace
+	if i < 0 {
+		i = 0
+	} else if i >= len(priority) {
+		i = len(priority) - 1
+	}
+	return priority[i]
+}
+
+// appendField appends a field in the native protocol. Values with a
+// newline are sent with an explicit length.
+func appendField(b []byte, key, value string) []byte {
+	b = append(b, key...)
+	if !strings.Contains(value, "\n") {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	b = append(b, '\n')
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(value)))
+	b = append(b, n[:]...)
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// appendRecord appends the fields of a record. Key value pairs are sent
+// as fields with upper case names.
+func (e *Encoder) appendRecord(b []byte, rec redlog.Record) []byte {
+	b = appendField(b, "MESSAGE", rec.Msg)
+	b = appendField(b, "PRIORITY", levelPriority(rec.Level))
+	if e.opts.Identifier != "" {
+		b = appendField(b, "SYSLOG_IDENTIFIER", e.opts.Identifier)
+	}
+	b = appendField(b, "SYSLOG_PID", strconv.Itoa(rec.Pid))
+	b = appendField(b, "REDLOG_APP", string(rec.App))
+	for i := 0; i+1 < len(rec.KVs); i += 2 {
+		if key := fieldName(rec.KVs[i]); key != "" {
+			b = appendField(b, key, toString(rec.KVs[i+1]))
+		}
+	}
+	return b
+}
+
+// fieldName returns a valid journal field name for a key, or an empty
+// string. Names are upper case letters, digits, and underscores, and do
+// not start with an underscore.
+func fieldName(key interface{}) string {
+	s, ok := key.(string)
+	if !ok {
+		return ""
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_':
+		default:
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	for len(b) > 0 && b[0] == '_' {
+		b = b[1:]
+	}
+	if len(b) == 0 || b[0] >= '0' && b[0] <= '9' {
+		return ""
+	}
+	return string(b)
+}
+
+func toString(v interface{}) string {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(v)
+}
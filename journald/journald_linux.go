@@ -0,0 +1,103 @@
+package journald
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/tidwall/redlog/v2"
+)
+
+// Encoder sends records to the journal.
+type Encoder struct {
+	opts Options
+	addr *net.UnixAddr
+
+	mu   sync.Mutex
+	conn *net.UnixConn // nil when the journal is not available
+	buf  []byte
+}
+
+// NewEncoder returns an encoder that sends records to the journal. The
+// Fallback encoder is used when the socket does not exist, and for records
+// that cannot be sent.
+func NewEncoder(opts *Options) *Encoder {
+	e := &Encoder{}
+	if opts != nil {
+		e.opts = *opts
+	}
+	if e.opts.Socket == "" {
+		e.opts.Socket = DefaultSocket
+	}
+	if e.opts.Fallback == nil {
+		e.opts.Fallback = redlog.TextCodec{}
+	}
+	e.addr = &net.UnixAddr{Name: e.opts.Socket, Net: "unixgram"}
+	if _, err := os.Stat(e.opts.Socket); err == nil {
+		conn, err := net.ListenUnixgram("unixgram",
+			&net.UnixAddr{Net: "unixgram"})
+		if err == nil {
+			e.conn = conn
+		}
+	}
+	return e
+}
+
+// Available returns true if records are sent to the journal.
+func (e *Encoder) Available() bool {
+	return e.conn != nil
+}
+
+// Encode sends the record to the journal, or encodes it to w with the
+// Fallback encoder.
+func (e *Encoder) Encode(w io.Writer, rec redlog.Record) error {
+	if e.conn == nil {
+		return e.opts.Fallback.Encode(w, rec)
+	}
+	e.mu.Lock()
+	e.buf = e.appendRecord(e.buf[:0], rec)
+	_, _, err := e.conn.WriteMsgUnix(e.buf, nil, e.addr)
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		err = e.sendFile(e.buf)
+	}
+	e.mu.Unlock()
+	if err != nil {
+		return e.opts.Fallback.Encode(w, rec)
+	}
+	return nil
+}
+
+// sendFile sends a large datagram by passing the descriptor of a deleted
+// temporary file holding it, as required by the protocol.
+func (e *Encoder) sendFile(b []byte) error {
+	f, err := ioutil.TempFile("/dev/shm", "redlog-journal-")
+	if err != nil {
+		f, err = ioutil.TempFile("", "redlog-journal-")
+		if err != nil {
+			return err
+		}
+	}
+	defer f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	_, _, err = e.conn.WriteMsgUnix(nil, rights, e.addr)
+	return err
+}
+
+// Close closes the connection to the journal. It's not closed by the
+// logger.
+func (e *Encoder) Close() error {
+	if e.conn == nil {
+		return nil
+	}
+	return e.conn.Close()
+}
@@ -0,0 +1,99 @@
+package journald
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/tidwall/redlog/v2"
+)
+
+// The tests using sockets only run when REDLOG_JOURNALD is set.
+func listen(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+	if os.Getenv("REDLOG_JOURNALD") == "" {
+		t.Skip("REDLOG_JOURNALD not set")
+	}
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram",
+		&net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, path
+}
+
+// readDatagram reads a datagram, or the file passed with it.
+func readDatagram(t *testing.T, conn *net.UnixConn) []byte {
+	t.Helper()
+	b := make([]byte, 1<<20)
+	oob := make([]byte, 1024)
+	n, oobn, _, _, err := conn.ReadMsgUnix(b, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oobn == 0 {
+		return b[:n]
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("unexpected control messages %v %v", msgs, err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("unexpected rights %v %v", fds, err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestJournal(t *testing.T) {
+	conn, path := listen(t)
+	e := NewEncoder(&Options{Socket: path, Identifier: "myapp"})
+	defer e.Close()
+	if !e.Available() {
+		t.Fatal("expected the journal to be available")
+	}
+	buf := &bytes.Buffer{}
+	l := redlog.New(buf, &redlog.Options{Level: redlog.LevelDebug,
+		App: 'S', Encoder: e})
+	l.Debugf("hello")
+	l.Errorf("failed")
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output, got %q", buf.String())
+	}
+	fields := parseFields(t, readDatagram(t, conn))
+	if fields["MESSAGE"] != "hello" || fields["PRIORITY"] != "7" ||
+		fields["SYSLOG_IDENTIFIER"] != "myapp" ||
+		fields["REDLOG_APP"] != "S" ||
+		fields["SYSLOG_PID"] != strconv.Itoa(os.Getpid()) {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+	fields = parseFields(t, readDatagram(t, conn))
+	if fields["MESSAGE"] != "failed" || fields["PRIORITY"] != "3" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	// too large for a datagram
+	msg := strings.Repeat("x", 4<<20)
+	l.Noticef("%s", msg)
+	fields = parseFields(t, readDatagram(t, conn))
+	if fields["MESSAGE"] != msg || fields["PRIORITY"] != "5" {
+		t.Fatalf("unexpected fields for large message")
+	}
+}
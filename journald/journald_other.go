@@ -0,0 +1,43 @@
+//go:build !linux
+// +build !linux
+
+package journald
+
+import (
+	"io"
+
+	"github.com/tidwall/redlog/v2"
+)
+
+// Encoder sends records to the journal. The journal is only available on
+// Linux, and the Fallback encoder is always used on this platform.
+type Encoder struct {
+	opts Options
+}
+
+// NewEncoder returns an encoder that uses the Fallback encoder.
+func NewEncoder(opts *Options) *Encoder {
+	e := &Encoder{}
+	if opts != nil {
+		e.opts = *opts
+	}
+	if e.opts.Fallback == nil {
+		e.opts.Fallback = redlog.TextCodec{}
+	}
+	return e
+}
+
+// Available returns false.
+func (e *Encoder) Available() bool {
+	return false
+}
+
+// Encode encodes the record to w with the Fallback encoder.
+func (e *Encoder) Encode(w io.Writer, rec redlog.Record) error {
+	return e.opts.Fallback.Encode(w, rec)
+}
+
+// Close does nothing.
+func (e *Encoder) Close() error {
+	return nil
+}
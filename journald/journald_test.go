@@ -0,0 +1,99 @@
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tidwall/redlog/v2"
+)
+
+// parseFields parses a datagram in the native protocol.
+func parseFields(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i == -1 {
+			t.Fatalf("missing newline in %q", b)
+		}
+		line := string(b[:i])
+		b = b[i+1:]
+		if j := strings.IndexByte(line, '='); j != -1 {
+			fields[line[:j]] = line[j+1:]
+			continue
+		}
+		if len(b) < 8 {
+			t.Fatalf("missing length for %s", line)
+		}
+		n := int(binary.LittleEndian.Uint64(b))
+		b = b[8:]
+		if len(b) < n+1 || b[n] != '\n' {
+			t.Fatalf("invalid value for %s", line)
+		}
+		fields[line] = string(b[:n])
+		b = b[n+1:]
+	}
+	return fields
+}
+
+func TestAppendRecord(t *testing.T) {
+	e := &Encoder{opts: Options{Identifier: "myapp"}}
+	b := e.appendRecord(nil, redlog.Record{
+		Pid:   1234,
+		App:   'S',
+		Time:  time.Now(),
+		Level: redlog.LevelWarning,
+		Msg:   "line 1\nline 2",
+		KVs: []interface{}{"shard", 3, "node-id", "a1", "_x", 1,
+			"9bad", 1, 5, "not a key", "err", errors.New("boom")},
+	})
+	want := map[string]string{
+		"MESSAGE":           "line 1\nline 2",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "myapp",
+		"SYSLOG_PID":        "1234",
+		"REDLOG_APP":        "S",
+		"SHARD":             "3",
+		"NODE_ID":           "a1",
+		"X":                 "1",
+		"ERR":               "boom",
+	}
+	if fields := parseFields(t, b); !reflect.DeepEqual(fields, want) {
+		t.Fatalf("expected %v, got %v", want, fields)
+	}
+}
+
+func TestPriority(t *testing.T) {
+	for level, want := range map[int]string{
+		redlog.LevelTrace:   "7",
+		redlog.LevelDebug:   "7",
+		redlog.LevelVerbose: "6",
+		redlog.LevelNotice:  "5",
+		redlog.LevelWarning: "4",
+		redlog.LevelError:   "3",
+	} {
+		if p := levelPriority(level); p != want {
+			t.Fatalf("level %d: expected %s, got %s", level, want, p)
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	e := NewEncoder(&Options{Socket: "/nonexistent/journal/socket"})
+	defer e.Close()
+	if e.Available() {
+		t.Fatal("expected the journal to be unavailable")
+	}
+	buf := &bytes.Buffer{}
+	l := redlog.New(buf, &redlog.Options{Level: redlog.LevelNotice,
+		Encoder: e})
+	l.Noticef("hello")
+	if !strings.HasSuffix(buf.String(), " * hello\n") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
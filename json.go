@@ -0,0 +1,190 @@
+package redlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// recordLevels are the names of the levels of records, from LevelTrace to
+// LevelError.
+var recordLevels = []string{"trace", "debug", "verbose", "notice", "warning",
+	"error"}
+
+// jsonTimeFormat is the layout of the "ts" field.
+const jsonTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// JSONCodec encodes and decodes records as JSON objects, one per line:
+//
+//	{"pid":1234,"role":"M","ts":"2024-06-01T15:04:05.000Z","level":"notice","msg":"hello"}
+//
+// The key value pairs follow as fields. Errors and durations are written
+// as strings. It's the format of FormatJSON.
+type JSONCodec struct{}
+
+// Encode writes the record as a line.
+func (JSONCodec) Encode(w io.Writer, rec Record) error {
+	b := bufferPool.Get().(*buffer)
+	line := append((*b)[:0], `{"pid":`...)
+	line = strconv.AppendInt(line, int64(rec.Pid), 10)
+	line = append(line, `,"role":`...)
+	line = appendJSONString(line, string(rec.App))
+	line = append(line, `,"ts":"`...)
+	line = rec.Time.AppendFormat(line, jsonTimeFormat)
+	line = append(line, `","level":"`...)
+	line = append(line, recordLevels[clampLevel(rec.Level)-LevelTrace]...)
+	line = append(line, `","msg":`...)
+	line = appendJSONString(line, rec.Msg)
+	for i := 0; i < len(rec.KVs); i += 2 {
+		var v interface{}
+		if i+1 < len(rec.KVs) {
+			v = rec.KVs[i+1]
+		}
+		line = append(line, ',')
+		line = appendJSONString(line, fmt.Sprint(rec.KVs[i]))
+		line = append(line, ':')
+		line = appendJSONValue(line, v)
+	}
+	line = append(line, "}\n"...)
+	_, err := w.Write(line)
+	*b = line
+	putBuffer(b)
+	return err
+}
+
+// appendJSONString appends s as a JSON string. Unlike json.Marshal, it
+// does not escape HTML characters, so "<nil>" stays readable.
+func appendJSONString(dst []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c == '\n':
+			dst = append(dst, '\\', 'n')
+		case c == '\r':
+			dst = append(dst, '\\', 'r')
+		case c == '\t':
+			dst = append(dst, '\\', 't')
+		case c < 0x20:
+			dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&15])
+		case c < utf8.RuneSelf:
+			dst = append(dst, c)
+		default:
+			r, n := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && n == 1 {
+				dst = append(dst, `\ufffd`...)
+			} else {
+				dst = append(dst, s[i:i+n]...)
+			}
+			i += n
+			continue
+		}
+		i++
+	}
+	return append(dst, '"')
+}
+
+func appendJSONValue(dst []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return appendJSONString(dst, v)
+	case error:
+		return appendJSONString(dst, v.Error())
+	case time.Duration:
+		return appendJSONString(dst, v.String())
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(dst, fmt.Sprint(v))
+	}
+	return append(dst, b...)
+}
+
+// Decode reads the next line. The fields after "msg" are the key value
+// pairs, in order, with numbers decoded as json.Number. It returns
+// ErrMalformed for a line that is not a record, and reading can continue
+// with the next line.
+func (JSONCodec) Decode(r *bufio.Reader) (Record, error) {
+	line, err := r.ReadBytes('\n')
+	if len(bytes.TrimSpace(line)) == 0 {
+		if err == nil {
+			err = ErrMalformed
+		}
+		return Record{}, err
+	}
+	var rec Record
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return rec, ErrMalformed
+	}
+	var seen int // fields of the record that were seen
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return rec, ErrMalformed
+		}
+		key, _ := tok.(string)
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return rec, ErrMalformed
+		}
+		s, isString := v.(string)
+		switch {
+		case key == "pid" && seen&1 == 0:
+			n, ok := v.(json.Number)
+			pid, err := strconv.Atoi(string(n))
+			if !ok || err != nil {
+				return rec, ErrMalformed
+			}
+			rec.Pid = pid
+			seen |= 1
+		case key == "role" && seen&2 == 0:
+			if !isString || len(s) != 1 {
+				return rec, ErrMalformed
+			}
+			rec.App = s[0]
+			seen |= 2
+		case key == "ts" && seen&4 == 0:
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if !isString || err != nil {
+				return rec, ErrMalformed
+			}
+			rec.Time = t
+			seen |= 4
+		case key == "level" && seen&8 == 0:
+			level := -1
+			for i, name := range recordLevels {
+				if strings.EqualFold(s, name) {
+					level = LevelTrace + i
+				}
+			}
+			if !isString || level < LevelTrace {
+				return rec, ErrMalformed
+			}
+			rec.Level = level
+			seen |= 8
+		case key == "msg" && seen&16 == 0:
+			if !isString {
+				return rec, ErrMalformed
+			}
+			rec.Msg = s
+			seen |= 16
+		default:
+			rec.KVs = append(rec.KVs, key, v)
+		}
+	}
+	if seen != 31 {
+		return rec, ErrMalformed
+	}
+	return rec, nil
+}
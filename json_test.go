@@ -0,0 +1,80 @@
+package redlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice, Format: FormatJSON,
+		Now: func() time.Time {
+			return time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+		}})
+	l.pid = 1234
+	l.Noticef("hello \"world\" <nil>\n")
+	l.Errorf("failed")
+	l.Timed(LevelWarning, "loading")()
+	l.SetFormat(FormatRedis)
+	l.Noticef("text")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`{"pid":1234,"role":"M","ts":"2024-06-01T15:04:05.000Z","level":"notice","msg":"hello \"world\" <nil>"}`,
+		`{"pid":1234,"role":"M","ts":"2024-06-01T15:04:05.000Z","level":"error","msg":"failed"}`,
+		`{"pid":1234,"role":"M","ts":"2024-06-01T15:04:05.000Z","level":"warning","msg":"loading done in 0s","duration":"0s"}`,
+		`1234:M 01 Jun 2024 15:04:05.000 * text`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", strings.Join(lines, "\n"))
+	}
+	for _, line := range lines[:3] {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("invalid JSON %s", line)
+		}
+	}
+}
+
+func TestJSONCodec(t *testing.T) {
+	tm := time.Date(2024, 6, 1, 15, 4, 5, 123000000, time.UTC)
+	recs := []Record{
+		{Pid: 1, App: 'C', Time: tm, Level: LevelTrace,
+			Msg: "tab\there \x01 \xff é"},
+		{Pid: 2, App: 'M', Time: tm, Level: LevelError, Msg: "",
+			KVs: []interface{}{"err", errors.New("eof"), "n", 3,
+				"ok", true, "nil", nil, "list", []int{1, 2}, "odd"}},
+	}
+	var buf bytes.Buffer
+	for _, rec := range recs {
+		if err := (JSONCodec{}).Encode(&buf, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf.WriteString("not json\n")
+	buf.WriteString(`{"pid":1,"role":"M","level":"notice","msg":"no ts"}` + "\n")
+	rd := NewReader(&buf, JSONCodec{})
+	rec, err := rd.Read()
+	if err != nil || rec.Pid != 1 || rec.App != 'C' || !rec.Time.Equal(tm) ||
+		rec.Level != LevelTrace || rec.Msg != "tab\there \x01 � é" {
+		t.Fatalf("unexpected record %+v %v", rec, err)
+	}
+	rec, err = rd.Read()
+	if err != nil || rec.Level != LevelError || len(rec.KVs) != 12 ||
+		rec.KVs[1] != "eof" || rec.KVs[3] != json.Number("3") ||
+		rec.KVs[5] != true || rec.KVs[7] != nil || rec.KVs[10] != "odd" {
+		t.Fatalf("unexpected record %+v %v", rec, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := rd.Read(); err != ErrMalformed {
+			t.Fatalf("expected ErrMalformed, got %v", err)
+		}
+	}
+	if _, err := rd.Read(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package redlog
+
+import (
+	"bytes"
+	"runtime"
+)
+
+// WithLabel returns a logger that adds the label after the app character,
+// such as "1234:M[w3]", to identify a worker. Spaces, brackets, and other
+// characters that would break the prefix are replaced with underscores. It
+// shares the writer and settings of its parent.
+func (l *Logger) WithLabel(label string) *Logger {
+	label = sanitizeTag(label)
+	child := l.clone()
+	child.label = make([]byte, 0, len(label)+2)
+	child.label = append(child.label, '[')
+	child.label = append(child.label, label...)
+	child.label = append(child.label, ']')
+	return child
+}
+
+// appendGoroutineID appends "[gN]" with the id of the current goroutine.
+func appendGoroutineID(dst []byte) []byte {
+	dst = append(dst, "[g"...)
+	dst = appendGoroutineNumber(dst)
+	return append(dst, ']')
+}
+
+// appendGoroutineNumber appends the id of the current goroutine, which is
+// parsed from the first line of its stack trace.
+func appendGoroutineNumber(dst []byte) []byte {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i != -1 {
+		b = b[:i]
+	}
+	return append(dst, b...)
+}
@@ -0,0 +1,100 @@
+package redlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithLabel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{
+		Level: LevelNotice,
+		Now:   func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) },
+	})
+	l.pid = 1234
+	w3 := l.WithLabel("w3")
+	w3.Noticef("hello")
+	w3.WithModule("raft").Noticef("raft")
+	l.Noticef("plain")
+	l.WithLabel("a] 01 [b").Noticef("spoofed")
+	want := "1234:M[w3] 01 Jun 2024 00:00:00.000 * hello\n" +
+		"1234:M[w3] 01 Jun 2024 00:00:00.000 * raft\n" +
+		"1234:M 01 Jun 2024 00:00:00.000 * plain\n" +
+		"1234:M[a__01__b] 01 Jun 2024 00:00:00.000 * spoofed\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+
+	pl, ok := parseLine("1234:M[w3] 01 Jun 2024 00:00:00.000 * hello",
+		DefaultOptions.TimeFormat)
+	if !ok || pl.app != 'M' || pl.tags != "[w3]" || pl.msg != "hello" {
+		t.Fatalf("unexpected result %v %+v", ok, pl)
+	}
+	if _, ok := parseLine("1234:Mx 01 Jun 2024 00:00:00.000 * hello",
+		DefaultOptions.TimeFormat); ok {
+		t.Fatal("expected an invalid line")
+	}
+
+	// take the best of a few runs to avoid noise from the race detector
+	allocs := func(l *Logger) float64 {
+		min := -1.0
+		for i := 0; i < 3; i++ {
+			n := testing.AllocsPerRun(100, func() { l.Noticef("hello") })
+			if min < 0 || n < min {
+				min = n
+			}
+		}
+		return min
+	}
+	plain := newBenchLogger(nil)
+	if a, b := allocs(plain), allocs(plain.WithLabel("w3")); b > a {
+		t.Fatalf("expected %v allocs, got %v", a, b)
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Level: LevelNotice, GoroutineID: true})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Noticef("a")
+			l.Noticef("b")
+		}()
+	}
+	wg.Wait()
+	ids := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		pl, ok := parseLine(line, DefaultOptions.TimeFormat)
+		if !ok || !strings.HasPrefix(pl.tags, "[g") {
+			t.Fatalf("unexpected line %q", line)
+		}
+		ids[pl.tags]++
+	}
+	if len(ids) != 4 {
+		t.Fatalf("expected 4 goroutines, got %v", ids)
+	}
+	for id, n := range ids {
+		if n != 2 {
+			t.Fatalf("expected 2 lines for %s, got %d", id, n)
+		}
+	}
+}
+
+func TestGoroutineIDRecord(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Level: LevelNotice, GoroutineID: true,
+		Format: FormatJSON})
+	l.Noticef("hello")
+	var rec struct{ Goroutine string }
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil ||
+		rec.Goroutine == "" || strings.Trim(rec.Goroutine, "0123456789") != "" {
+		t.Fatalf("unexpected record %q %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,20 @@
+package redlog
+
+// Tracefn logs the message returned by fn at the trace level. The fn is
+// only called when the line is logged, or kept by the crash ring, so an
+// expensive message, such as a hex dump, costs nothing otherwise.
+func (l *Logger) Tracefn(fn func() string) { l.writefn(LevelTrace, fn) }
+
+// Debugfn logs the message returned by fn at the debug level, like
+// Tracefn.
+func (l *Logger) Debugfn(fn func() string) { l.writefn(LevelDebug, fn) }
+
+// Verbfn logs the message returned by fn at the verbose level, like
+// Tracefn.
+func (l *Logger) Verbfn(fn func() string) { l.writefn(LevelVerbose, fn) }
+
+func (l *Logger) writefn(level int, fn func() string) {
+	if l.enabled(level) && l.sampled(level, "") {
+		write(false, l, l.App(), level, "", []interface{}{fn()}, nil)
+	}
+}
@@ -0,0 +1,31 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDebugfn(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelVerbose})
+	var calls int
+	msg := func(s string) func() string {
+		return func() string {
+			calls++
+			return s
+		}
+	}
+	l.Tracefn(msg("trace"))
+	l.Debugfn(msg("debug"))
+	l.Verbfn(msg("verbose"))
+	if calls != 1 || !strings.HasSuffix(buf.String(), " - verbose\n") {
+		t.Fatalf("unexpected calls %d or output %q", calls, buf.String())
+	}
+	l.SetLevel(LevelTrace)
+	l.Tracefn(msg("trace"))
+	l.Debugfn(msg("debug"))
+	if calls != 3 || !strings.HasSuffix(buf.String(), " . debug\n") {
+		t.Fatalf("unexpected calls %d or output %q", calls, buf.String())
+	}
+}
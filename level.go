@@ -0,0 +1,169 @@
+package redlog
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Level is a level by name, for configs and flags. The level constants
+// are untyped, so they are both Levels and the ints taken by the logger:
+//
+//	level, err := redlog.ParseLevel("notice")
+//	l.SetLevel(int(level))
+type Level int
+
+// LevelError is the level of Error and Errorf, for Log, such as for the
+// error levels of other logging libraries. Errors are always logged.
+const LevelError = levelError
+
+// ParseLevel parses a level name, such as "notice", as in the loglevel of
+// redis.conf, or a level number.
+func ParseLevel(s string) (Level, error) {
+	level, err := parseLevel(s)
+	return Level(level), err
+}
+
+// String returns the name of the level, such as "notice".
+func (level Level) String() string {
+	if level < LevelTrace || level > levelError {
+		return "Level(" + strconv.Itoa(int(level)) + ")"
+	}
+	return recordLevels[level-LevelTrace]
+}
+
+// Set parses a level name for flag.Var.
+func (level *Level) Set(s string) error {
+	v, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	*level = v
+	return nil
+}
+
+// maxLevelHistory is the number of changes kept by LevelHistory.
+const maxLevelHistory = 32
+
+// LevelChange is a change of the level of a logger.
+type LevelChange struct {
+	Time   time.Time
+	From   int
+	To     int
+	Reason string // such as "revert" for the end of a SetLevelFor window
+}
+
+// stopper is a pending revert, such as a *time.Timer.
+type stopper interface {
+	Stop() bool
+}
+
+// Level returns the level of the logger, without module overrides.
+func (l *Logger) Level() int {
+	return int(atomic.LoadInt64(&l.level))
+}
+
+// Enabled reports whether lines of level are logged, taking the module
+// level into account, so that expensive arguments can be skipped. Lines
+// that are not logged are still kept by the crash ring, which Enabled
+// ignores.
+func (l *Logger) Enabled(level int) bool {
+	return level >= l.minLevel()
+}
+
+// IsTrace reports whether trace lines are logged.
+func (l *Logger) IsTrace() bool { return l.Enabled(LevelTrace) }
+
+// IsDebug reports whether debug lines are logged.
+func (l *Logger) IsDebug() bool { return l.Enabled(LevelDebug) }
+
+// IsVerbose reports whether verbose lines are logged.
+func (l *Logger) IsVerbose() bool { return l.Enabled(LevelVerbose) }
+
+// SetLevel sets the level of the logger. It cancels the revert of a
+// pending SetLevelFor.
+func (l *Logger) SetLevel(level int) {
+	l.lvmu.Lock()
+	defer l.lvmu.Unlock()
+	l.cancelRevert()
+	l.changeLevel(level, "")
+}
+
+// SetLevelFor sets the level of the logger for the duration d, after which
+// the level reverts. A notice is logged for the change and for the revert,
+// with the optional reason, such as who made the change.
+//
+// A call while another window is pending replaces that window: the level
+// reverts d after the last call, to the level from before the first call.
+func (l *Logger) SetLevelFor(level int, d time.Duration, reason string) {
+	if level < LevelTrace || level > LevelWarning {
+		panic("invalid level")
+	}
+	l.lvmu.Lock()
+	defer l.lvmu.Unlock()
+	if l.revert == nil {
+		l.revertTo = l.Level()
+	}
+	l.cancelRevert()
+	note := ""
+	if reason != "" {
+		note = " (" + reason + ")"
+	}
+	notice := func() {
+		l.Noticef("level set to %s for %s%s", levelNames[level-LevelTrace],
+			d, note)
+	}
+	// logged before a change that disables notices, as in revertLevel, and
+	// after one that may enable them
+	if level > LevelNotice {
+		notice()
+		l.changeLevel(level, reason)
+	} else {
+		l.changeLevel(level, reason)
+		notice()
+	}
+	l.revertGen++
+	gen := l.revertGen
+	l.revert = l.afterFunc(d, func() { l.revertLevel(gen) })
+}
+
+// revertLevel ends the SetLevelFor window gen, if it's still pending.
+func (l *Logger) revertLevel(gen int) {
+	l.lvmu.Lock()
+	defer l.lvmu.Unlock()
+	if l.revert == nil || gen != l.revertGen {
+		return
+	}
+	l.revert = nil
+	// logged before the revert, which may disable notices
+	l.Noticef("level reverted to %s", levelNames[l.revertTo-LevelTrace])
+	l.changeLevel(l.revertTo, "revert")
+}
+
+func (l *Logger) cancelRevert() {
+	if l.revert != nil {
+		l.revert.Stop()
+		l.revert = nil
+	}
+}
+
+// changeLevel sets the level and records the change. It's called with
+// lvmu held.
+func (l *Logger) changeLevel(level int, reason string) {
+	if level < LevelTrace || level > LevelWarning {
+		panic("invalid level")
+	}
+	from := int(atomic.SwapInt64(&l.level, int64(level)))
+	if len(l.history) == maxLevelHistory {
+		l.history = append(l.history[:0], l.history[1:]...)
+	}
+	l.history = append(l.history, LevelChange{Time: l.now(), From: from,
+		To: level, Reason: reason})
+}
+
+// LevelHistory returns the recent changes of the level, oldest first.
+func (l *Logger) LevelHistory() []LevelChange {
+	l.lvmu.Lock()
+	defer l.lvmu.Unlock()
+	return append([]LevelChange(nil), l.history...)
+}
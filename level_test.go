@@ -0,0 +1,210 @@
+package redlog
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTimers replaces the timers of a logger with a fake clock.
+type fakeTimers struct {
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	stopped := t.stopped
+	t.stopped = true
+	return !stopped
+}
+
+func (ft *fakeTimers) install(l *Logger) {
+	l.clock = func() time.Time { return ft.now }
+	l.afterFunc = func(d time.Duration, f func()) stopper {
+		t := &fakeTimer{at: ft.now.Add(d), f: f}
+		ft.timers = append(ft.timers, t)
+		return t
+	}
+}
+
+// advance moves the clock and fires the timers that are due.
+func (ft *fakeTimers) advance(d time.Duration) {
+	ft.now = ft.now.Add(d)
+	for _, t := range ft.timers {
+		if !t.stopped && !t.at.After(ft.now) {
+			t.stopped = true
+			t.f()
+		}
+	}
+}
+
+func TestSetLevelFor(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Level: LevelWarning})
+	ft := &fakeTimers{now: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	ft.install(l)
+
+	// revert
+	l.SetLevelFor(LevelDebug, time.Minute, "alice")
+	l.Debugf("debug 1")
+	ft.advance(59 * time.Second)
+	if l.Level() != LevelDebug {
+		t.Fatal("reverted early")
+	}
+	ft.advance(time.Second)
+	l.Debugf("debug 2")
+	if l.Level() != LevelWarning {
+		t.Fatalf("expected warning, got %d", l.Level())
+	}
+	out := buf.String()
+	if !strings.Contains(out, " * level set to debug for 1m0s (alice)\n") ||
+		!strings.Contains(out, " . debug 1\n") ||
+		!strings.Contains(out, " * level reverted to warning\n") ||
+		strings.Contains(out, "debug 2") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	// overlapping windows replace each other and revert to the original
+	buf.Reset()
+	l.SetLevelFor(LevelDebug, time.Minute, "")
+	ft.advance(30 * time.Second)
+	l.SetLevelFor(LevelTrace, time.Minute, "")
+	ft.advance(45 * time.Second)
+	if l.Level() != LevelTrace {
+		t.Fatalf("expected trace, got %d", l.Level())
+	}
+	ft.advance(15 * time.Second)
+	if l.Level() != LevelWarning {
+		t.Fatalf("expected warning, got %d", l.Level())
+	}
+	if n := strings.Count(buf.String(), "level reverted"); n != 1 {
+		t.Fatalf("expected one revert, got %d", n)
+	}
+
+	// SetLevel cancels the revert
+	l.SetLevelFor(LevelDebug, time.Minute, "")
+	l.SetLevel(LevelVerbose)
+	ft.advance(time.Hour)
+	if l.Level() != LevelVerbose {
+		t.Fatalf("expected verbose, got %d", l.Level())
+	}
+
+	hist := l.LevelHistory()
+	exp := []LevelChange{
+		{From: LevelWarning, To: LevelDebug, Reason: "alice"},
+		{From: LevelDebug, To: LevelWarning, Reason: "revert"},
+		{From: LevelWarning, To: LevelDebug},
+		{From: LevelDebug, To: LevelTrace},
+		{From: LevelTrace, To: LevelWarning, Reason: "revert"},
+		{From: LevelWarning, To: LevelDebug},
+		{From: LevelDebug, To: LevelVerbose},
+	}
+	if len(hist) != len(exp) {
+		t.Fatalf("expected %d changes, got %+v", len(exp), hist)
+	}
+	for i := range exp {
+		hist[i].Time = time.Time{}
+		if hist[i] != exp[i] {
+			t.Fatalf("change %d: expected %+v, got %+v", i, exp[i], hist[i])
+		}
+	}
+	for i := 0; i < maxLevelHistory*2; i++ {
+		l.SetLevel(LevelNotice)
+	}
+	if len(l.LevelHistory()) != maxLevelHistory {
+		t.Fatal("expected the history to be limited")
+	}
+
+	// a window that disables notices is still announced
+	buf.Reset()
+	l.SetLevelFor(LevelWarning, time.Minute, "quiet")
+	if !strings.Contains(buf.String(),
+		" * level set to warning for 1m0s (quiet)\n") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestSetLevelForTimer(t *testing.T) {
+	l := New(&bytes.Buffer{}, &Options{Level: LevelNotice})
+	l.SetLevelFor(LevelDebug, 10*time.Millisecond, "")
+	for i := 0; l.Level() != LevelNotice; i++ {
+		if i == 100 {
+			t.Fatal("expected the level to revert")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, name := range []string{"trace", "debug", "verbose", "notice",
+		"warning"} {
+		level, err := ParseLevel(name)
+		if err != nil || level.String() != name {
+			t.Fatalf("unexpected level %v %v", level, err)
+		}
+	}
+	if level, err := ParseLevel("NOTICE"); err != nil || level != LevelNotice {
+		t.Fatalf("unexpected level %v %v", level, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Fatal("expected error")
+	}
+	if s := Level(levelError).String(); s != "error" {
+		t.Fatalf("unexpected name %q", s)
+	}
+	if s := Level(9).String(); s != "Level(9)" {
+		t.Fatalf("unexpected name %q", s)
+	}
+	var level Level
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&level, "loglevel", "")
+	if err := fs.Parse([]string{"-loglevel", "verbose"}); err != nil ||
+		level != LevelVerbose {
+		t.Fatalf("unexpected level %v %v", level, err)
+	}
+}
+
+func TestSetLevelConcurrent(t *testing.T) {
+	l := New(ioutil.Discard, &Options{Level: LevelNotice})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.SetLevel(LevelDebug + (i+j)%4)
+				l.Debugf("debug %d", j)
+				l.Noticef("notice %d", j)
+				_ = l.Level()
+			}
+		}(i)
+	}
+	wg.Wait()
+	l.SetLevel(LevelWarning)
+	if l.Level() != LevelWarning {
+		t.Fatalf("unexpected level %d", l.Level())
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	l := New(ioutil.Discard, &Options{Level: LevelVerbose, CrashRing: 8})
+	if l.IsTrace() || l.IsDebug() || !l.IsVerbose() ||
+		!l.Enabled(levelError) {
+		t.Fatal("unexpected levels")
+	}
+	raft := l.WithModule("raft")
+	l.SetModuleLevel("raft", LevelTrace)
+	if !raft.IsTrace() || l.IsTrace() {
+		t.Fatal("unexpected module levels")
+	}
+}
@@ -0,0 +1,91 @@
+package redlog
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// appendLines appends n large lines to the file at path using a logger
+// with the provided app character.
+func appendLines(t *testing.T, path string, app byte, n int) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer f.Close()
+	l := New(f, &Options{App: app, LockFile: true, Color: ColorNever})
+	payload := strings.Repeat(string(app), 2000)
+	for i := 0; i < n; i++ {
+		l.Noticef("%d %s", i, payload)
+	}
+}
+
+// checkLines checks that the file at path has n uncorrupted lines from
+// each of the apps.
+func checkLines(t *testing.T, path string, apps string, n int) {
+	t.Helper()
+	counts := make(map[byte]int)
+	for _, line := range strings.Split(strings.TrimSpace(readFile(t, path)),
+		"\n") {
+		pl, ok := parseLine(line, DefaultOptions.TimeFormat)
+		if !ok {
+			t.Fatalf("corrupted line %q", line)
+		}
+		expect := fmt.Sprintf("%d %s", counts[pl.app],
+			strings.Repeat(string(pl.app), 2000))
+		if pl.msg != expect {
+			t.Fatalf("corrupted line %q", line)
+		}
+		counts[pl.app]++
+	}
+	for i := 0; i < len(apps); i++ {
+		if counts[apps[i]] != n {
+			t.Fatalf("expected %d lines for %c, got %d", n, apps[i],
+				counts[apps[i]])
+		}
+	}
+}
+
+func TestAtomicWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.log")
+	var wg sync.WaitGroup
+	for _, app := range []byte("MC") {
+		wg.Add(1)
+		go func(app byte) {
+			defer wg.Done()
+			appendLines(t, path, app, 2000)
+		}(app)
+	}
+	wg.Wait()
+	checkLines(t, path, "MC", 2000)
+}
+
+// TestAtomicWritesProcesses appends to a shared file from two processes.
+// It only runs when REDLOG_STRESS is set.
+func TestAtomicWritesProcesses(t *testing.T) {
+	if path := os.Getenv("REDLOG_STRESS_CHILD"); path != "" {
+		appendLines(t, path, 'C', 5000)
+		return
+	}
+	if os.Getenv("REDLOG_STRESS") == "" {
+		t.Skip("set REDLOG_STRESS to run")
+	}
+	path := filepath.Join(t.TempDir(), "shared.log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestAtomicWritesProcesses$")
+	cmd.Env = append(os.Environ(), "REDLOG_STRESS_CHILD="+path)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, path, 'M', 5000)
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, path, "MC", 5000)
+}
@@ -0,0 +1,134 @@
+package redlog
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogfmtCodec encodes and decodes records in the logfmt format, one per
+// line:
+//
+//	ts=2024-06-01T15:04:05.000Z level=notice role=M pid=1234 msg=hello
+//
+// The key value pairs follow, as with the text format. Values with spaces
+// are quoted. It's the format of FormatLogfmt.
+type LogfmtCodec struct{}
+
+// Encode writes the record as a line.
+func (LogfmtCodec) Encode(w io.Writer, rec Record) error {
+	b := bufferPool.Get().(*buffer)
+	line := append((*b)[:0], "ts="...)
+	line = rec.Time.AppendFormat(line, jsonTimeFormat)
+	line = append(line, " level="...)
+	line = append(line, recordLevels[clampLevel(rec.Level)-LevelTrace]...)
+	line = appendKVs(line, []interface{}{"role", string(rec.App),
+		"pid", rec.Pid, "msg", rec.Msg})
+	line = appendKVs(line, rec.KVs)
+	line = append(line, '\n')
+	_, err := w.Write(line)
+	*b = line
+	putBuffer(b)
+	return err
+}
+
+// Decode reads the next line. The pairs after "msg" are the key value
+// pairs, in order, with string values. It returns ErrMalformed for a line
+// that is not a record, and reading can continue with the next line.
+func (LogfmtCodec) Decode(r *bufio.Reader) (Record, error) {
+	line, err := r.ReadString('\n')
+	if line == "" {
+		return Record{}, err
+	}
+	var rec Record
+	var seen int // fields of the record that were seen
+	s := strings.TrimRight(line, "\r\n")
+	for s != "" {
+		var key, val string
+		var ok bool
+		if key, val, s, ok = nextLogfmtPair(s); !ok {
+			return rec, ErrMalformed
+		}
+		switch {
+		case key == "ts" && seen&1 == 0:
+			t, err := time.Parse(time.RFC3339Nano, val)
+			if err != nil {
+				return rec, ErrMalformed
+			}
+			rec.Time = t
+			seen |= 1
+		case key == "level" && seen&2 == 0:
+			level := -1
+			for i, name := range recordLevels {
+				if strings.EqualFold(val, name) {
+					level = LevelTrace + i
+				}
+			}
+			if level < LevelTrace {
+				return rec, ErrMalformed
+			}
+			rec.Level = level
+			seen |= 2
+		case key == "role" && seen&4 == 0:
+			if len(val) != 1 {
+				return rec, ErrMalformed
+			}
+			rec.App = val[0]
+			seen |= 4
+		case key == "pid" && seen&8 == 0:
+			pid, err := strconv.Atoi(val)
+			if err != nil {
+				return rec, ErrMalformed
+			}
+			rec.Pid = pid
+			seen |= 8
+		case key == "msg" && seen&16 == 0:
+			rec.Msg = val
+			seen |= 16
+		default:
+			rec.KVs = append(rec.KVs, key, val)
+		}
+	}
+	if seen != 31 {
+		return rec, ErrMalformed
+	}
+	return rec, nil
+}
+
+// nextLogfmtPair returns the first pair of s, with the value unquoted, and
+// the rest of s.
+func nextLogfmtPair(s string) (key, val, rest string, ok bool) {
+	s = strings.TrimLeft(s, " ")
+	i := strings.IndexByte(s, '=')
+	if i <= 0 || strings.ContainsAny(s[:i], " \"") {
+		return "", "", "", false
+	}
+	key, s = s[:i], s[i+1:]
+	if strings.HasPrefix(s, `"`) {
+		end := 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			return "", "", "", false
+		}
+		var err error
+		if val, err = strconv.Unquote(s[:end+1]); err != nil {
+			return "", "", "", false
+		}
+		s = s[end+1:]
+		if s != "" && s[0] != ' ' {
+			return "", "", "", false
+		}
+	} else if i := strings.IndexByte(s, ' '); i != -1 {
+		val, s = s[:i], s[i:]
+	} else {
+		val, s = s, ""
+	}
+	return key, val, strings.TrimLeft(s, " "), true
+}
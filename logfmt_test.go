@@ -0,0 +1,60 @@
+package redlog
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogfmtFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice, Format: FormatLogfmt,
+		Now: func() time.Time {
+			return time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+		}})
+	l.pid = 1234
+	l.Noticef("ready")
+	l.Warningf("disk \"data\" full")
+	l.Timed(LevelNotice, "loading")()
+
+	want := "ts=2024-06-01T15:04:05.000Z level=notice role=M pid=1234 msg=ready\n" +
+		`ts=2024-06-01T15:04:05.000Z level=warning role=M pid=1234 msg="disk \"data\" full"` + "\n" +
+		`ts=2024-06-01T15:04:05.000Z level=notice role=M pid=1234 msg="loading done in 0s" duration=0s` + "\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output\n%s", buf.String())
+	}
+
+	rd := NewReader(strings.NewReader(buf.String()+
+		"not logfmt\n"+
+		"ts=2024-06-01T15:04:05.000Z level=notice role=M msg=nopid\n"+
+		`ts=2024-06-01T15:04:05.000Z level=notice role=M pid=1 msg="open`+"\n"),
+		LogfmtCodec{})
+	for i := 0; i < 3; i++ {
+		rec, err := rd.Read()
+		if err != nil || rec.Pid != 1234 || rec.App != 'M' ||
+			rec.Time.Hour() != 15 {
+			t.Fatalf("unexpected record %+v %v", rec, err)
+		}
+		switch i {
+		case 1:
+			if rec.Msg != `disk "data" full` || rec.Level != LevelWarning {
+				t.Fatalf("unexpected record %+v", rec)
+			}
+		case 2:
+			if rec.Msg != "loading done in 0s" || len(rec.KVs) != 2 ||
+				rec.KVs[0] != "duration" || rec.KVs[1] != "0s" {
+				t.Fatalf("unexpected record %+v", rec)
+			}
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := rd.Read(); err != ErrMalformed {
+			t.Fatalf("expected ErrMalformed, got %v", err)
+		}
+	}
+	if _, err := rd.Read(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package redlog
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+)
+
+// noLevel marks a module that has no level override.
+const noLevel = math.MinInt64
+
+// module is a registry entry for a named component. The level is stored
+// atomically so overrides take effect immediately for every logger that
+// references the module.
+type module struct {
+	name  string
+	level int64
+}
+
+func (m *module) getLevel() (int, bool) {
+	level := atomic.LoadInt64(&m.level)
+	if level == noLevel {
+		return 0, false
+	}
+	return int(level), true
+}
+
+// getModule returns the registry entry for name, creating it if needed.
+func (c *core) getModule(name string) *module {
+	c.modmu.Lock()
+	defer c.modmu.Unlock()
+	if c.modules == nil {
+		c.modules = make(map[string]*module)
+	}
+	m := c.modules[name]
+	if m == nil {
+		m = &module{name: name, level: noLevel}
+		c.modules[name] = m
+	}
+	return m
+}
+
+// WithModule returns a logger for the named component. It shares the
+// writer and settings of its parent, but its level may be overridden
+// using SetModuleLevel.
+func (l *Logger) WithModule(name string) *Logger {
+	child := l.clone()
+	child.module = l.getModule(name)
+	return child
+}
+
+// Named returns a module logger that tags its messages with the name,
+// such as "[raft] elected", with the tag colored for terminals. Names of
+// nested loggers are joined with dots, such as "raft.snapshot", which is
+// also the name of the module for SetModuleLevel.
+func (l *Logger) Named(name string) *Logger {
+	if l.tag != nil {
+		name = string(l.tag[1:len(l.tag)-1]) + "." + name
+	}
+	n := l.WithModule(name)
+	n.tag = append(append([]byte{'['}, name...), ']')
+	return n
+}
+
+// SetModuleLevel overrides the level for all loggers of the named module.
+// The change takes effect immediately.
+func (l *Logger) SetModuleLevel(module string, level int) {
+	if level < LevelTrace || level > LevelWarning {
+		panic("invalid level")
+	}
+	atomic.StoreInt64(&l.getModule(module).level, int64(level))
+}
+
+// ClearModuleLevel removes the level override for the named module, which
+// then falls back to the level of the parent logger.
+func (l *Logger) ClearModuleLevel(module string) {
+	atomic.StoreInt64(&l.getModule(module).level, noLevel)
+}
+
+// SetModuleLevels replaces the module level overrides with levels, such
+// as from a config file, clearing the overrides of the modules that are
+// not in levels.
+func (l *Logger) SetModuleLevels(levels map[string]int) {
+	for _, level := range levels {
+		if level < LevelTrace || level > LevelWarning {
+			panic("invalid level")
+		}
+	}
+	for name := range l.ModuleLevels() {
+		if _, ok := levels[name]; !ok {
+			l.ClearModuleLevel(name)
+		}
+	}
+	for name, level := range levels {
+		l.SetModuleLevel(name, level)
+	}
+}
+
+// ParseModuleLevels parses module levels for SetModuleLevels, such as
+// "raft=debug,store=notice". The levels are names or numbers.
+func ParseModuleLevels(s string) (map[string]int, error) {
+	levels := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid module level %q", pair)
+		}
+		level, err := parseLevel(strings.TrimSpace(pair[i+1:]))
+		if err != nil {
+			return nil, err
+		}
+		levels[strings.TrimSpace(pair[:i])] = level
+	}
+	return levels, nil
+}
+
+// ModuleLevels returns the current module level overrides.
+func (l *Logger) ModuleLevels() map[string]int {
+	l.modmu.Lock()
+	defer l.modmu.Unlock()
+	levels := make(map[string]int)
+	for name, m := range l.modules {
+		if level, ok := m.getLevel(); ok {
+			levels[name] = level
+		}
+	}
+	return levels
+}
+
+// minLevel returns the lowest level that the logger will output.
+func (l *Logger) minLevel() int {
+	if l.module != nil {
+		if level, ok := l.module.getLevel(); ok {
+			return level
+		}
+	}
+	return int(atomic.LoadInt64(&l.level))
+}
+
+// enabled returns true if a message at level needs to be formatted, which
+// is always the case when the crash ring is enabled.
+func (l *Logger) enabled(level int) bool {
+	return level >= l.minLevel() || l.ring != nil
+}
@@ -0,0 +1,137 @@
+package redlog
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestModuleLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, nil)
+	raft := l.WithModule("raft")
+	http := l.WithModule("http")
+
+	raft.Verb("raft 1")
+	http.Verb("http 1")
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output, got %q", buf.String())
+	}
+
+	l.SetModuleLevel("raft", LevelVerbose)
+	raft.Verb("raft 2")
+	http.Verb("http 2")
+	if !strings.Contains(buf.String(), "raft 2") {
+		t.Fatalf("expected raft output, got %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "http") {
+		t.Fatalf("expected no http output, got %q", buf.String())
+	}
+	if levels := l.ModuleLevels(); !reflect.DeepEqual(levels,
+		map[string]int{"raft": LevelVerbose}) {
+		t.Fatalf("unexpected levels %v", levels)
+	}
+
+	buf.Reset()
+	l.ClearModuleLevel("raft")
+	raft.Verb("raft 3")
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output, got %q", buf.String())
+	}
+	if levels := l.ModuleLevels(); len(levels) != 0 {
+		t.Fatalf("unexpected levels %v", levels)
+	}
+}
+
+func TestModuleLevelsConcurrent(t *testing.T) {
+	l := New(nil, nil)
+	raft := l.WithModule("raft")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			l.SetModuleLevel("raft", i%4)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			raft.Debugf("%d", i)
+			l.ModuleLevels()
+		}
+	}()
+	wg.Wait()
+}
+
+func TestNamed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, nil)
+	l.pid = 1234
+	raft := l.Named("raft")
+	snap := raft.Named("snapshot")
+	l.SetModuleLevel("raft.snapshot", LevelVerbose)
+	raft.Noticef("elected")
+	raft.Verb("hidden")
+	snap.Verb("saved")
+	raft.WithLabel("w1").Warningf("slow")
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		// strip the timestamp after the app and label
+		got = append(got, line[:strings.IndexByte(line, ' ')]+
+			line[strings.IndexByte(line, '.')+4:])
+	}
+	want := []string{
+		`1234:M * [raft] elected`,
+		`1234:M - [raft.snapshot] saved`,
+		`1234:M[w1] # [raft] slow`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", strings.Join(got, "\n"))
+	}
+
+	buf.Reset()
+	l = New(buf, &Options{Color: ColorAlways})
+	l.Named("raft").Noticef("elected")
+	if !strings.HasSuffix(buf.String(), "\x1b[36m[raft]\x1b[0m elected\n") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestSetModuleLevels(t *testing.T) {
+	levels, err := ParseModuleLevels(" raft=debug, store=2,")
+	if err != nil || !reflect.DeepEqual(levels,
+		map[string]int{"raft": LevelDebug, "store": LevelNotice}) {
+		t.Fatalf("unexpected levels %v %v", levels, err)
+	}
+	for _, s := range []string{"raft", "=debug", "raft=loud"} {
+		if _, err := ParseModuleLevels(s); err == nil {
+			t.Fatalf("expected an error for %q", s)
+		}
+	}
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Level: LevelNotice,
+		ModuleLevels: map[string]int{"http": LevelVerbose}})
+	l.SetModuleLevels(levels)
+	if got := l.ModuleLevels(); !reflect.DeepEqual(got, levels) {
+		t.Fatalf("unexpected levels %v", got)
+	}
+	l.Named("raft").Debugf("raft")
+	l.Named("http").Verbf("http")
+	if out := buf.String(); !strings.Contains(out, "[raft] raft") ||
+		strings.Contains(out, "http") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	// config changes
+	l.applyConfig(&Options{Level: LevelNotice,
+		ModuleLevels: map[string]int{"http": LevelDebug}})
+	if !strings.Contains(buf.String(),
+		"config: modules=http=debug (was raft=debug,store=notice)") ||
+		!reflect.DeepEqual(l.ModuleLevels(), map[string]int{"http": 0}) {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
@@ -0,0 +1,384 @@
+// Package msgpack encodes redlog records as MessagePack.
+//
+// Each record is an array of six elements: the pid, the app character,
+// the time as a timestamp extension, the level, the message, and an array
+// of alternating keys and values.
+package msgpack
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/tidwall/redlog/v2"
+)
+
+// Codec encodes and decodes records. It can be used as the Encoder option
+// of a logger, and with redlog.NewReader.
+type Codec struct{}
+
+// Encode writes the record to w with a single call.
+func (Codec) Encode(w io.Writer, rec redlog.Record) error {
+	b := make([]byte, 0, 64+len(rec.Msg))
+	b = appendArray(b, 6)
+	b = appendInt(b, int64(rec.Pid))
+	b = appendInt(b, int64(rec.App))
+	b = appendTime(b, rec.Time)
+	b = appendInt(b, int64(rec.Level))
+	b = appendString(b, rec.Msg)
+	b = appendArray(b, len(rec.KVs))
+	for _, v := range rec.KVs {
+		b = appendValue(b, v)
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// Decode reads the next record. Keys and values are decoded as nil, bool,
+// int64, uint64 for integers larger than math.MaxInt64, float64, string,
+// []byte, or time.Time. It returns redlog.ErrMalformed for data that is
+// not a record, after which the stream cannot be read any further.
+func (Codec) Decode(r *bufio.Reader) (redlog.Record, error) {
+	var rec redlog.Record
+	if _, err := r.Peek(1); err != nil {
+		return rec, err
+	}
+	d := decoder{r}
+	n, err := d.arrayLen()
+	if err != nil {
+		return rec, err
+	}
+	if n != 6 {
+		return rec, redlog.ErrMalformed
+	}
+	var vals [5]interface{}
+	for i := range vals {
+		if vals[i], err = d.value(); err != nil {
+			return rec, err
+		}
+	}
+	pid, ok1 := vals[0].(int64)
+	app, ok2 := vals[1].(int64)
+	t, ok3 := vals[2].(time.Time)
+	level, ok4 := vals[3].(int64)
+	msg, ok5 := vals[4].(string)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || app < 0 || app > 255 {
+		return rec, redlog.ErrMalformed
+	}
+	if n, err = d.arrayLen(); err != nil {
+		return rec, err
+	}
+	rec = redlog.Record{Pid: int(pid), App: byte(app), Time: t,
+		Level: int(level), Msg: msg}
+	if n > 0 {
+		rec.KVs = make([]interface{}, n)
+		for i := range rec.KVs {
+			if rec.KVs[i], err = d.value(); err != nil {
+				return rec, err
+			}
+		}
+	}
+	return rec, nil
+}
+
+func appendArray(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xdc, byte(n>>8), byte(n))
+	default:
+		return append(b, 0xdd, byte(n>>24), byte(n>>16), byte(n>>8),
+			byte(n))
+	}
+}
+
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return append(b, 0xd1, byte(v>>8), byte(v))
+	case v >= math.MinInt32:
+		return append(b, 0xd2, byte(v>>24), byte(v>>16), byte(v>>8),
+			byte(v))
+	default:
+		b = append(b, 0xd3)
+		return appendUint64(b, uint64(v))
+	}
+}
+
+func appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v < 128:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return append(b, 0xcd, byte(v>>8), byte(v))
+	case v <= math.MaxUint32:
+		return append(b, 0xce, byte(v>>24), byte(v>>16), byte(v>>8),
+			byte(v))
+	default:
+		b = append(b, 0xcf)
+		return appendUint64(b, v)
+	}
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var x [8]byte
+	binary.BigEndian.PutUint64(x[:], v)
+	return append(b, x[:]...)
+}
+
+func appendString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xdb, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, s...)
+}
+
+func appendBytes(b []byte, p []byte) []byte {
+	n := len(p)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xc5, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xc6, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, p...)
+}
+
+// appendTime appends the time using the 96-bit timestamp extension.
+func appendTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xc7, 12, 0xff)
+	nsec := t.Nanosecond()
+	b = append(b, byte(nsec>>24), byte(nsec>>16), byte(nsec>>8), byte(nsec))
+	return appendUint64(b, uint64(t.Unix()))
+}
+
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case int:
+		return appendInt(b, int64(v))
+	case int8:
+		return appendInt(b, int64(v))
+	case int16:
+		return appendInt(b, int64(v))
+	case int32:
+		return appendInt(b, int64(v))
+	case int64:
+		return appendInt(b, v)
+	case uint:
+		return appendUint(b, uint64(v))
+	case uint8:
+		return appendUint(b, uint64(v))
+	case uint16:
+		return appendUint(b, uint64(v))
+	case uint32:
+		return appendUint(b, uint64(v))
+	case uint64:
+		return appendUint(b, v)
+	case float32:
+		return appendFloat(b, float64(v))
+	case float64:
+		return appendFloat(b, v)
+	case string:
+		return appendString(b, v)
+	case []byte:
+		return appendBytes(b, v)
+	case time.Time:
+		return appendTime(b, v)
+	case error:
+		return appendString(b, v.Error())
+	case fmt.Stringer:
+		return appendString(b, v.String())
+	default:
+		return appendString(b, fmt.Sprint(v))
+	}
+}
+
+func appendFloat(b []byte, v float64) []byte {
+	b = append(b, 0xcb)
+	return appendUint64(b, math.Float64bits(v))
+}
+
+type decoder struct {
+	r *bufio.Reader
+}
+
+// read returns the next n bytes. A missing byte is an unexpected EOF.
+func (d decoder) read(n int) ([]byte, error) {
+	p := make([]byte, n)
+	if _, err := io.ReadFull(d.r, p); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func (d decoder) uint(n int) (uint64, error) {
+	p, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range p {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (d decoder) arrayLen() (int, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	var n uint64
+	switch {
+	case c&0xf0 == 0x90:
+		return int(c & 0x0f), nil
+	case c == 0xdc:
+		n, err = d.uint(2)
+	case c == 0xdd:
+		n, err = d.uint(4)
+	default:
+		return 0, redlog.ErrMalformed
+	}
+	return int(n), err
+}
+
+func (d decoder) value() (interface{}, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	switch {
+	case c < 0x80:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := 1 << (c - 0xd0)
+		v, err := d.uint(n)
+		if err != nil {
+			return nil, err
+		}
+		// sign extend
+		shift := 64 - 8*uint(n)
+		return int64(v<<shift) >> shift, nil
+	case 0xcb:
+		v, err := d.uint(8)
+		return math.Float64frombits(v), err
+	case 0xca:
+		v, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.read(int(n))
+	case 0xd6, 0xd7, 0xc7:
+		return d.time(c)
+	}
+	return nil, redlog.ErrMalformed
+}
+
+func (d decoder) str(n int) (interface{}, error) {
+	p, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(p), nil
+}
+
+// time reads the 32, 64, and 96-bit timestamp extensions.
+func (d decoder) time(c byte) (interface{}, error) {
+	n := 4
+	if c == 0xd7 {
+		n = 8
+	} else if c == 0xc7 {
+		size, err := d.uint(1)
+		if err != nil {
+			return nil, err
+		}
+		n = int(size)
+	}
+	p, err := d.read(n + 1)
+	if err != nil {
+		return nil, err
+	}
+	if int8(p[0]) != -1 {
+		return nil, redlog.ErrMalformed
+	}
+	p = p[1:]
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(p)), 0), nil
+	case 8:
+		v := binary.BigEndian.Uint64(p)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(p[4:])),
+			int64(binary.BigEndian.Uint32(p))), nil
+	}
+	return nil, redlog.ErrMalformed
+}
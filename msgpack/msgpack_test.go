@@ -0,0 +1,95 @@
+package msgpack
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tidwall/redlog/v2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tm := time.Date(2024, 6, 1, 15, 4, 5, 123456789, time.UTC)
+	recs := []redlog.Record{
+		{Pid: 1234, App: 'M', Time: tm, Level: redlog.LevelNotice,
+			Msg: "hello"},
+		{Pid: 1, App: 'C', Time: tm, Level: redlog.LevelTrace,
+			Msg: string(bytes.Repeat([]byte("x"), 70000)),
+			KVs: []interface{}{
+				"nil", nil, "bool", true, "int", -100000, "small", -5,
+				"uint", uint64(math.MaxUint64), "float", 1.5,
+				"bytes", []byte{1, 2}, "time", tm, "dur", time.Second,
+			}},
+	}
+	buf := &bytes.Buffer{}
+	for _, rec := range recs {
+		if err := (Codec{}).Encode(buf, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := redlog.NewReader(buf, Codec{})
+	for i, want := range recs {
+		rec, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rec.Time.Equal(want.Time) {
+			t.Fatalf("%d: expected %v, got %v", i, want.Time, rec.Time)
+		}
+		rec.Time, want.Time = time.Time{}, time.Time{}
+		if i == 1 {
+			want.KVs = []interface{}{
+				"nil", nil, "bool", true, "int", int64(-100000),
+				"small", int64(-5), "uint", uint64(math.MaxUint64),
+				"float", 1.5, "bytes", []byte{1, 2}, "time", rec.KVs[15],
+				"dur", "1s",
+			}
+			if !rec.KVs[15].(time.Time).Equal(tm) {
+				t.Fatalf("unexpected time %v", rec.KVs[15])
+			}
+		}
+		if !reflect.DeepEqual(rec, want) {
+			t.Fatalf("%d: expected %+v, got %+v", i, want, rec)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := redlog.New(buf, &redlog.Options{
+		Level:   redlog.LevelDebug,
+		App:     'S',
+		Encoder: Codec{},
+	})
+	l.Debugf("debug %d", 1)
+	l.Warningf("warning")
+	r := redlog.NewReader(buf, Codec{})
+	for _, want := range []string{"debug 1", "warning"} {
+		rec, err := r.Read()
+		if err != nil || rec.Msg != want || rec.App != 'S' ||
+			time.Since(rec.Time) > time.Minute {
+			t.Fatalf("unexpected record %+v %v", rec, err)
+		}
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	for _, data := range []string{
+		"\x01",
+		"\x96\x01",
+		"\x93\x01\x02\x03",
+		"\x96\xa1x\x01\xd6\xff\x00\x00\x00\x00\x01\xa0\x90",
+	} {
+		_, err := (Codec{}).Decode(bufio.NewReader(bytes.NewBufferString(data)))
+		if err != redlog.ErrMalformed && err != io.ErrUnexpectedEOF {
+			t.Fatalf("%q: expected an error, got %v", data, err)
+		}
+	}
+}
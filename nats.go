@@ -0,0 +1,47 @@
+package redlog
+
+// NATSLogger logs the messages of an embedded NATS server. It implements
+// the server.Logger of NATS, without depending on it, for SetLogger:
+//
+//	ns.SetLogger(redlog.NewNATSLogger(l), opts.Debug, opts.Trace)
+//
+// Errors are logged at the error level, and Fatalf exits like
+// Logger.Fatalf.
+type NATSLogger struct {
+	l *Logger
+}
+
+// NewNATSLogger returns a NATSLogger that logs to l.
+func NewNATSLogger(l *Logger) *NATSLogger {
+	return &NATSLogger{l}
+}
+
+// Noticef logs at the notice level.
+func (n *NATSLogger) Noticef(format string, v ...interface{}) {
+	n.l.Noticef(format, v...)
+}
+
+// Warnf logs at the warning level.
+func (n *NATSLogger) Warnf(format string, v ...interface{}) {
+	n.l.Warningf(format, v...)
+}
+
+// Fatalf logs at the error level and exits.
+func (n *NATSLogger) Fatalf(format string, v ...interface{}) {
+	n.l.Fatalf(format, v...)
+}
+
+// Errorf logs at the error level.
+func (n *NATSLogger) Errorf(format string, v ...interface{}) {
+	n.l.Errorf(format, v...)
+}
+
+// Debugf logs at the debug level.
+func (n *NATSLogger) Debugf(format string, v ...interface{}) {
+	n.l.Debugf(format, v...)
+}
+
+// Tracef logs at the trace level.
+func (n *NATSLogger) Tracef(format string, v ...interface{}) {
+	n.l.Tracef(format, v...)
+}
@@ -0,0 +1,44 @@
+package redlog
+
+import (
+	"strings"
+	"testing"
+)
+
+// natsLogger is server.Logger of NATS.
+type natsLogger interface {
+	Noticef(format string, v ...interface{})
+	Warnf(format string, v ...interface{})
+	Fatalf(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+	Debugf(format string, v ...interface{})
+	Tracef(format string, v ...interface{})
+}
+
+func TestNATSLogger(t *testing.T) {
+	var exits int
+	l, lines := capture(&Options{Level: LevelDebug,
+		ExitFunc: func(int) { exits++ }})
+	var n natsLogger = NewNATSLogger(l)
+	n.Tracef("hidden")
+	n.Debugf("client %d connected", 1)
+	n.Noticef("Server is ready")
+	n.Warnf("slow consumer")
+	n.Errorf("error: %v", "eof")
+	n.Fatalf("cannot listen")
+	if exits != 1 {
+		t.Fatalf("expected an exit, got %d", exits)
+	}
+
+	got := lines()
+	want := []string{
+		`. client 1 connected`,
+		`* Server is ready`,
+		`# slow consumer`,
+		`# error: eof`,
+		`# cannot listen`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,40 @@
+package redlog
+
+import "fmt"
+
+// NoticeOnce logs a formatted notice, like Noticef, only the first time
+// that the message is logged, such as a deprecation notice. Messages are
+// remembered for the life of the logger and its module loggers, so it's
+// meant for a few distinct messages.
+func (l *Logger) NoticeOnce(format string, args ...interface{}) {
+	l.writeOnce(LevelNotice, format, args)
+}
+
+// WarningOnce logs a formatted warning, like Warningf, only the first time
+// that the message is logged, as with NoticeOnce.
+func (l *Logger) WarningOnce(format string, args ...interface{}) {
+	l.writeOnce(LevelWarning, format, args)
+}
+
+func (l *Logger) writeOnce(level int, format string, args []interface{}) {
+	if level < l.minLevel() {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if _, dup := l.once.Load(onceKey{level, msg}); dup {
+		return
+	}
+	// a message that is sampled out is not remembered, so it's logged later
+	if !l.sampled(level, format) {
+		return
+	}
+	if _, dup := l.once.LoadOrStore(onceKey{level, msg}, nil); !dup {
+		write(false, l, l.App(), level, "", []interface{}{msg}, nil)
+	}
+}
+
+// onceKey is a message of NoticeOnce or WarningOnce.
+type onceKey struct {
+	level int
+	msg   string
+}
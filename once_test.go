@@ -0,0 +1,57 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOnce(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelWarning})
+	for i := 0; i < 3; i++ {
+		l.NoticeOnce("hidden")
+		l.WarningOnce("option %q is deprecated", "save")
+		l.WithModule("m").WarningOnce("option %q is deprecated", "save")
+		l.WarningOnce("option %q is deprecated", "appendonly")
+	}
+	l.SetLevel(LevelNotice)
+	l.NoticeOnce("hidden")
+	l.NoticeOnce("hidden")
+	l.NoticeOnce("option %q is deprecated", "save")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		` # option "save" is deprecated`,
+		` # option "appendonly" is deprecated`,
+		` * hidden`,
+		` * option "save" is deprecated`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Fatalf("unexpected lines %q", lines)
+		}
+	}
+}
+
+func TestOnceRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	l := New(&buf, &Options{Level: LevelNotice, Format: FormatPlain,
+		Now:       func() time.Time { return now },
+		RateLimit: RateLimit{Every: time.Minute, Burst: 1}})
+	l.Noticef("busy")
+	l.WarningOnce("deprecated")
+	now = now.Add(time.Minute)
+	// the message was dropped, so it's logged now
+	l.WarningOnce("deprecated")
+	now = now.Add(time.Minute)
+	l.WarningOnce("deprecated")
+	exp := "busy\nwarning: deprecated\n"
+	if buf.String() != exp || l.Stats().Suppressed != 1 {
+		t.Fatalf("expected %q, got %q %+v", exp, buf.String(), l.Stats())
+	}
+}
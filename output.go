@@ -0,0 +1,94 @@
+package redlog
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+)
+
+// levelOutput is a destination that overrides the primary writer for a
+// single level.
+type levelOutput struct {
+	wr   io.Writer
+	tty  bool
+	also bool // also write to the primary writer
+}
+
+// levelIndex returns the index into the level outputs. Errors are routed
+// with warnings.
+func levelIndex(level int) int {
+	if level > LevelWarning {
+		level = LevelWarning
+	}
+	return level - LevelTrace
+}
+
+// SetOutput replaces the primary writer, such as with a log file after
+// daemonizing. Lines that are queued for the previous writer are written
+// to it first, and colors are detected again for wr according to the
+// Color option. The previous writer is not closed. A nil wr discards the
+// lines. Does nothing after Close.
+func (l *Logger) SetOutput(wr io.Writer) {
+	if wr == nil {
+		wr = ioutil.Discard
+	}
+	l.mu.Lock()
+	if l.isClosed() {
+		l.mu.Unlock()
+		return
+	}
+	l.waitFlush()
+	tw := l.tw
+	l.setWriter(wr)
+	l.mu.Unlock()
+	if tw != nil {
+		tw.close()
+	}
+}
+
+// SetLevelOutput sends lines of exactly level to wr instead of the primary
+// writer. Colors are used for wr according to the Color option, as if it
+// were the primary writer. A nil wr removes the override.
+func (l *Logger) SetLevelOutput(level int, wr io.Writer) {
+	l.setLevelOutput(level, wr, false)
+}
+
+// AlsoLevelOutput is like SetLevelOutput, but lines of level are written
+// to both the primary writer and wr.
+func (l *Logger) AlsoLevelOutput(level int, wr io.Writer) {
+	l.setLevelOutput(level, wr, true)
+}
+
+func (l *Logger) setLevelOutput(level int, wr io.Writer, also bool) {
+	if level < LevelTrace || level > LevelWarning {
+		panic("invalid level")
+	}
+	var out *levelOutput
+	if wr != nil {
+		out = &levelOutput{wr: wr, tty: isTTY(wr, l.color), also: also}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	idx := levelIndex(level)
+	if l.outputs[idx] == nil && out != nil {
+		atomic.AddInt32(&l.nouts, 1)
+	} else if l.outputs[idx] != nil && out == nil {
+		atomic.AddInt32(&l.nouts, -1)
+	}
+	l.outputs[idx] = out
+}
+
+// hasOutput returns false when lines are not written anywhere.
+func (l *Logger) hasOutput() bool {
+	return (atomic.LoadUint32(&l.discard) == 0 ||
+		atomic.LoadInt32(&l.nouts) > 0 ||
+		l.wf != nil) && !l.isClosed()
+}
+
+// outputType returns the type of the primary writer, such as "*os.File".
+func (l *Logger) outputType() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return fmt.Sprintf("%T", l.wr)
+}
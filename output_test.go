@@ -0,0 +1,145 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetLevelOutput(t *testing.T) {
+	primary, debug, warning := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+	l := New(primary, &Options{Level: LevelDebug})
+	l.SetLevelOutput(LevelDebug, debug)
+	l.SetLevelOutput(LevelWarning, warning)
+	l.Debugf("debug")
+	l.Noticef("notice")
+	l.Warningf("warning")
+	l.Errorf("error")
+	if out := primary.String(); strings.Count(out, "\n") != 1 ||
+		!strings.HasSuffix(out, " * notice\n") {
+		t.Fatalf("unexpected primary output %q", out)
+	}
+	if out := debug.String(); strings.Count(out, "\n") != 1 ||
+		!strings.HasSuffix(out, " . debug\n") {
+		t.Fatalf("unexpected debug output %q", out)
+	}
+	if out := warning.String(); strings.Count(out, "\n") != 2 ||
+		!strings.Contains(out, " # warning\n") ||
+		!strings.HasSuffix(out, " # error\n") {
+		t.Fatalf("unexpected warning output %q", out)
+	}
+
+	// also mode, and removing an override
+	primary.Reset()
+	debug.Reset()
+	l.AlsoLevelOutput(LevelDebug, debug)
+	l.SetLevelOutput(LevelWarning, nil)
+	l.Debugf("debug")
+	l.Warningf("warning")
+	if out := primary.String(); !strings.Contains(out, " . debug\n") ||
+		!strings.HasSuffix(out, " # warning\n") {
+		t.Fatalf("unexpected primary output %q", out)
+	}
+	if out := debug.String(); !strings.HasSuffix(out, " . debug\n") {
+		t.Fatalf("unexpected debug output %q", out)
+	}
+	if st := l.Stats(); st.Debug != 2 || st.Warning != 2 || st.Error != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestSetLevelOutputDiscard(t *testing.T) {
+	debug := &bytes.Buffer{}
+	l := New(nil, &Options{Level: LevelDebug})
+	l.SetLevelOutput(LevelDebug, debug)
+	l.Debugf("debug")
+	l.Noticef("notice")
+	if out := debug.String(); !strings.HasSuffix(out, " . debug\n") {
+		t.Fatalf("unexpected debug output %q", out)
+	}
+	if st := l.Stats(); st.Debug != 1 || st.Notice != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestSetLevelOutputColor(t *testing.T) {
+	primary, debug := &bytes.Buffer{}, &bytes.Buffer{}
+	l := New(primary, &Options{Level: LevelDebug, Color: ColorAlways})
+	l.pid = 1234
+	l.AlsoLevelOutput(LevelDebug, debug)
+	l.Debugf("debug")
+	if out := primary.String(); !strings.Contains(out, "\x1b[35m.\x1b[0m") {
+		t.Fatalf("expected colors, got %q", out)
+	}
+	if debug.String() != primary.String() {
+		t.Fatalf("expected %q, got %q", primary.String(), debug.String())
+	}
+
+	// an auto color destination that is not a terminal
+	primary.Reset()
+	debug.Reset()
+	l = New(primary, &Options{Level: LevelDebug})
+	l.AlsoLevelOutput(LevelDebug, debug)
+	l.Debugf("debug")
+	if strings.Contains(debug.String(), "\x1b[") {
+		t.Fatalf("expected no colors, got %q", debug.String())
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	first, second := &bytes.Buffer{}, &bytes.Buffer{}
+	l := New(first, &Options{Level: LevelNotice, Color: ColorAlways})
+	l.Noticef("first")
+	l.SetOutput(second)
+	l.Noticef("second")
+	if out := first.String(); strings.Count(out, "\n") != 1 ||
+		!strings.Contains(out, "first") || !strings.Contains(out, "\x1b[") {
+		t.Fatalf("unexpected first output %q", out)
+	}
+	if out := second.String(); strings.Count(out, "\n") != 1 ||
+		!strings.Contains(out, "second") || !strings.Contains(out, "\x1b[") {
+		t.Fatalf("unexpected second output %q", out)
+	}
+
+	// colors are detected again for the new writer
+	l = New(first, &Options{Level: LevelNotice})
+	l.SetOutput(second)
+	second.Reset()
+	l.Noticef("plain")
+	if out := second.String(); strings.Contains(out, "\x1b[") {
+		t.Fatalf("unexpected colors %q", out)
+	}
+	l.SetOutput(nil)
+	l.Noticef("discarded")
+	if strings.Contains(second.String(), "discarded") {
+		t.Fatal("expected the line to be discarded")
+	}
+}
+
+func TestSetOutputConcurrent(t *testing.T) {
+	var bufs [2]lockedBuffer
+	l := New(&bufs[0], &Options{Level: LevelNotice, WriteTimeout: time.Second})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			l.Noticef("line %d", i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			l.SetOutput(&bufs[(i+1)%2])
+		}
+	}()
+	wg.Wait()
+	l.Close()
+	n := strings.Count(bufs[0].String(), "\n") +
+		strings.Count(bufs[1].String(), "\n")
+	if n != 200 {
+		t.Fatalf("expected 200 lines, got %d", n)
+	}
+}
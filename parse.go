@@ -0,0 +1,110 @@
+package redlog
+
+import (
+	"strings"
+	"time"
+)
+
+// parsedLine is a line in the redlog format.
+type parsedLine struct {
+	pid   int
+	app   byte
+	tags  string // such as "[w3]" or "@node-3/leader" after the app
+	time  string
+	level int
+	msg   string
+	// layout of the timestamp, which may be the other Redis layout
+	layout string
+}
+
+// parseLine parses a line in the redlog format, such as:
+//
+//	1234:M 02 Jan 2006 15:04:05.000 * message
+//	1234:M[w3] 02 Jan 2006 15:04:05.000 * message
+//	1234:M@node-3/leader 02 Jan 2006 15:04:05.000 * message
+//
+// where the timestamp is in the layout format. The level character '#'
+// is parsed as a warning. When layout is TimeFormatRedis or
+// TimeFormatRedisNumeric, at any TimePrecision, lines in either layout and
+// with any fraction of the seconds are accepted.
+func parseLine(line, layout string) (parsedLine, bool) {
+	pl, ok := parseLineLayout(line, layout)
+	if !ok {
+		// time.Parse accepts a fraction after the seconds of a layout
+		// without one
+		redis := trimFraction(TimeFormatRedis)
+		numeric := trimFraction(TimeFormatRedisNumeric)
+		switch trimFraction(layout) {
+		case redis:
+			if pl, ok = parseLineLayout(line, redis); !ok {
+				pl, ok = parseLineLayout(line, numeric)
+			}
+		case numeric:
+			if pl, ok = parseLineLayout(line, numeric); !ok {
+				pl, ok = parseLineLayout(line, redis)
+			}
+		}
+	}
+	return pl, ok
+}
+
+func parseLineLayout(line, layout string) (parsedLine, bool) {
+	pl := parsedLine{layout: layout}
+	i := 0
+	for ; i < len(line) && line[i] >= '0' && line[i] <= '9'; i++ {
+		pl.pid = pl.pid*10 + int(line[i]-'0')
+	}
+	if i == 0 || i > 10 || i+3 > len(line) || line[i] != ':' ||
+		line[i+1] <= ' ' || line[i+1] > '~' {
+		return pl, false
+	}
+	pl.app = line[i+1]
+	line = line[i+2:]
+	// tags are everything up to the space, starting with a '[', or an '@'
+	// for the hostname and role
+	j := strings.IndexByte(line, ' ')
+	if j == -1 || j > 0 && line[0] != '[' && line[0] != '@' {
+		return pl, false
+	}
+	pl.tags = line[:j]
+	line = line[j+1:]
+	// the timestamp has as many spaces as its layout
+	i = 0
+	for n := strings.Count(layout, " "); ; n-- {
+		j := strings.IndexByte(line[i:], ' ')
+		if j == -1 {
+			return pl, false
+		}
+		if n == 0 {
+			i += j
+			break
+		}
+		i += j + 1
+	}
+	pl.time = line[:i]
+	if _, err := time.Parse(layout, pl.time); err != nil {
+		return pl, false
+	}
+	line = line[i+1:]
+	if len(line) == 0 || (len(line) > 1 && line[1] != ' ') {
+		return pl, false
+	}
+	switch line[0] {
+	case ',':
+		pl.level = LevelTrace
+	case '.':
+		pl.level = LevelDebug
+	case '-':
+		pl.level = LevelVerbose
+	case '*':
+		pl.level = LevelNotice
+	case '#':
+		pl.level = LevelWarning
+	default:
+		return pl, false
+	}
+	if len(line) > 1 {
+		pl.msg = line[2:]
+	}
+	return pl, true
+}
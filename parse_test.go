@@ -0,0 +1,144 @@
+package redlog
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	layout := DefaultOptions.TimeFormat
+	pl, ok := parseLine("1234:C 01 Jun 2024 15:04:05.000 # hello world",
+		layout)
+	if !ok || pl.pid != 1234 || pl.app != 'C' ||
+		pl.time != "01 Jun 2024 15:04:05.000" || pl.level != LevelWarning ||
+		pl.msg != "hello world" {
+		t.Fatalf("unexpected result %v %+v", ok, pl)
+	}
+	pl, ok = parseLine("1:M 01 Jun 2024 15:04:05.000 .", layout)
+	if !ok || pl.level != LevelDebug || pl.msg != "" {
+		t.Fatalf("unexpected result %v %+v", ok, pl)
+	}
+	for _, line := range []string{
+		"",
+		"1234",
+		"1234:",
+		"1234:M",
+		":M 01 Jun 2024 15:04:05.000 * hello",
+		"12a4:M 01 Jun 2024 15:04:05.000 * hello",
+		"1234:MM 01 Jun 2024 15:04:05.000 * hello",
+		"1234:M 01 Jun 2024 15:04:05.000",
+		"1234:M 01 Jun 2024 15:04:05.000 ",
+		"1234:M 01 Jun 2024 15:04:05.000 x hello",
+		"1234:M 01 Jun 2024 15:04:05.000 *hello",
+		"1234:M 01 Jux 2024 15:04:05.000 * hello",
+		"1234:M not a valid date at all * hello",
+		"12345678901:M 01 Jun 2024 15:04:05.000 * hello",
+	} {
+		if _, ok := parseLine(line, layout); ok {
+			t.Fatalf("expected %q to fail", line)
+		}
+	}
+}
+
+func TestPassthroughFormatted(t *testing.T) {
+	var child, parent bytes.Buffer
+	cl := New(&child, &Options{Level: LevelDebug, App: 'C'})
+	cl.pid = 1234
+	cl.Debugf("debug")
+	cl.Noticef("notice")
+	cl.Warningf("warning")
+
+	pl := New(&parent, &Options{
+		Level:                LevelNotice,
+		PassthroughFormatted: true,
+	})
+	pl.pid = 5678
+	for _, line := range strings.SplitAfter(child.String(), "\n") {
+		if line != "" {
+			pl.Write([]byte(line))
+		}
+	}
+	lines := strings.Split(child.String(), "\n")
+	if parent.String() != strings.Join(lines[1:], "\n") {
+		t.Fatalf("unexpected output %q", parent.String())
+	}
+
+	parent.Reset()
+	for _, line := range []string{
+		"1234:C not a date * spoofed\n",
+		"1234:C 01 Jun 2024 15:04:05.000 *spoofed\n",
+		"just text\n",
+	} {
+		pl.Write([]byte(line))
+	}
+	lines = strings.Split(strings.TrimSpace(parent.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected output %q", parent.String())
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "5678:M ") {
+			t.Fatalf("expected fallback, got %q", line)
+		}
+	}
+
+	parent.Reset()
+	pl = New(&parent, &Options{Level: LevelNotice})
+	pl.Write([]byte(strings.Split(child.String(), "\n")[1]))
+	if strings.Count(parent.String(), ":C ") != 1 ||
+		strings.Count(parent.String(), ":M ") != 1 {
+		t.Fatalf("expected double prefix, got %q", parent.String())
+	}
+}
+
+func TestParseLineNumeric(t *testing.T) {
+	var buf bytes.Buffer
+	for i, layout := range []string{TimeFormatRedis, TimeFormatRedisNumeric,
+		TimeFormatRedis} {
+		l := New(&buf, &Options{Level: LevelNotice, TimeFormat: layout,
+			Now: func() time.Time {
+				return time.Date(2024, 6, 1, 15, 4, 5, 0, time.Local)
+			}})
+		l.pid = 1234
+		l.Noticef("line %d", i)
+		l.Warningf("line %d", i)
+	}
+	if !strings.Contains(buf.String(), "1234:M 01-06 15:04:05.000 # line 1") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	for _, layout := range []string{TimeFormatRedis, TimeFormatRedisNumeric} {
+		rd := NewReader(strings.NewReader(buf.String()),
+			TextCodec{TimeFormat: layout})
+		for i := 0; i < 6; i++ {
+			rec, err := rd.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rec.Msg != "line "+string(rune('0'+i/2)) ||
+				rec.Level != LevelNotice+i%2 || rec.Time.Month() != 6 ||
+				rec.Time.Day() != 1 || rec.Time.Hour() != 15 ||
+				rec.Time.Year() != 2024 && i/2 != 1 {
+				t.Fatalf("unexpected record %d %+v", i, rec)
+			}
+		}
+		if _, err := rd.Read(); err != io.EOF {
+			t.Fatalf("expected EOF, got %v", err)
+		}
+	}
+	// a custom layout only accepts its own lines
+	if _, ok := parseLine("1:M 01-06 15:04:05.000 * hello",
+		"2006-01-02 15:04:05"); ok {
+		t.Fatal("expected failure")
+	}
+	for _, line := range []string{
+		"1234:M 01 Jun 2024 15:04:05.000 # hello\n",
+		"1234:M 01-06 15:04:05.000 # hello\n",
+	} {
+		if out := colorizeLine(line); !strings.Contains(out,
+			"\x1b[33m#\x1b[0m") || !strings.HasPrefix(out, "\x1b[35m1234:M") {
+			t.Fatalf("unexpected colorized line %q", out)
+		}
+	}
+}
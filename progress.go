@@ -0,0 +1,60 @@
+package redlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// eraseLine returns the cursor to the start of the line and clears it.
+const eraseLine = "\r\x1b[K"
+
+// Progress shows a transient status line, such as "loading 42%", on a
+// terminal. Each call replaces the previous status. The status is erased
+// before a line is logged and redrawn after it. Does nothing when the
+// writer is not a terminal.
+func (l *Logger) Progress(format string, args ...interface{}) {
+	if !l.isTerminal() || l.encoder != nil {
+		return
+	}
+	status := fmt.Sprintf(format, args...)
+	if i := strings.IndexAny(status, "\r\n"); i != -1 {
+		status = status[:i]
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return
+	}
+	l.progress = append(l.progress[:0], status...)
+	l.writeProgress(append([]byte(eraseLine), status...))
+}
+
+// ProgressDone erases the status line of Progress.
+func (l *Logger) ProgressDone() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.progress == nil || l.isClosed() {
+		return
+	}
+	l.progress = nil
+	l.writeProgress([]byte(eraseLine))
+}
+
+// writeProgress writes to the terminal once the queued lines are written.
+// It's called with the mutex held, which is kept while writing.
+func (l *Logger) writeProgress(p []byte) {
+	l.waitFlush()
+	l.writeLine(l.output(), p)
+}
+
+// withProgress returns a line for the terminal that erases the status of
+// Progress before it, and redraws it after. It's called with the mutex
+// held.
+func (l *Logger) withProgress(line []byte) []byte {
+	if l.progress == nil {
+		return line
+	}
+	l.pbuf = append(append(l.pbuf[:0], eraseLine...), line...)
+	l.pbuf = append(l.pbuf, l.progress...)
+	return l.pbuf
+}
@@ -0,0 +1,46 @@
+package redlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{
+		Color: ColorAlways,
+		Formatter: func(dst []byte, rec Record, tty bool) []byte {
+			return append(dst, rec.Msg...)
+		},
+	})
+	l.Noticef("before")
+	l.Progress("loading %d%%", 1)
+	l.Progress("loading %d%%\nignored", 2)
+	l.Noticef("line 1")
+	l.Noticef("line 2")
+	l.Progress("loading %d%%", 3)
+	l.ProgressDone()
+	l.ProgressDone()
+	l.Noticef("after")
+	exp := "before\n" +
+		"\r\x1b[Kloading 1%" +
+		"\r\x1b[Kloading 2%" +
+		"\r\x1b[Kline 1\nloading 2%" +
+		"\r\x1b[Kline 2\nloading 2%" +
+		"\r\x1b[Kloading 3%" +
+		"\r\x1b[K" +
+		"after\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+
+	// not a terminal
+	buf.Reset()
+	l = New(buf, nil)
+	l.Progress("loading")
+	l.Noticef("line")
+	l.ProgressDone()
+	if bytes.Contains(buf.Bytes(), []byte("\r")) {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
@@ -0,0 +1,95 @@
+package redlog
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimit is a limit of the rate of lines, for the RateLimit option and
+// RateLimited.
+type RateLimit struct {
+	Every time.Duration // a line is allowed every Every
+	Burst int           // lines allowed at once, at least 1
+	// PerMessage gives each message a limit of its own, so a hot message
+	// does not drop the others. The message is the format of the Printf
+	// style functions, such as "failed to connect to %s", or else the
+	// first argument when it is a string. Lines without either share a
+	// limit.
+	PerMessage bool
+}
+
+// maxLimiterKeys is the number of messages with a limit of their own,
+// after which the limits of all of them are reset.
+const maxLimiterKeys = 4096
+
+// bucket holds the tokens of a limit.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter is a token bucket, or a bucket per message for PerMessage.
+type limiter struct {
+	mu    sync.Mutex
+	every time.Duration
+	burst float64
+	all   bucket
+	keys  map[string]*bucket // nil without PerMessage
+}
+
+func newLimiter(limit RateLimit) *limiter {
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	lm := &limiter{every: limit.Every, burst: float64(burst)}
+	lm.all.tokens = lm.burst
+	if limit.PerMessage {
+		lm.keys = make(map[string]*bucket)
+	}
+	return lm
+}
+
+// allow returns true if a line with the message key is allowed at now,
+// taking its token.
+func (lm *limiter) allow(now time.Time, key string) bool {
+	if lm.every <= 0 {
+		return true
+	}
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	b := &lm.all
+	if lm.keys != nil && key != "" {
+		b = lm.keys[key]
+		if b == nil {
+			if len(lm.keys) >= maxLimiterKeys {
+				lm.keys = make(map[string]*bucket)
+			}
+			b = &bucket{tokens: lm.burst}
+			lm.keys[key] = b
+		}
+	}
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens += float64(now.Sub(b.last)) / float64(lm.every)
+		if b.tokens > lm.burst {
+			b.tokens = lm.burst
+		}
+	}
+	if b.last.IsZero() || now.After(b.last) {
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// RateLimited returns a logger that logs at most Burst lines at once, and
+// then a line every Every, such as for a hot error path. Each call returns
+// a logger with its own limit, in addition to the RateLimit option. Lines
+// are dropped before they are formatted, and they are counted in Stats as
+// Suppressed. See RateLimit.PerMessage for a limit per message.
+func (l *Logger) RateLimited(limit RateLimit) *Logger {
+	return l.withSampler(&sampler{limit: newLimiter(limit)})
+}
@@ -0,0 +1,118 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	l := New(&buf, &Options{Level: LevelNotice,
+		Now:       func() time.Time { return now },
+		RateLimit: RateLimit{Every: time.Second, Burst: 3}})
+	for i := 0; i < 10; i++ {
+		l.Warningf("failed %d", i)
+	}
+	now = now.Add(1500 * time.Millisecond)
+	for i := 10; i < 20; i++ {
+		l.WithModule("m").Warningf("failed %d", i)
+	}
+	now = now.Add(time.Hour)
+	for i := 20; i < 30; i++ {
+		l.Warningf("failed %d", i)
+	}
+	got := strings.Count(buf.String(), "\n")
+	if got != 3+1+3 || !strings.Contains(buf.String(), "failed 10\n") ||
+		l.Stats().Suppressed != 30-7 {
+		t.Fatalf("unexpected lines %d %+v\n%s", got, l.Stats(), buf.String())
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	l := New(&buf, &Options{Level: LevelNotice,
+		Now: func() time.Time { return now }})
+	hot := l.RateLimited(RateLimit{Every: time.Minute})
+	for i := 0; i < 10; i++ {
+		hot.Warningf("hot %d", i)
+		l.Noticef("cold %d", i)
+	}
+	now = now.Add(time.Minute)
+	hot.Warningf("hot again")
+	out := buf.String()
+	if strings.Count(out, "hot") != 2 || strings.Count(out, "cold") != 10 ||
+		!strings.HasSuffix(out, " # hot again\n") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestRateLimitPerMessage(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	l := New(&buf, &Options{Level: LevelNotice, Format: FormatPlain,
+		Now: func() time.Time { return now },
+		RateLimit: RateLimit{Every: time.Minute, Burst: 2,
+			PerMessage: true}})
+	for i := 0; i < 10; i++ {
+		l.Warningf("hot %d", i)
+		l.Warning("warm")
+		l.Noticef("cold %d", i)
+		if i == 0 {
+			l.Notice(i)
+			l.Notice(i)
+			l.Notice(i)
+		}
+	}
+	exp := "warning: hot 0\nwarning: warm\ncold 0\n0\n0\n" +
+		"warning: hot 1\nwarning: warm\ncold 1\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+	if s := l.Stats().Suppressed; s != 30+3-8 {
+		t.Fatalf("expected %d suppressed, got %d", 30+3-8, s)
+	}
+}
+
+func TestRateLimitWrite(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	l := New(&buf, &Options{Level: LevelNotice, Format: FormatPlain,
+		Now: func() time.Time { return now },
+		Filter: func(line string, tty bool) (string, byte, int) {
+			return strings.TrimSpace(line), 0, LevelWarning
+		},
+		RateLimit: RateLimit{Every: time.Minute, Burst: 2}})
+	for i := 0; i < 5; i++ {
+		l.Write([]byte("from a library\n"))
+	}
+	exp := "warning: from a library\nwarning: from a library\n"
+	if buf.String() != exp || l.Stats().Suppressed != 3 {
+		t.Fatalf("unexpected output %q %+v", buf.String(), l.Stats())
+	}
+}
+
+func TestRateLimitCrashRing(t *testing.T) {
+	// lines for the ring only do not take tokens
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice, Format: FormatPlain,
+		CrashRing: 10, RateLimit: RateLimit{Every: time.Hour, Burst: 1}})
+	l.Debugf("for the ring")
+	l.Noticef("shown")
+	hot := New(&buf, &Options{Level: LevelNotice, Format: FormatPlain,
+		CrashRing: 10}).RateLimited(RateLimit{Every: time.Hour, Burst: 1})
+	hot.Debugf("for the ring")
+	hot.Warningf("hot")
+	if buf.String() != "shown\nwarning: hot\n" ||
+		l.Stats().Suppressed != 0 || hot.Stats().Suppressed != 0 {
+		t.Fatalf("unexpected output %q %+v", buf.String(), l.Stats())
+	}
+	var ring bytes.Buffer
+	l.DumpRing(&ring)
+	if !strings.Contains(ring.String(), "for the ring") {
+		t.Fatalf("unexpected ring %q", ring.String())
+	}
+}
@@ -0,0 +1,87 @@
+package redlog
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// PanicError is the value of the panics of Panic with the PanicErrors
+// option.
+type PanicError struct {
+	Level Level
+	Time  time.Time
+	Msg   string
+}
+
+func (e *PanicError) Error() string { return e.Msg }
+
+// panic dumps the crash ring, and panics with the message of Panic.
+func (l *Logger) panic(msg string) {
+	l.DumpRing(l.output())
+	msg = strings.TrimRight(msg, "\t\r\n ")
+	if l.panicErrs {
+		panic(&PanicError{Level: levelError, Time: l.now(), Msg: msg})
+	}
+	panic(msg)
+}
+
+// Recover logs a panic as an error, with its stack trace as indented
+// continuation lines, and then panics again with the same value. It's
+// meant to be deferred at the top of a goroutine:
+//
+//	defer l.Recover()
+func (l *Logger) Recover() {
+	if r := recover(); r != nil {
+		l.logPanic(r)
+		panic(r)
+	}
+}
+
+// Go runs fn in a goroutine that logs a panic like Recover. The panic is
+// then raised again, crashing the program, unless the SwallowPanics option
+// is set.
+func (l *Logger) Go(fn func()) {
+	go func() {
+		if l.swallow {
+			defer l.swallowPanic()
+		} else {
+			defer l.Recover()
+		}
+		fn()
+	}()
+}
+
+func (l *Logger) swallowPanic() {
+	if r := recover(); r != nil {
+		l.logPanic(r)
+	}
+}
+
+// panicStack carries the stack of a panic to write.
+type panicStack struct {
+	msg string
+	pcs []uintptr
+}
+
+func (e panicStack) Error() string         { return e.msg }
+func (e panicStack) StackTrace() []uintptr { return e.pcs }
+
+// logPanic logs the panic value r with the stack of the panicking
+// goroutine, and waits for it to be written.
+func (l *Logger) logPanic(r interface{}) {
+	pcs := make([]uintptr, 64)
+	// skips runtime.Callers, logPanic, and the deferred func
+	pcs = pcs[:runtime.Callers(3, pcs)]
+	msg := fmt.Sprintf("panic: %v", r)
+	var arg interface{} = msg + formatStack(pcs)
+	if l.stackLevel > 0 && levelError >= l.stackLevel {
+		// write appends the stack
+		arg = panicStack{msg, pcs}
+	}
+	write(false, l, l.App(), levelError, "", []interface{}{arg}, nil)
+	l.mu.Lock()
+	l.waitFlush()
+	l.mu.Unlock()
+}
@@ -0,0 +1,80 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecover(t *testing.T) {
+	for _, stackLevel := range []int{0, LevelWarning} {
+		buf := &bytes.Buffer{}
+		l := New(buf, &Options{StackTraceLevel: stackLevel})
+		var r interface{}
+		func() {
+			defer func() { r = recover() }()
+			defer l.Recover()
+			panic("boom")
+		}()
+		if r != "boom" {
+			t.Fatalf("expected the panic again, got %v", r)
+		}
+		out := buf.String()
+		lines := strings.Split(out, "\n")
+		if !strings.HasSuffix(lines[0], " # panic: boom") ||
+			!strings.HasPrefix(lines[1], "    "+pkgPath+".TestRecover.") ||
+			strings.Count(out, "TestRecover.") != 1 ||
+			strings.Contains(out, "(*Logger).Recover") ||
+			strings.Contains(out, "runtime.gopanic") {
+			t.Fatalf("unexpected output %q", out)
+		}
+	}
+}
+
+func TestGo(t *testing.T) {
+	w := &slowWriter{}
+	l := New(w, &Options{SwallowPanics: true})
+	l.Go(func() { panic("from goroutine") })
+	for i := 0; ; i++ {
+		w.mu.Lock()
+		n := len(w.calls)
+		w.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("expected the panic to be logged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if out := string(w.calls[0]); !strings.Contains(out,
+		" # panic: from goroutine\n") || !strings.Contains(out, ".TestGo.") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestPanicMessage(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	l := New(&buf, &Options{Now: func() time.Time { return now }})
+	recovered := func(fn func()) (r interface{}) {
+		defer func() { r = recover() }()
+		fn()
+		return nil
+	}
+	if r := recovered(func() { l.Panicf("bad %s\n", "state") }); r != "bad state" {
+		t.Fatalf("unexpected panic %#v", r)
+	}
+	if r := recovered(func() { l.Panic("bad", 1) }); r != "bad1" {
+		t.Fatalf("unexpected panic %#v", r)
+	}
+	l = New(&buf, &Options{Now: func() time.Time { return now },
+		PanicErrors: true})
+	r := recovered(func() { l.Panicln("bad", "state") })
+	err, ok := r.(*PanicError)
+	if !ok || err.Error() != "badstate" || err.Level.String() != "error" ||
+		!err.Time.Equal(now) {
+		t.Fatalf("unexpected panic %#v", r)
+	}
+}
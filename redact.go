@@ -0,0 +1,94 @@
+package redlog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Redaction masks secrets before lines are written, for the Redaction
+// option and Redacted.
+type Redaction struct {
+	// Keys are the names of secrets, such as "password" or "AUTH". The
+	// values of pairs with these keys are masked, and so is the word after
+	// a key in messages, such as "password=hunter2" or "AUTH hunter2".
+	// Keys are not case sensitive.
+	Keys []string
+	// Patterns mask their matches in messages, such as the numbers of
+	// credit cards.
+	Patterns []*regexp.Regexp
+	// Mask replaces the secrets. Defaults to "***".
+	Mask string
+}
+
+// redactor applies a Redaction, after the one of its parent logger.
+type redactor struct {
+	parent *redactor
+	keys   map[string]bool // lower case
+	words  *regexp.Regexp  // key, separator, and value in messages
+	rules  []*regexp.Regexp
+	mask   string
+}
+
+func newRedactor(r Redaction, parent *redactor) *redactor {
+	rd := &redactor{parent: parent, rules: r.Patterns, mask: r.Mask}
+	if rd.mask == "" {
+		rd.mask = "***"
+	}
+	if len(r.Keys) > 0 {
+		rd.keys = make(map[string]bool)
+		quoted := make([]string, len(r.Keys))
+		for i, key := range r.Keys {
+			rd.keys[strings.ToLower(key)] = true
+			quoted[i] = regexp.QuoteMeta(key)
+		}
+		rd.words = regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") +
+			`)(\s*[=:]\s*|\s+)("(?:[^"\\]|\\.)*"|\S+)`)
+	}
+	return rd
+}
+
+// Redacted returns a logger that masks secrets with the Redaction, such as
+// for a component that logs requests. The rules of its parent still apply.
+// It shares the writer and settings of its parent.
+func (l *Logger) Redacted(r Redaction) *Logger {
+	child := l.clone()
+	child.redact = newRedactor(r, l.redact)
+	return child
+}
+
+// message masks the secrets of line[ms:].
+func (rd *redactor) message(line []byte, ms int) []byte {
+	if rd.parent != nil {
+		line = rd.parent.message(line, ms)
+	}
+	msg := line[ms:]
+	if rd.words != nil {
+		msg = rd.words.ReplaceAll(msg, []byte("${1}${2}"+
+			strings.Replace(rd.mask, "$", "$$", -1)))
+	}
+	for _, re := range rd.rules {
+		msg = re.ReplaceAllLiteral(msg, []byte(rd.mask))
+	}
+	return append(line[:ms], msg...)
+}
+
+// pairs returns the key value pairs with the values of secrets masked,
+// copying them when needed.
+func (rd *redactor) pairs(kvs []interface{}) []interface{} {
+	if rd.parent != nil {
+		kvs = rd.parent.pairs(kvs)
+	}
+	copied := false
+	for i := 0; i+1 < len(kvs); i += 2 {
+		if !rd.keys[strings.ToLower(fmt.Sprint(kvs[i]))] {
+			continue
+		}
+		if !copied {
+			kvs = append([]interface{}(nil), kvs...)
+			copied = true
+		}
+		kvs[i+1] = rd.mask
+	}
+	return kvs
+}
@@ -0,0 +1,52 @@
+package redlog
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Format: FormatPlain, Redaction: Redaction{
+		Keys: []string{"password", "AUTH"},
+	}})
+	l.Noticef("client sent AUTH hunter2")
+	l.Noticef("login user=bob Password=hunter2 ok")
+	l.Noticef(`login password: "hunter 2" ok`)
+	l.With("password", "hunter2", "user", "bob").Noticef("login")
+	l.Noticef("authority hunter2")
+	exp := "client sent AUTH ***\n" +
+		"login user=bob Password=*** ok\n" +
+		"login password: *** ok\n" +
+		"login password=*** user=bob\n" +
+		"authority hunter2\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+
+	// child loggers add rules
+	buf.Reset()
+	card := regexp.MustCompile(`\b\d{4}(-\d{4}){3}\b`)
+	rl := l.Redacted(Redaction{Patterns: []*regexp.Regexp{card},
+		Mask: "[$]"})
+	rl.With("password", "x").Noticef("card 1234-5678-9012-3456 AUTH y")
+	l.Noticef("card 1234-5678-9012-3456")
+	exp = "card [$] AUTH *** password=***\n" +
+		"card 1234-5678-9012-3456\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
+
+func TestRedactionRecord(t *testing.T) {
+	var recs []Record
+	l := New(&bytes.Buffer{}, &Options{Redaction: Redaction{
+		Keys: []string{"token"}}})
+	l.AddHook(func(rec Record) { recs = append(recs, rec) })
+	l.With("token", "abc").Noticef("token abc")
+	if len(recs) != 1 || recs[0].Msg != "token ***" ||
+		recs[0].KVs[1] != "***" {
+		t.Fatalf("unexpected records %v", recs)
+	}
+}
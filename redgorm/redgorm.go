@@ -0,0 +1,92 @@
+// Package redgorm adapts a redlog Logger to GORM, in a module of its own so
+// that redlog does not depend on GORM.
+package redgorm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tidwall/redlog/v2"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// since returns the time elapsed since a statement began, replaced by the
+// tests.
+var since = time.Since
+
+// logger adapts a Logger to the logger.Interface of GORM.
+type logger struct {
+	l     *redlog.Logger
+	slow  time.Duration
+	level gormlogger.LogLevel
+}
+
+// New returns a GORM logger that logs to l, for the Logger of a
+// gorm.Config. Statements are logged as debug, with the "duration",
+// "rows", and "sql" keys, statements slower than slow as warnings, and
+// failed statements at the error level. A record that is not found is not
+// an error. A zero slow disables the warnings.
+func New(l *redlog.Logger, slow time.Duration) gormlogger.Interface {
+	return &logger{l: l, slow: slow, level: gormlogger.Info}
+}
+
+// LogMode returns a logger with the GORM level, which only logs errors for
+// gormlogger.Error, and also slow statements for gormlogger.Warn. The
+// level of the logger applies as well.
+func (g *logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	g2 := *g
+	g2.level = level
+	return &g2
+}
+
+func (g *logger) Info(_ context.Context, format string,
+	args ...interface{}) {
+	if g.level >= gormlogger.Info {
+		g.l.Noticef(format, args...)
+	}
+}
+
+func (g *logger) Warn(_ context.Context, format string,
+	args ...interface{}) {
+	if g.level >= gormlogger.Warn {
+		g.l.Warningf(format, args...)
+	}
+}
+
+func (g *logger) Error(_ context.Context, format string,
+	args ...interface{}) {
+	if g.level >= gormlogger.Error {
+		g.l.Errorf(format, args...)
+	}
+}
+
+func (g *logger) Trace(_ context.Context, begin time.Time,
+	fc func() (sql string, rowsAffected int64), err error) {
+	if g.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := since(begin)
+	var level int
+	var msg string
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = redlog.LevelError, "sql error: "+err.Error()
+	case g.slow > 0 && elapsed >= g.slow && g.level >= gormlogger.Warn:
+		level, msg = redlog.LevelWarning, "slow sql"
+	case g.level >= gormlogger.Info:
+		level, msg = redlog.LevelDebug, "sql"
+	default:
+		return
+	}
+	if !g.l.Enabled(level) {
+		return
+	}
+	sql, rows := fc()
+	kvs := []interface{}{"duration", elapsed}
+	if rows >= 0 {
+		kvs = append(kvs, "rows", rows)
+	}
+	g.l.Log(level, msg, append(kvs, "sql", sql)...)
+}
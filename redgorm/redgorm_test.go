@@ -0,0 +1,61 @@
+package redgorm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tidwall/redlog/v2"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	since = func(begin time.Time) time.Duration { return now.Sub(begin) }
+	defer func() { since = time.Since }()
+	l := redlog.New(&buf, &redlog.Options{Level: redlog.LevelDebug})
+	ctx := context.Background()
+	g := New(l, 100*time.Millisecond)
+	query := func(sql string, rows int64) func() (string, int64) {
+		return func() (string, int64) { return sql, rows }
+	}
+	g.Info(ctx, "migrating %s", "users")
+	g.Trace(ctx, now.Add(-5*time.Millisecond), query("SELECT 1", 1), nil)
+	g.Trace(ctx, now.Add(-time.Second), query("SELECT * FROM users", -1), nil)
+	g.Trace(ctx, now, query("SELECT 2", 0), gorm.ErrRecordNotFound)
+	g.Trace(ctx, now, query("INSERT", 0), errors.New("duplicate key"))
+
+	warn := g.LogMode(gormlogger.Warn)
+	warn.Info(ctx, "hidden")
+	warn.Trace(ctx, now, query("hidden", 1), nil)
+	warn.Warn(ctx, "deprecated %s", "option")
+	g.LogMode(gormlogger.Silent).Error(ctx, "hidden")
+	g.LogMode(gormlogger.Error).Error(ctx, "failed %d", 1)
+
+	want := []string{
+		`* migrating users`,
+		`. sql duration=5ms rows=1 sql="SELECT 1"`,
+		`# slow sql duration=1s sql="SELECT * FROM users"`,
+		`. sql duration=0s rows=0 sql="SELECT 2"`,
+		`# sql error: duplicate key duration=0s rows=0 sql=INSERT`,
+		`# deprecated option`,
+		`# failed 1`,
+	}
+	if got := messages(&buf); got != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", got)
+	}
+}
+
+// messages returns the lines of buf without the pid, role, and time.
+func messages(buf *bytes.Buffer) string {
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		msgs = append(msgs, strings.SplitN(line, " ", 6)[5])
+	}
+	return strings.Join(msgs, "\n")
+}
@@ -0,0 +1,10 @@
+module github.com/tidwall/redlog/v2/redhclog
+
+go 1.15
+
+require (
+	github.com/hashicorp/go-hclog v1.6.3
+	github.com/tidwall/redlog/v2 v2.0.0
+)
+
+replace github.com/tidwall/redlog/v2 => ../
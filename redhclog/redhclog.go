@@ -0,0 +1,201 @@
+// Package redhclog adapts a redlog Logger to hclog, in a module of its own
+// so that redlog does not depend on hclog.
+package redhclog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/tidwall/redlog/v2"
+)
+
+// hclogger adapts a Logger to hclog.Logger.
+type hclogger struct {
+	root *redlog.Logger // the logger passed to New
+	l    *redlog.Logger // the module of the name
+	name string
+	args []interface{} // implied args from With
+}
+
+// New returns an hclog.Logger that logs to l, such as for the Logger
+// of a hashicorp/raft Config. Messages are prefixed with the name of the
+// logger, such as "raft: ", and the args are appended as " key=value".
+// A named logger is a module of l, so its level can be set separately
+// with SetLevel or SetModuleLevel.
+func New(l *redlog.Logger) hclog.Logger {
+	return &hclogger{root: l, l: l}
+}
+
+// hclogLevel maps an hclog level to a level of the logger, and returns
+// false for hclog.Off.
+func hclogLevel(level hclog.Level) (int, bool) {
+	switch level {
+	case hclog.Trace:
+		return redlog.LevelTrace, true
+	case hclog.Debug:
+		return redlog.LevelDebug, true
+	case hclog.Warn:
+		return redlog.LevelWarning, true
+	case hclog.Error:
+		return redlog.LevelError, true
+	case hclog.Off:
+		return 0, false
+	}
+	return redlog.LevelNotice, true
+}
+
+func (h *hclogger) Log(level hclog.Level, msg string, args ...interface{}) {
+	lv, ok := hclogLevel(level)
+	if !ok || !h.l.Enabled(lv) {
+		return
+	}
+	if h.name != "" {
+		msg = h.name + ": " + msg
+	}
+	kvs, copied := args, false
+	if len(h.args) > 0 {
+		kvs = append(append([]interface{}(nil), h.args...), args...)
+		copied = true
+	}
+	for i := 1; i < len(kvs); i += 2 {
+		if f, ok := kvs[i].(hclog.Format); ok && len(f) > 0 {
+			if !copied {
+				kvs = append([]interface{}(nil), kvs...)
+				copied = true
+			}
+			kvs[i] = fmt.Sprintf(fmt.Sprint(f[0]), f[1:]...)
+		}
+	}
+	h.l.Log(lv, msg, kvs...)
+}
+
+func (h *hclogger) Trace(msg string, args ...interface{}) {
+	h.Log(hclog.Trace, msg, args...)
+}
+
+func (h *hclogger) Debug(msg string, args ...interface{}) {
+	h.Log(hclog.Debug, msg, args...)
+}
+
+func (h *hclogger) Info(msg string, args ...interface{}) {
+	h.Log(hclog.Info, msg, args...)
+}
+
+func (h *hclogger) Warn(msg string, args ...interface{}) {
+	h.Log(hclog.Warn, msg, args...)
+}
+
+func (h *hclogger) Error(msg string, args ...interface{}) {
+	h.Log(hclog.Error, msg, args...)
+}
+
+func (h *hclogger) IsTrace() bool { return h.l.Enabled(redlog.LevelTrace) }
+func (h *hclogger) IsDebug() bool { return h.l.Enabled(redlog.LevelDebug) }
+func (h *hclogger) IsInfo() bool  { return h.l.Enabled(redlog.LevelNotice) }
+func (h *hclogger) IsWarn() bool  { return h.l.Enabled(redlog.LevelWarning) }
+func (h *hclogger) IsError() bool { return true }
+
+func (h *hclogger) ImpliedArgs() []interface{} {
+	return h.args
+}
+
+func (h *hclogger) With(args ...interface{}) hclog.Logger {
+	h2 := *h
+	h2.args = append(append([]interface{}(nil), h.args...), args...)
+	return &h2
+}
+
+func (h *hclogger) Name() string {
+	return h.name
+}
+
+func (h *hclogger) Named(name string) hclog.Logger {
+	if h.name != "" {
+		name = h.name + "." + name
+	}
+	return h.ResetNamed(name)
+}
+
+func (h *hclogger) ResetNamed(name string) hclog.Logger {
+	h2 := *h
+	h2.name = name
+	h2.l = h.root
+	if name != "" {
+		h2.l = h.root.WithModule(name)
+	}
+	return &h2
+}
+
+// SetLevel sets the level of the module of a named logger, or else of the
+// logger. Error and Off are set as the warning level, the highest level of
+// the logger.
+func (h *hclogger) SetLevel(level hclog.Level) {
+	lv, ok := hclogLevel(level)
+	if !ok || lv > redlog.LevelWarning {
+		lv = redlog.LevelWarning
+	}
+	if h.name != "" {
+		h.l.SetModuleLevel(h.name, lv)
+	} else {
+		h.l.SetLevel(lv)
+	}
+}
+
+func (h *hclogger) GetLevel() hclog.Level {
+	switch {
+	case h.IsTrace():
+		return hclog.Trace
+	case h.IsDebug():
+		return hclog.Debug
+	case !h.IsInfo():
+		return hclog.Warn
+	}
+	return hclog.Info
+}
+
+func (h *hclogger) StandardLogger(opts *hclog.StandardLoggerOptions) *log.Logger {
+	return log.New(h.StandardWriter(opts), "", 0)
+}
+
+// StandardWriter returns a writer that logs each line at the level of
+// opts.ForceLevel, or else at the info level. With opts.InferLevels, a
+// line with a level prefix, such as "[WARN] message", is logged at that
+// level.
+func (h *hclogger) StandardWriter(opts *hclog.StandardLoggerOptions) io.Writer {
+	if opts == nil {
+		opts = &hclog.StandardLoggerOptions{}
+	}
+	return hclogWriter{h, *opts}
+}
+
+type hclogWriter struct {
+	h    *hclogger
+	opts hclog.StandardLoggerOptions
+}
+
+func (w hclogWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\r\n"), []byte("\n")) {
+		msg := string(bytes.TrimRight(line, "\r"))
+		level := hclog.Info
+		if w.opts.ForceLevel != hclog.NoLevel {
+			level = w.opts.ForceLevel
+		}
+		if i := strings.IndexByte(msg, ']'); w.opts.InferLevels &&
+			strings.HasPrefix(msg, "[") && i != -1 {
+			word := msg[1:i]
+			if word == "ERR" {
+				word = "error" // the prefix of older hashicorp libraries
+			}
+			if lv := hclog.LevelFromString(word); lv != hclog.NoLevel {
+				level = lv
+				msg = strings.TrimLeft(msg[i+1:], " ")
+			}
+		}
+		w.h.Log(level, msg)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,63 @@
+package redhclog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/tidwall/redlog/v2"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := redlog.New(&buf, &redlog.Options{Level: redlog.LevelNotice})
+	h := New(l)
+	raft := h.Named("raft")
+	raft.Debug("hidden")
+	raft.Info("entering follower state", "follower", "node 1", "leader", nil)
+	raft.With("peer", 2).Warn("heartbeat failed", "err", "timeout")
+	raft.Named("snapshot").Error("failed", "size", hclog.Fmt("%dMB", 12))
+	raft.ResetNamed("").Info("unnamed", "odd")
+	h.Log(hclog.Off, "off")
+
+	raft.SetLevel(hclog.Debug)
+	if !raft.IsDebug() || h.IsDebug() || raft.GetLevel() != hclog.Debug ||
+		h.GetLevel() != hclog.Info || raft.Name() != "raft" ||
+		raft.Named("fsm").Name() != "raft.fsm" {
+		t.Fatal("unexpected levels or names")
+	}
+	raft.Debug("shown")
+	h.Debug("hidden")
+	if args := raft.With("a", 1).With("b", 2).ImpliedArgs(); len(args) != 4 {
+		t.Fatalf("unexpected args %v", args)
+	}
+
+	std := raft.StandardLogger(&hclog.StandardLoggerOptions{InferLevels: true})
+	std.Printf("[WARN] raft: from std")
+	std.Printf("[ERR] raft: older error")
+	std.Printf("[bracket] kept")
+
+	want := []string{
+		`* raft: entering follower state follower="node 1" leader=<nil>`,
+		`# raft: heartbeat failed peer=2 err=timeout`,
+		`# raft.snapshot: failed size=12MB`,
+		`* unnamed odd=`,
+		`. raft: shown`,
+		`# raft: raft: from std`,
+		`# raft: raft: older error`,
+		`* raft: [bracket] kept`,
+	}
+	if got := messages(&buf); got != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", got)
+	}
+}
+
+// messages returns the lines of buf without the pid, role, and time.
+func messages(buf *bytes.Buffer) string {
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		msgs = append(msgs, strings.SplitN(line, " ", 6)[5])
+	}
+	return strings.Join(msgs, "\n")
+}
@@ -1,9 +1,11 @@
 // Package redlog provides a Redis compatible logger.
-//   http://build47.com/redis-log-format-levels/
+//
+//	http://build47.com/redis-log-format-levels/
 package redlog
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -14,28 +16,288 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/ssh/terminal"
 )
 
+// Levels
 const (
-	levelDebug   = 0 // '.'
-	levelVerbose = 1 // '-'
-	levelNotice  = 2 // '*'
-	levelWarning = 3 // '#'
-	levelError   = 4 // '#' special condition, red
+	LevelTrace   = -1 // ','
+	LevelDebug   = 0  // '.'
+	LevelVerbose = 1  // '-'
+	LevelNotice  = 2  // '*'
+	LevelWarning = 3  // '#'
+	levelError   = 4  // '#' special condition, red
 )
 
-var levelChars = []byte{'.', '-', '*', '#', '#'}
-var levelColors = []string{"35", "", "1", "33", "31"}
+// LevelDrop may be returned by a Filter to discard the line entirely.
+const LevelDrop = -1 << 31
+
+// LevelKeep may be returned by a Filter that finds no level in the line, to
+// keep the level of the filters before it in ChainFilters. It's logged as
+// a notice otherwise.
+const LevelKeep = LevelDrop + 1
+
+// The following are indexed by level-LevelTrace.
+var levelChars = []byte{',', '.', '-', '*', '#', '#'}
+var levelColors = []string{"2", "35", "", "1", "33", "31"}
+var levelNames = []string{"trace", "debug", "verbose", "notice", "warning"}
+var levelWords = []string{"TRC", "DBG", "VRB", "NTC", "WRN", "ERR"}
+
+// parseLevel parses a level name, such as "notice", or number.
+func parseLevel(s string) (int, error) {
+	for i, name := range levelNames {
+		level := i + LevelTrace
+		if strings.EqualFold(s, name) || s == strconv.Itoa(level) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid level %q", s)
+}
+
+// Color modes
+const (
+	ColorAuto   = 0 // colorize when writing to a terminal
+	ColorAlways = 1 // always colorize
+	ColorNever  = 2 // never colorize
+)
+
+// Output formats
+const (
+	FormatRedis  = 0 // pid, app, timestamp, level, and message
+	FormatPlain  = 1 // only the message, for command line tools
+	FormatJSON   = 2 // a JSON object per line, see JSONCodec
+	FormatLogfmt = 3 // logfmt pairs per line, see LogfmtCodec
+)
+
+// Multi-line modes, for messages with newlines
+const (
+	MultilineKeep   = 0 // write the newlines as they are
+	MultilineSplit  = 1 // repeat the prefix on each line
+	MultilineIndent = 2 // indent the lines after the first to the message
+)
+
+// Timestamp layouts for the TimeFormat option. Lines in either layout are
+// recognized when reading and colorizing logs that use one of them.
+// TimeFormatRedis, the default, is the layout of redis-server 4.0 and
+// later, with the year, such as "1:M 12 Mar 2024 10:11:12.123 * message".
+const (
+	TimeFormatRedis        = "02 Jan 2006 15:04:05.000"
+	TimeFormatRedisNumeric = "02-01 15:04:05.000" // day-month, no names
+)
+
+// plainPrefixes are the prefixes of the plain format, by level.
+var plainPrefixes = []string{"", "", "", "", "warning", "error"}
 
 // Options ...
 type Options struct {
 	Level      int
-	Filter     func(line string, tty bool) (msg string, app byte, level int)
+	Filter     Filter
 	PostFilter func(line string, tty bool) string
 	TimeFormat string
 	App        byte
+	Color      int
+	// Filters convert the lines written with Write in order, after the
+	// Filter, such as to drop noise from the lines of HashicorpRaftFilter.
+	// See ChainFilters.
+	Filters []Filter
+	// Format is the output format. FormatPlain writes only the message,
+	// prefixed with "warning: " or "error: " for warnings and errors.
+	// FormatJSON and FormatLogfmt write records in those formats, unless
+	// an Encoder or Formatter is set.
+	Format int
+	// Multiline is how newlines in messages are written by the text
+	// formats, so that each line of the log is an entry, or belongs to the
+	// entry above it. MultilineSplit writes each line with the prefix of
+	// the entry, and MultilineIndent indents the lines after the first to
+	// the start of the message. The lines of an entry are written together,
+	// and stack traces are handled the same way.
+	Multiline int
+	// Rules drop, relevel, or rewrite the messages that match them, in
+	// order, such as to silence a noisy message. See SetRules and
+	// ParseRules.
+	Rules []Rule
+	// Redaction masks secrets in messages and pairs before they are
+	// written, such as Redaction{Keys: []string{"password", "AUTH"}}. See
+	// Redacted for the rules of a child logger.
+	Redaction Redaction
+	// Sanitize escapes the control characters in messages and pairs, such
+	// as "\x1b" for an escape, so that a logged payload cannot fake a line
+	// or control a terminal. SanitizeUTF8 also escapes invalid UTF-8.
+	// Newlines are kept when the Multiline option handles them. Encoders
+	// escape the pairs themselves.
+	Sanitize int
+	// Pretty renders a developer friendly output when colors are enabled,
+	// with dimmed metadata and level words such as NTC and WRN.
+	Pretty bool
+	// CondenseTimestamps blanks out a timestamp that is the same as the
+	// one on the previous line. Only used when colors are enabled, so it
+	// never applies to files.
+	CondenseTimestamps bool
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+	// UTC uses UTC instead of local time for timestamps and rotation.
+	UTC bool
+	// Location is the time zone of timestamps and rotation, such as from
+	// time.LoadLocation. It overrides UTC.
+	Location *time.Location
+	// TimePrecision is the resolution of the seconds in the TimeFormat,
+	// time.Millisecond, time.Microsecond, time.Nanosecond, or time.Second
+	// for none. Zero keeps the fraction of the layout.
+	TimePrecision time.Duration
+	// ModuleLevels overrides the level of modules, such as
+	// {"raft": LevelDebug}, as with SetModuleLevels. See ParseModuleLevels.
+	ModuleLevels map[string]int
+	// Verbosity is the highest verbosity of the messages logged with V.
+	// It can be changed with SetVerbosity.
+	Verbosity int
+	// VerbositySuffix appends the verbosity to messages logged with V,
+	// such as "(v5)".
+	VerbositySuffix bool
+	// Caller appends the file and line of the log call, as the pair
+	// "caller=server.go:42", after the pairs of With. Frames of this
+	// package, such as its adapters, are skipped.
+	Caller bool
+	// DebugSampleEvery logs only every nth line below the notice level,
+	// starting with the first one, so debug lines can stay on in
+	// production. The crash ring still keeps all of them. Dropped lines
+	// are counted in Stats as Suppressed.
+	DebugSampleEvery int
+	// CollapseRepeats drops lines that are the same as the previous line,
+	// other than the time, and writes "last message repeated N times" when
+	// a different line is logged or CollapseRepeats after the first dropped
+	// line, like syslog. Dropped lines are counted in Stats as Suppressed.
+	// Not used with an Encoder, Formatter, or Template.
+	CollapseRepeats time.Duration
+	// RateLimit limits the rate of the lines of the logger and all of its
+	// child loggers, such as RateLimit{Every: time.Second, Burst: 10}, so a
+	// hot path cannot flood the log. Dropped lines are counted in Stats as
+	// Suppressed. See RateLimited for a limit of its own.
+	RateLimit RateLimit
+	// Sequence numbers the lines that are written, starting at 1, after
+	// the app character, such as "1234:M[#42]", or as the "seq" pair of
+	// the record for Encoders and Formatters, so that dropped lines can be
+	// detected. The numbers are assigned as the lines are written, so they
+	// are in the order of the output.
+	Sequence bool
+	// GoroutineID adds the id of the logging goroutine after the app
+	// character, such as "1234:M[g42]", or as the "goroutine" pair of the
+	// record for Encoders and Formatters. Getting the id is slow, so it's
+	// only intended for debugging.
+	GoroutineID bool
+	// SwallowPanics stops the goroutines of Go from panicking again after
+	// logging a panic.
+	SwallowPanics bool
+	// PanicErrors makes Panic panic with a *PanicError instead of the
+	// message.
+	PanicErrors bool
+	// StackTraceLevel is the level at or above which a stack trace is
+	// appended to the message. Zero disables stack traces.
+	StackTraceLevel int
+	// CrashRing is the number of recent lines, of all levels, that are
+	// kept in memory and dumped by Fatal, Panic, and DumpRing. Zero
+	// disables the ring.
+	CrashRing int
+	// ExitFunc is called by Fatal. Defaults to os.Exit.
+	ExitFunc func(code int)
+	// OnFatal is called by Fatal before the ExitFunc, once the line is
+	// written, such as to flush the state of a server. A panic in it does
+	// not stop the exit.
+	OnFatal func()
+	// LockFile takes an exclusive flock around each write to a file, for
+	// processes sharing a log file on filesystems where O_APPEND writes
+	// are not atomic, such as NFS. Each line is always written with a
+	// single Write call, which is atomic for files opened with O_APPEND
+	// on local filesystems, and for pipes up to PIPE_BUF bytes.
+	LockFile bool
+	// PassthroughFormatted makes Write forward lines that are already in
+	// the redlog format verbatim, keeping their pid, app, and timestamp.
+	// Only the level character is used, for filtering.
+	PassthroughFormatted bool
+	// WriteTimeout abandons writes to the writer passed to New that take
+	// longer than the timeout, counting the lines as dropped. Lines are
+	// dropped until the stuck write completes. Not used for files.
+	WriteTimeout time.Duration
+	// Fallback receives the lines when the writer passed to New fails
+	// FailoverThreshold times in a row, such as os.Stderr. The writer is
+	// retried every second, and a notice is logged when switching to the
+	// fallback and back.
+	Fallback io.Writer
+	// FailoverThreshold is the number of consecutive write errors before
+	// switching to the Fallback. Defaults to 3.
+	FailoverThreshold int
+	// Formatter produces the entire line, without the trailing newline,
+	// replacing the built-in prefix, colors, and PostFilter. It's called
+	// for every destination, with tty set for terminals. Filter still runs
+	// before it. See DefaultFormatter. Not used with an Encoder.
+	Formatter Formatter
+	// Template is the layout of the lines, with the tokens {pid}, {role},
+	// {time}, {level}, and {msg}, such as "{time} {level} {msg}" to drop
+	// the pid and app. The time uses the TimeFormat. Not used with an
+	// Encoder or Formatter.
+	Template string
+	// Hostname is added to the prefix after the app character, such as
+	// "1234:M@node-3", to tell the nodes of a cluster apart. Encoders and
+	// Formatters get it as the "host" pair, with the role, such as
+	// "node-3/leader".
+	Hostname string
+	// IncludeHostname uses the hostname of the system when Hostname is
+	// not set.
+	IncludeHostname bool
+	// Role is added after the hostname, such as "1234:M@node-3/leader".
+	// See SetRole.
+	Role string
+	// WarningFile is a file that warnings and errors are also appended to,
+	// in the text format without colors, regardless of the other outputs.
+	// It's rotated by size using MaxSize and MaxBackups, independently of
+	// the file of OpenFile. A failure to write it is reported once to
+	// stderr.
+	WarningFile string
+	// WarningSync syncs the WarningFile to disk after every line.
+	WarningSync bool
+	// Encoder replaces the text format with another wire format, such as
+	// the one in the msgpack package. Colors are not used.
+	Encoder Encoder
+	// MaxBatchBytes is the most bytes written with a single call. Lines
+	// that are logged while the writer is busy are batched into the next
+	// call, up to this size, but a line is never split. Defaults to 64KB.
+	MaxBatchBytes int
+	// MaxMessageLen is the longest message, in bytes, before the pairs of
+	// With. Longer messages are truncated and end with an ellipsis and
+	// their length, such as "... (1048576 bytes)". Zero is no limit.
+	MaxMessageLen int
+	// MaxMessageSize is the longest line, in bytes, that ReadFrom passes
+	// to Write. Longer lines are truncated. Defaults to 64KB.
+	MaxMessageSize int
+	// LogFile is the path of the log file for WatchConfig, which switches
+	// the output to the file when the path changes. It's opened like the
+	// file of OpenFile, with the options that follow. Not used by New.
+	LogFile string
+
+	// The following options are used by loggers created with OpenFile.
+
+	// MaxSize is the size in bytes at which the log file is rotated.
+	// Zero disables size-based rotation.
+	MaxSize int64
+	// MaxBackups is the number of rotated files to keep. Zero keeps all.
+	MaxBackups int
+	// RotateInterval rotates the log file at fixed intervals aligned to
+	// midnight, such as 24*time.Hour for daily files. Zero disables
+	// time-based rotation.
+	RotateInterval time.Duration
+	// MaxAge is the age at which rotated files are deleted. Zero keeps
+	// them forever.
+	MaxAge time.Duration
+	// CompressBackups gzips rotated files in the background.
+	CompressBackups bool
+	// FileMode is the mode of new log files. Defaults to 0644. Rotated
+	// files keep the mode of the file they replace.
+	FileMode os.FileMode
+	// DirMode is the mode of the directories created for the log file.
+	// Defaults to 0755.
+	DirMode os.FileMode
 }
 
 // DefaultOptions ...
@@ -43,29 +305,141 @@ var DefaultOptions = &Options{
 	Level:      2,
 	Filter:     nil,
 	PostFilter: nil,
-	App:        'M',
-	TimeFormat: "02 Jan 2006 15:04:05.000",
+	App:        AppMaster,
+	TimeFormat: TimeFormatRedis,
 }
 
 // Logger ...
 type Logger struct {
-	appch      uint32
-	tty        bool
-	level      int
-	pid        int
-	timeFormat string
-	filter     func(line string, tty bool) (msg string, app byte, level int)
-	postFilter func(line string, tty bool) string
+	*core
+	module *module       // nil unless created by WithModule
+	label  []byte        // "[label]" from WithLabel
+	tag    []byte        // "[name]" from Named
+	fields []interface{} // key value pairs from With
+	sample *sampler      // nil unless created by Sampled
+	redact *redactor     // nil without Redaction or Redacted
+	app    byte          // from As, or zero for the app of the core
+	views  sync.Map      // app -> *Logger, see As
+}
+
+// clone returns a child logger with the settings of l, sharing its core,
+// for the functions that return loggers.
+func (l *Logger) clone() *Logger {
+	return &Logger{core: l.core, module: l.module, label: l.label, tag: l.tag,
+		fields: l.fields, sample: l.sample, redact: l.redact, app: l.app}
+}
+
+// core is the state shared by a logger and all of its module loggers.
+type core struct {
+	// the 64-bit atomics come first, where they are aligned on 32-bit
+	// platforms
+	level      int64
+	verbosity  int64
+	seq        uint64 // the last sequence number
+	debugN     uint64 // debug and verbose lines, see DebugSampleEvery
+	counts     [levelError - LevelTrace + 1]uint64
+	dropped    uint64
+	writes     uint64
+	written    uint64
+	failovers  uint64
+	recoveries uint64
+	suppressed uint64 // see Stats
+
+	appch        uint32
+	tty          uint32 // atomic, 1 when colors are used for wr
+	discard      uint32 // atomic, 1 when wr is ioutil.Discard
+	pid          int
+	timeFormat   atomic.Value // string, see SetTimeFormat
+	precision    time.Duration
+	clock        func() time.Time
+	loc          *time.Location // nil for local time
+	stackLevel   int
+	swallow      bool
+	panicErrs    bool
+	pretty       bool
+	condense     bool
+	vsuffix      bool
+	goid         bool
+	caller       bool
+	sequence     bool
+	format       int32 // atomic, see SetFormat
+	color        int
+	passthru     bool
+	maxMsg       int
+	maxLen       int // MaxMessageLen
+	multiline    int
+	sanitizeMode int // Sanitize
+	encoder      Encoder
+	lockFile     bool
+	exit         func(code int)
+	onFatal      func()
+	filter       func(line string, tty bool) (msg string, app byte, level int)
+	postFilter   func(line string, tty bool) string
+	hooks        atomic.Value // []func(Record), see AddHook
+	limit        *limiter     // nil without the RateLimit option
+	debugEvery   uint64       // DebugSampleEvery
+	once         sync.Map     // onceKey -> nil, see NoticeOnce
+	rules        atomic.Value // []Rule, see SetRules
+	startup      atomic.Value // string from LogStartup
+	repeats      *repeats     // nil without the CollapseRepeats option
 
-	mu sync.Mutex
-	wr io.Writer
+	mu       sync.Mutex
+	wr       io.Writer
+	tw       *timeoutWriter // wraps wr when WriteTimeout is set
+	timeout  time.Duration  // WriteTimeout
+	fo       *failover      // when Fallback is set
+	wf       *fileWriter    // when WarningFile is set
+	wfbuf    []byte
+	wfFailed uint32
+	progress []byte // status line, see Progress
+	pbuf     []byte
+	fmtr     Formatter
+	buf      []byte
+	ring     *ring
+	last     []byte // previous timestamp, for condensing
+
+	// lines waiting for the writer
+	pend     []byte
+	ends     []int // end of each queued line
+	spare    []byte
+	flushing bool
+	flushed  *sync.Cond
+	maxBatch int
+
+	outputs [LevelWarning - LevelTrace + 1]*levelOutput
+	obuf    []byte
+	sbuf    []byte // for insertSeq
+	nouts   int32  // number of level outputs, atomic
+	closed  uint32
+	owned   []io.Closer // closed by Close
+	file    *fileWriter // of OpenFile or the LogFile option
+
+	modmu   sync.Mutex
+	modules map[string]*module
+
+	hostmu   sync.Mutex
+	hostname string
+	role     string
+	hostTag  atomic.Value // []byte "@host/role", see SetRole
+
+	// level changes, see SetLevelFor
+	lvmu      sync.Mutex
+	history   []LevelChange
+	revert    stopper
+	revertTo  int
+	revertGen int
+	afterFunc func(d time.Duration, f func()) stopper
+
+	watchInterval time.Duration // see WatchConfig
 }
 
 // New sets the level of the logger.
-//   0 - Debug
-//   1 - Verbose
-//   2 - Notice
-//   3 - Warning
+//
+//	-1 - Trace
+//	0 - Debug
+//	1 - Verbose
+//	2 - Notice
+//	3 - Warning
 func New(wr io.Writer, opts *Options) *Logger {
 	if wr == nil {
 		wr = ioutil.Discard
@@ -73,7 +447,7 @@ func New(wr io.Writer, opts *Options) *Logger {
 	if opts == nil {
 		opts = DefaultOptions
 	}
-	if opts.Level < levelDebug || opts.Level > levelWarning {
+	if opts.Level < LevelTrace || opts.Level > LevelWarning {
 		panic("invalid level")
 	}
 	if opts.App == 0 {
@@ -82,25 +456,179 @@ func New(wr io.Writer, opts *Options) *Logger {
 	if opts.TimeFormat == "" {
 		opts.TimeFormat = DefaultOptions.TimeFormat
 	}
-	l := new(Logger)
-	l.timeFormat = opts.TimeFormat
-	l.wr = wr
+	l := &Logger{core: new(core)}
+	if len(opts.Redaction.Keys) > 0 || len(opts.Redaction.Patterns) > 0 {
+		l.redact = newRedactor(opts.Redaction, nil)
+	}
+	l.afterFunc = func(d time.Duration, f func()) stopper {
+		return time.AfterFunc(d, f)
+	}
+	l.watchInterval = time.Second
+	l.precision = opts.TimePrecision
+	l.timeFormat.Store(withPrecision(opts.TimeFormat, l.precision))
+	l.SetRules(opts.Rules)
 	l.filter = opts.Filter
+	if len(opts.Filters) > 0 {
+		l.filter = ChainFilters(append([]Filter{opts.Filter},
+			opts.Filters...)...)
+	}
 	l.postFilter = opts.PostFilter
 	l.SetApp(opts.App)
-	l.level = opts.Level
+	l.level = int64(opts.Level)
+	if opts.ModuleLevels != nil {
+		l.SetModuleLevels(opts.ModuleLevels)
+	}
+	l.verbosity = int64(opts.Verbosity)
+	l.vsuffix = opts.VerbositySuffix
+	l.goid = opts.GoroutineID
+	l.caller = opts.Caller
+	l.sequence = opts.Sequence
+	if opts.DebugSampleEvery > 1 {
+		l.debugEvery = uint64(opts.DebugSampleEvery)
+	}
+	if opts.CollapseRepeats > 0 {
+		l.repeats = &repeats{every: opts.CollapseRepeats}
+	}
+	if opts.RateLimit.Every > 0 {
+		l.limit = newLimiter(opts.RateLimit)
+	}
+	l.format = int32(opts.Format)
 	l.pid = os.Getpid()
-	if f, ok := wr.(*os.File); ok && terminal.IsTerminal(int(f.Fd())) {
-		l.tty = true
+	l.clock = opts.Now
+	if l.clock == nil {
+		l.clock = time.Now
+	}
+	l.loc = opts.Location
+	if l.loc == nil && opts.UTC {
+		l.loc = time.UTC
+	}
+	l.stackLevel = opts.StackTraceLevel
+	l.swallow = opts.SwallowPanics
+	l.panicErrs = opts.PanicErrors
+	l.pretty = opts.Pretty
+	l.condense = opts.CondenseTimestamps
+	l.passthru = opts.PassthroughFormatted
+	l.initHost(opts)
+	if opts.WarningFile != "" {
+		l.openWarningFile(opts)
+	}
+	l.encoder = opts.Encoder
+	if l.encoder == nil {
+		l.fmtr = opts.Formatter
+		if l.fmtr == nil && opts.Template != "" {
+			l.fmtr = templateFormatter(opts.Template, l.TimeFormat)
+		}
+	}
+	l.flushed = sync.NewCond(&l.mu)
+	l.maxBatch = opts.MaxBatchBytes
+	if l.maxBatch <= 0 {
+		l.maxBatch = 64 * 1024
 	}
+	if opts.Fallback != nil {
+		l.fo = &failover{wr: opts.Fallback, threshold: opts.FailoverThreshold}
+		if l.fo.threshold <= 0 {
+			l.fo.threshold = 3
+		}
+	}
+	l.maxMsg = opts.MaxMessageSize
+	if l.maxMsg <= 0 {
+		l.maxMsg = defaultMaxMessageSize
+	}
+	l.maxLen = opts.MaxMessageLen
+	l.multiline = opts.Multiline
+	l.sanitizeMode = opts.Sanitize
+	l.lockFile = opts.LockFile
+	l.onFatal = opts.OnFatal
+	l.exit = opts.ExitFunc
+	if l.exit == nil {
+		l.exit = os.Exit
+	}
+	if opts.CrashRing > 0 {
+		l.ring = newRing(opts.CrashRing)
+	}
+	l.color = opts.Color
+	l.timeout = opts.WriteTimeout
+	l.setWriter(wr)
 	return l
 }
 
+// setWriter sets the primary writer, its colors, and its timeout writer.
+func (c *core) setWriter(wr io.Writer) {
+	c.wr = wr
+	atomic.StoreUint32(&c.tty, boolFlag(isTTY(wr, c.color)))
+	atomic.StoreUint32(&c.discard, boolFlag(wr == ioutil.Discard))
+	c.tw = nil
+	switch wr.(type) {
+	case *os.File, *fileWriter:
+	default:
+		if c.timeout > 0 && wr != ioutil.Discard {
+			c.tw = newTimeoutWriter(wr, c.timeout)
+		}
+	}
+}
+
+func boolFlag(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// isTerminal returns true if colors are used for the primary writer.
+func (c *core) isTerminal() bool {
+	return atomic.LoadUint32(&c.tty) == 1
+}
+
+// output returns the primary writer.
+func (c *core) output() io.Writer {
+	if c.tw != nil {
+		return c.tw
+	}
+	return c.wr
+}
+
+// isTTY returns true if colors are used for wr in the color mode.
+func isTTY(wr io.Writer, color int) bool {
+	switch color {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	f, ok := wr.(*os.File)
+	return ok && terminal.IsTerminal(int(f.Fd()))
+}
+
+// now returns the current time of the logger's clock.
+func (c *core) now() time.Time {
+	t := c.clock()
+	if c.loc != nil {
+		t = t.In(c.loc)
+	}
+	return t
+}
+
 func logPostFilter(line string) string {
 	a := strings.IndexByte(line, ':')
 	b := strings.IndexByte(line, ' ')
-	c := b + 25
-	if a == -1 || b == -1 || b != a+2 || c >= len(line) || line[c] != ' ' {
+	// the timestamp ends after the fraction of the seconds, if any, or
+	// after the '"' of a condensed timestamp
+	c := b + len(trimFraction(TimeFormatRedis)) + 1
+	if b != -1 && b+3 < len(line) && line[b+3] == '-' {
+		c = b + len(trimFraction(TimeFormatRedisNumeric)) + 1
+	}
+	if c < len(line) && line[c] == '.' {
+		for c++; c < len(line) && line[c] >= '0' && line[c] <= '9'; c++ {
+		}
+	} else if c < len(line) && line[c] == ' ' {
+		k := c
+		for ; k < len(line) && line[k] == ' '; k++ {
+		}
+		if k < len(line) && line[k] == '"' {
+			c = k + 1
+		}
+	}
+	if a == -1 || b == -1 || b < a+2 || c >= len(line) || line[c] != ' ' {
 		return line
 	}
 	clr := ""
@@ -117,243 +645,865 @@ func logPostFilter(line string) string {
 		clr = "\x1b[33m"
 	case 'M':
 		clr = "\x1b[35m"
+	case 'X':
+		clr = "\x1b[34m"
 	}
 	line = clr + line[:b] + "\x1b[0m\x1b[2m" + line[b:c] + "\x1b[0m" + line[c:]
 	return line
 }
 
-// SetApp sets the app character
+// SetFormat sets the output format, FormatRedis, FormatPlain, FormatJSON,
+// or FormatLogfmt.
+func (l *Logger) SetFormat(format int) {
+	if !validFormat(format) {
+		panic("invalid format")
+	}
+	atomic.StoreInt32(&l.format, int32(format))
+}
+
+// Format returns the output format.
+func (l *Logger) Format() int {
+	return int(atomic.LoadInt32(&l.format))
+}
+
+func validFormat(format int) bool {
+	return format >= FormatRedis && format <= FormatLogfmt
+}
+
+// enc returns the Encoder option, or else the encoder of the format when
+// there is no Formatter option, or nil.
+func (l *Logger) enc() Encoder {
+	if l.encoder != nil || l.fmtr != nil {
+		return l.encoder
+	}
+	switch l.Format() {
+	case FormatJSON:
+		return JSONCodec{}
+	case FormatLogfmt:
+		return LogfmtCodec{}
+	}
+	return nil
+}
+
+// SetTimeFormat sets the layout of the timestamps, such as time.RFC3339.
+// An empty layout is the default TimeFormat. The TimePrecision option
+// applies to it.
+func (l *Logger) SetTimeFormat(layout string) {
+	if layout == "" {
+		layout = DefaultOptions.TimeFormat
+	}
+	l.timeFormat.Store(withPrecision(layout, l.precision))
+}
+
+// withPrecision replaces the fraction of the seconds of a layout with the
+// digits of the precision. Layouts without seconds are returned as is.
+func withPrecision(layout string, precision time.Duration) string {
+	var digits int
+	switch precision {
+	case time.Second:
+	case time.Millisecond:
+		digits = 3
+	case time.Microsecond:
+		digits = 6
+	case time.Nanosecond:
+		digits = 9
+	default:
+		return layout
+	}
+	i := strings.Index(layout, "05")
+	if i == -1 {
+		return layout
+	}
+	i += 2
+	j := i
+	if j+1 < len(layout) && layout[j] == '.' &&
+		(layout[j+1] == '0' || layout[j+1] == '9') {
+		for j++; j < len(layout) && layout[j] == layout[i+1]; j++ {
+		}
+	}
+	frac := ""
+	if digits > 0 {
+		frac = "." + strings.Repeat("0", digits)
+	}
+	return layout[:i] + frac + layout[j:]
+}
+
+// trimFraction removes the fraction of the seconds of a layout.
+func trimFraction(layout string) string {
+	return withPrecision(layout, time.Second)
+}
+
+// TimeFormat returns the layout of the timestamps.
+func (l *Logger) TimeFormat() string {
+	return l.timeFormat.Load().(string)
+}
+
+// SetApp sets the app character, such as AppReplica when a master is
+// demoted to a replica. It's safe to call while logging, and applies to
+// the module loggers, but not to the views of As.
 func (l *Logger) SetApp(app byte) {
 	atomic.StoreUint32(&l.appch, uint32(app))
 }
 
 // App returns the app character
 func (l *Logger) App() byte {
+	if l.app != 0 {
+		return l.app
+	}
 	return byte(atomic.LoadUint32(&l.appch))
 }
 
+// Tracef ...
+func (l *Logger) Tracef(format string, args ...interface{}) {
+	if l.enabled(LevelTrace) {
+		l.writef(LevelTrace, format, args)
+	}
+}
+
+// Trace ...
+func (l *Logger) Trace(args ...interface{}) {
+	if l.enabled(LevelTrace) {
+		l.write(LevelTrace, args)
+	}
+}
+
+// Traceln ...
+func (l *Logger) Traceln(args ...interface{}) {
+	if l.enabled(LevelTrace) {
+		l.write(LevelTrace, args)
+	}
+}
+
 // Debugf ...
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	if levelDebug >= l.level {
-		l.writef(levelDebug, format, args)
+	if l.enabled(LevelDebug) {
+		l.writef(LevelDebug, format, args)
 	}
 }
 
 // Debug ...
 func (l *Logger) Debug(args ...interface{}) {
-	if levelDebug >= l.level {
-		l.write(levelDebug, args)
+	if l.enabled(LevelDebug) {
+		l.write(LevelDebug, args)
 	}
 }
 
 // Debugln ...
 func (l *Logger) Debugln(args ...interface{}) {
-	if levelDebug >= l.level {
-		l.write(levelDebug, args)
+	if l.enabled(LevelDebug) {
+		l.write(LevelDebug, args)
 	}
 }
 
 // Verbf ...
 func (l *Logger) Verbf(format string, args ...interface{}) {
-	if levelVerbose >= l.level {
-		l.writef(levelVerbose, format, args)
+	if l.enabled(LevelVerbose) {
+		l.writef(LevelVerbose, format, args)
 	}
 }
 
 // Verb ...
 func (l *Logger) Verb(args ...interface{}) {
-	if levelVerbose >= l.level {
-		l.write(levelVerbose, args)
+	if l.enabled(LevelVerbose) {
+		l.write(LevelVerbose, args)
 	}
 }
 
 // Verbln ...
 func (l *Logger) Verbln(args ...interface{}) {
-	if levelVerbose >= l.level {
-		l.write(levelVerbose, args)
+	if l.enabled(LevelVerbose) {
+		l.write(LevelVerbose, args)
 	}
 }
 
 // Noticef ...
 func (l *Logger) Noticef(format string, args ...interface{}) {
-	l.writef(levelNotice, format, args)
+	l.writef(LevelNotice, format, args)
 }
 
 // Notice ...
 func (l *Logger) Notice(args ...interface{}) {
-	l.write(levelNotice, args)
+	l.write(LevelNotice, args)
 }
 
 // Noticeln ...
 func (l *Logger) Noticeln(args ...interface{}) {
-	l.write(levelNotice, args)
+	l.write(LevelNotice, args)
 }
 
 // Printf ...
 func (l *Logger) Printf(format string, args ...interface{}) {
-	l.writef(levelNotice, format, args)
+	l.writef(LevelNotice, format, args)
 }
 
 // Print ...
 func (l *Logger) Print(args ...interface{}) {
-	l.write(levelNotice, args)
+	l.write(LevelNotice, args)
 }
 
 // Println ...
 func (l *Logger) Println(args ...interface{}) {
-	l.write(levelNotice, args)
+	l.write(LevelNotice, args)
 }
 
 // Warningf ...
 func (l *Logger) Warningf(format string, args ...interface{}) {
-	l.writef(levelWarning, format, args)
+	l.writef(LevelWarning, format, args)
 }
 
 // Warning ...
 func (l *Logger) Warning(args ...interface{}) {
-	l.write(levelWarning, args)
+	l.write(LevelWarning, args)
 }
 
 // Warningln ...
 func (l *Logger) Warningln(args ...interface{}) {
-	l.write(levelWarning, args)
+	l.write(LevelWarning, args)
 }
 
 // Fatalf ...
 func (l *Logger) Fatalf(format string, args ...interface{}) {
 	l.writef(levelError, format, args)
-	os.Exit(1)
+	l.fatal()
 }
 
 // Fatal ...
 func (l *Logger) Fatal(args ...interface{}) {
 	l.write(levelError, args)
-	os.Exit(1)
+	l.fatal()
 }
 
 // Fatalln ...
 func (l *Logger) Fatalln(args ...interface{}) {
 	l.write(levelError, args)
-	os.Exit(1)
+	l.fatal()
 }
 
 // Panicf ...
 func (l *Logger) Panicf(format string, args ...interface{}) {
 	l.writef(levelError, format, args)
-	panic("")
+	l.panic(fmt.Sprintf(format, args...))
 }
 
 // Panic ...
 func (l *Logger) Panic(args ...interface{}) {
 	l.write(levelError, args)
-	panic("")
+	l.panic(fmt.Sprint(args...))
 }
 
 // Panicln ...
 func (l *Logger) Panicln(args ...interface{}) {
 	l.write(levelError, args)
-	panic("")
+	l.panic(fmt.Sprint(args...))
 }
 
-// Errorf ...
+// Errorf logs at the error level, a '#' that is red on terminals, without
+// exiting.
 func (l *Logger) Errorf(format string, args ...interface{}) {
 	l.writef(levelError, format, args)
 }
 
-// Error ...
+// Error logs at the error level, a '#' that is red on terminals, without
+// exiting.
 func (l *Logger) Error(args ...interface{}) {
 	l.write(levelError, args)
 }
 
-// Errorln ...
+// Errorln logs at the error level, a '#' that is red on terminals, without
+// exiting.
 func (l *Logger) Errorln(args ...interface{}) {
 	l.write(levelError, args)
 }
 
+// WarningErr logs err at the warning level.
+func (l *Logger) WarningErr(err error) {
+	l.write(LevelWarning, []interface{}{err})
+}
+
+// ErrorErr logs err at the error level.
+func (l *Logger) ErrorErr(err error) {
+	l.write(levelError, []interface{}{err})
+}
+
 // Write writes to the log
 func (l *Logger) Write(p []byte) (int, error) {
-	level := l.level
+	level := l.minLevel()
 	app := l.App()
 	line := string(p)
+	if l.passthru {
+		line := strings.TrimRight(line, "\r\n")
+		if pl, ok := parseLine(line, l.TimeFormat()); ok {
+			if !l.enabled(pl.level) || !l.sampled(pl.level, pl.msg) {
+				return len(p), nil
+			}
+			output := l.hasOutput() && pl.level >= l.minLevel()
+			if enc := l.enc(); enc != nil {
+				l.emitEncoded(enc, pl.level, []byte(line), output,
+					pl.record(), 0, 0)
+			} else if l.fmtr != nil {
+				l.emitFormatted(pl.level, output, pl.record(), 0)
+			} else {
+				l.emit(pl.level, []byte(line), output, 0, 0, 0)
+			}
+			return len(p), nil
+		}
+	}
 	if l.filter != nil {
-		line, app, level = l.filter(line, l.tty)
+		line, app, level = l.filter(line, l.isTerminal())
+		if level == LevelDrop {
+			return len(p), nil
+		}
+		if level == LevelKeep {
+			level = LevelNotice
+		}
 		if app == 0 {
 			app = l.App()
 		}
-		if level < levelDebug {
-			level = levelDebug
-		} else if level > levelWarning {
-			level = levelWarning
+		if level < LevelTrace {
+			level = LevelTrace
+		} else if level > LevelWarning {
+			level = LevelWarning
 		}
 	}
-	if level >= l.level {
-		write(false, l, app, level, "", []interface{}{line})
+	if l.enabled(level) && l.sampled(level, line) {
+		write(false, l, app, level, "", []interface{}{line}, nil)
 	}
 	return len(p), nil
 }
 
+// defaultMaxMessageSize is the default for Options.MaxMessageSize.
+const defaultMaxMessageSize = 64 * 1024
+
+// ReadFrom reads lines from r until EOF and logs each one using Write, for
+// replaying another log file. Lines longer than Options.MaxMessageSize are
+// truncated. It returns the number of bytes read and the first read error,
+// not including io.EOF.
+func (l *Logger) ReadFrom(r io.Reader) (int64, error) {
+	rd := bufio.NewReaderSize(r, l.maxMsg)
+	var n int64
+	var long bool // discarding the rest of a truncated line
+	for {
+		line, err := rd.ReadSlice('\n')
+		n += int64(len(line))
+		if err == bufio.ErrBufferFull {
+			if !long {
+				l.Write(line)
+				long = true
+			}
+			continue
+		}
+		if len(line) > 0 && !long {
+			l.Write(line)
+		}
+		long = false
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return n, err
+		}
+	}
+}
+
+// fatal dumps the crash ring and exits.
+func (l *Logger) fatal() {
+	l.mu.Lock()
+	l.waitFlush()
+	l.mu.Unlock()
+	l.DumpRing(l.output())
+	if l.onFatal != nil {
+		defer l.exit(1)
+		l.onFatal()
+		return
+	}
+	l.exit(1)
+}
+
 func (l *Logger) writef(level int, format string, args []interface{}) {
-	if level >= l.level {
-		write(true, l, l.App(), level, format, args)
+	if l.enabled(level) && l.sampled(level, format) {
+		write(true, l, l.App(), level, format, args, nil)
 	}
 }
 
 //go:noinline
 func (l *Logger) write(level int, args []interface{}) {
-	if level >= l.level {
-		write(false, l, l.App(), level, "", args)
+	if l.enabled(level) && l.sampled(level, messageKey(args)) {
+		write(false, l, l.App(), level, "", args, nil)
 	}
 }
 
+// write formats and logs a line. The key value pairs are only used by the
+// Encoder and Formatter options, as they are already in the message.
+//
 //go:noinline
 func write(useFormat bool, l *Logger, app byte, level int, format string,
-	args []interface{}) {
-	if l.wr == ioutil.Discard {
+	args []interface{}, kvs []interface{}) {
+	stackArgs := args
+	if rules := l.Rules(); rules != nil {
+		var msg string
+		if useFormat {
+			msg = fmt.Sprintf(format, args...)
+		} else {
+			msg = fmt.Sprint(args...)
+		}
+		var ok bool
+		if msg, level, ok = applyRules(rules, msg, level); !ok {
+			atomic.AddUint64(&l.suppressed, 1)
+			return
+		}
+		useFormat, args = false, []interface{}{msg}
+	}
+	output := l.hasOutput() && level >= l.minLevel()
+	if output && level < LevelNotice && l.debugEvery > 0 &&
+		(atomic.AddUint64(&l.debugN, 1)-1)%l.debugEvery != 0 {
+		atomic.AddUint64(&l.suppressed, 1)
+		output = false
+	}
+	if !output && l.ring == nil {
 		return
 	}
-	var prefix []byte
-	now := time.Now()
-	prefix = strconv.AppendInt(prefix, int64(l.pid), 10)
-	prefix = append(prefix, ':', app, ' ')
-	prefix = now.AppendFormat(prefix, l.timeFormat)
-	prefix = append(prefix, ' ')
-	if l.tty && levelColors[level] != "" {
-		prefix = append(prefix, "\x1b["+levelColors[level]+"m"...)
-		prefix = append(prefix, levelChars[level])
-		prefix = append(prefix, "\x1b[0m"...)
+	b := bufferPool.Get().(*buffer)
+	line := (*b)[:0]
+	now := l.now()
+	// the sequence number is assigned by emit, under the mutex
+	seq := l.sequence && output
+	tags := l.label
+	if host := l.loadHostTag(); host != nil || l.goid || seq {
+		var tb [64]byte
+		tags = append(append(tb[:0], host...), tags...)
+		if l.goid {
+			tags = appendGoroutineID(tags)
+		}
+		if seq {
+			tags = append(tags, "[#]"...)
+		}
+	}
+	var ts, te, sp int // sp is the position of the sequence number
+	if l.fmtr != nil {
+		// the formatter adds the prefix
+	} else if l.Format() == FormatPlain {
+		if p := plainPrefixes[level-LevelTrace]; p != "" {
+			line = append(append(line, p...), ": "...)
+		}
 	} else {
-		prefix = append(prefix, levelChars[level])
+		line, ts, te = appendPrefix(line, l.pid, app, tags, now,
+			l.TimeFormat(), level)
+		if seq {
+			sp = ts - 2
+		}
+	}
+	ms := len(line) // start of the message
+	if l.tag != nil {
+		line = append(append(line, l.tag...), ' ')
 	}
-	var msg string
+	*b = line
 	if useFormat {
-		msg = fmt.Sprintf(format, args...)
+		fmt.Fprintf(b, format, args...)
 	} else {
-		msg = fmt.Sprint(args...)
+		fmt.Fprint(b, args...)
 	}
-	for len(msg) > 0 {
-		switch msg[len(msg)-1] {
+	line = *b
+	for len(line) > 0 {
+		switch line[len(line)-1] {
 		case '\t', ' ', '\r', '\n':
-			msg = msg[:len(msg)-1]
+			line = line[:len(line)-1]
 			continue
 		}
 		break
 	}
-	line := strings.TrimSpace(fmt.Sprintf("%s %s", prefix, msg))
-	if l.postFilter != nil {
-		line = strings.TrimSpace(l.postFilter(line, l.tty))
+	if l.redact != nil {
+		line = l.redact.message(line, ms)
+	}
+	line = l.sanitize(line, ms)
+	if l.maxLen > 0 && len(line)-ms > l.maxLen {
+		line = truncateMessage(line, ms, l.maxLen)
+	}
+	me := len(line) // end of the message
+	enc := l.enc()
+	fields := l.fields
+	if l.caller {
+		if loc := callerLocation(); loc != "" {
+			fields = append(fields[:len(fields):len(fields)], "caller", loc)
+		}
+	}
+	if l.redact != nil {
+		fields = l.redact.pairs(fields)
+		kvs = l.redact.pairs(kvs)
+	}
+	if enc != nil || l.fmtr != nil {
+		// the host and goroutine id are in the prefix of the text format
+		if host := l.loadHostTag(); host != nil {
+			fields = append(fields[:len(fields):len(fields)], "host",
+				string(host[1:]))
+		}
+		if l.goid {
+			var nb [20]byte
+			fields = append(fields[:len(fields):len(fields)], "goroutine",
+				string(appendGoroutineNumber(nb[:0])))
+		}
+		if seq {
+			fields = append(fields[:len(fields):len(fields)], "seq",
+				uint64(0))
+		}
+	}
+	var sk int // the index of the sequence number in kvs
+	if len(fields) > 0 {
+		if enc != nil || l.fmtr != nil {
+			kvs = append(kvs[:len(kvs):len(kvs)], fields...)
+			if seq {
+				sk = len(kvs) - 1
+			}
+		} else if line = l.sanitize(appendKVs(line, fields), me); me == ms {
+			line = append(line[:ms], line[ms+1:]...)
+		}
+	}
+	if l.stackLevel > 0 && level >= l.stackLevel {
+		line = append(line, formatStack(stackTrace(stackArgs))...)
+	}
+	if output && l.repeats != nil && enc == nil && l.fmtr == nil &&
+		l.repeated(app, level, line[ms:]) {
+		output = false
+	}
+	hooks := l.loadHooks()
+	if enc != nil || l.fmtr != nil || output && hooks != nil {
+		var msg string
+		if me > ms {
+			msg = string(line[ms:me])
+		}
+		rec := Record{Pid: l.pid, App: app, Time: now, Level: level, Msg: msg,
+			KVs: kvs}
+		if enc != nil {
+			l.emitEncoded(enc, level, line, output, rec, sp, sk)
+		} else if l.fmtr != nil {
+			l.emitFormatted(level, output, rec, sk)
+		} else {
+			l.emit(level, l.splitLines(line, ms), output, ts, te, sp)
+			if len(fields) > 0 {
+				rec.KVs = append(kvs[:len(kvs):len(kvs)], fields...)
+			}
+		}
+		if output {
+			for _, hook := range hooks {
+				hook(rec)
+			}
+		}
+	} else {
+		l.emit(level, l.splitLines(line, ms), output, ts, te, sp)
+	}
+	*b = line
+	putBuffer(b)
+}
+
+// writeKVs logs msg followed by the key value pairs as " key=value". The
+// Encoder and Formatter options get the pairs separately instead.
+func writeKVs(l *Logger, level int, msg string, kvs []interface{}) {
+	if len(kvs) > 0 && l.enc() == nil && l.fmtr == nil {
+		b := appendKVs([]byte(msg), kvs)
+		if msg == "" {
+			b = b[1:]
+		}
+		msg = string(b)
+	}
+	write(false, l, l.App(), level, "", []interface{}{msg}, kvs)
+}
+
+// splitLines returns the line with the prefix at line[:ms], or spaces of
+// its width, after each newline, as set by the Multiline option.
+func (l *Logger) splitLines(line []byte, ms int) []byte {
+	if l.multiline == MultilineKeep || bytes.IndexByte(line[ms:], '\n') < 0 {
+		return line
+	}
+	prefix := line[:ms]
+	if l.multiline == MultilineIndent {
+		prefix = bytes.Repeat([]byte{' '}, ms)
+	}
+	dst := make([]byte, 0, len(line)+len(prefix)*4)
+	dst = append(dst, line[:ms]...)
+	for i, s := range bytes.Split(line[ms:], []byte{'\n'}) {
+		if i > 0 {
+			dst = append(append(dst, '\n'), prefix...)
+		}
+		dst = append(dst, s...)
+	}
+	return dst
+}
+
+// truncateMessage cuts the message at line[ms:] to n bytes, without
+// splitting a character, and appends its length.
+func truncateMessage(line []byte, ms, n int) []byte {
+	size := len(line) - ms
+	end := ms + n
+	for end > ms && !utf8.RuneStart(line[end]) {
+		end--
+	}
+	line = append(line[:end], "... ("...)
+	line = strconv.AppendInt(line, int64(size), 10)
+	return append(line, " bytes)"...)
+}
+
+// buffer is a reusable byte buffer for formatting lines.
+type buffer []byte
+
+func (b *buffer) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
+
+var bufferPool = sync.Pool{New: func() interface{} { return new(buffer) }}
+
+func putBuffer(b *buffer) {
+	// large buffers are not kept around
+	if cap(*b) <= 64*1024 {
+		bufferPool.Put(b)
+	}
+}
+
+// emit writes a formatted line to the outputs and the crash ring. The
+// timestamp is at line[ts:te], or te is zero for lines that were not
+// formatted by the logger.
+func (l *Logger) emit(level int, line []byte, output bool, ts, te, sp int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return
+	}
+	if sp > 0 {
+		var n int
+		line, n = l.insertSeq(line, sp, atomic.AddUint64(&l.seq, 1))
+		ts, te = ts+n, te+n
+	}
+	out := l.outputs[levelIndex(level)]
+	var dup bool
+	if l.condense && te > 0 && (l.isTerminal() || out != nil && out.tty) {
+		dup = string(line[ts:te]) == string(l.last)
+		if !dup {
+			l.last = append(l.last[:0], line[ts:te]...)
+		}
+	}
+	primary := (out == nil || out.also) && l.wr != ioutil.Discard
+	if primary || l.ring != nil {
+		l.buf = l.render(l.buf[:0], line, level, ts, te, l.isTerminal(), dup)
+		if l.ring != nil {
+			l.ring.add(l.buf)
+		}
+	}
+	if output && l.wf != nil && level >= LevelWarning {
+		l.wfbuf = l.render(l.wfbuf[:0], line, level, ts, te, false, false)
+		l.writeWarning(append(l.wfbuf, '\n'))
+	}
+	if !output || !primary && out == nil {
+		return
+	}
+	atomic.AddUint64(&l.counts[level-LevelTrace], 1)
+	// the complete line is written with a single call
+	if out != nil {
+		l.obuf = l.render(l.obuf[:0], line, level, ts, te, out.tty, dup)
+		l.obuf = append(l.obuf, '\n')
+		if _, err := l.writeLine(out.wr, l.obuf); err != nil {
+			atomic.AddUint64(&l.dropped, 1)
+		}
+	}
+	if primary {
+		l.queue(l.withProgress(append(l.buf, '\n')))
+	}
+}
+
+// emitEncoded writes a record to the outputs using enc. The line in the
+// text format is used for the crash ring.
+func (l *Logger) emitEncoded(enc Encoder, level int, line []byte,
+	output bool, rec Record, sp, sk int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return
+	}
+	if sk > 0 {
+		seq := atomic.AddUint64(&l.seq, 1)
+		rec.KVs[sk] = seq
+		if sp > 0 {
+			line, _ = l.insertSeq(line, sp, seq)
+		}
 	}
+	if l.ring != nil {
+		l.ring.add(line)
+	}
+	if output && l.wf != nil && level >= LevelWarning {
+		l.wfbuf = append(append(l.wfbuf[:0], line...), '\n')
+		l.writeWarning(l.wfbuf)
+	}
+	out := l.outputs[levelIndex(level)]
+	primary := (out == nil || out.also) && l.wr != ioutil.Discard
+	if !output || !primary && out == nil {
+		return
+	}
+	atomic.AddUint64(&l.counts[level-LevelTrace], 1)
+	b := (*buffer)(&l.buf)
+	*b = (*b)[:0]
+	if err := enc.Encode(b, rec); err != nil {
+		atomic.AddUint64(&l.dropped, 1)
+		return
+	}
+	if len(l.buf) == 0 {
+		// the encoder delivered the record itself
+		return
+	}
+	if out != nil {
+		if _, err := l.writeLine(out.wr, l.buf); err != nil {
+			atomic.AddUint64(&l.dropped, 1)
+		}
+	}
+	if primary {
+		l.queue(l.buf)
+	}
+}
+
+// insertSeq returns the line with the sequence number inserted at sp, the
+// position of the "]" of the "[#]" tag, and at the same position of the
+// prefixes that MultilineSplit repeats after the newlines, and the width of
+// the number. It's called with the mutex held, so the numbers are in the
+// order of the output.
+func (l *Logger) insertSeq(line []byte, sp int, seq uint64) ([]byte, int) {
+	var nb [20]byte
+	num := strconv.AppendUint(nb[:0], seq, 10)
+	dst := append(l.sbuf[:0], line[:sp]...)
+	dst = append(dst, num...)
+	line = line[sp:]
+	for l.multiline == MultilineSplit {
+		i := bytes.IndexByte(line, '\n')
+		if i < 0 {
+			break
+		}
+		dst = append(dst, line[:i+1+sp]...)
+		dst = append(dst, num...)
+		line = line[i+1+sp:]
+	}
+	l.sbuf = append(dst, line...)
+	return l.sbuf, len(num)
+}
+
+// emitFormatted writes a record to the outputs using the Formatter option.
+func (l *Logger) emitFormatted(level int, output bool, rec Record, sk int) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.tty {
-		line = logPostFilter(line)
+	if l.isClosed() {
+		return
+	}
+	if sk > 0 {
+		rec.KVs[sk] = atomic.AddUint64(&l.seq, 1)
+	}
+	rec.TimeFormat = l.TimeFormat()
+	if l.ring != nil {
+		l.buf = l.fmtr(l.buf[:0], rec, false)
+		l.ring.add(l.buf)
+	}
+	if output && l.wf != nil && level >= LevelWarning {
+		l.wfbuf = append(l.fmtr(l.wfbuf[:0], rec, false), '\n')
+		l.writeWarning(l.wfbuf)
+	}
+	out := l.outputs[levelIndex(level)]
+	primary := (out == nil || out.also) && l.wr != ioutil.Discard
+	if !output || !primary && out == nil {
+		return
+	}
+	atomic.AddUint64(&l.counts[level-LevelTrace], 1)
+	if out != nil {
+		l.obuf = append(l.fmtr(l.obuf[:0], rec, out.tty), '\n')
+		if _, err := l.writeLine(out.wr, l.obuf); err != nil {
+			atomic.AddUint64(&l.dropped, 1)
+		}
+	}
+	if primary {
+		l.buf = append(l.fmtr(l.buf[:0], rec, l.isTerminal()), '\n')
+		l.queue(l.withProgress(l.buf))
 	}
-	fmt.Fprintf(l.wr, "%s\n", line)
+}
+
+// render appends the line to dst as it's written to a destination, adding
+// colors for terminals. A duplicate timestamp is replaced with blanks and
+// a ditto mark, keeping the width, when dup is set.
+func (l *Logger) render(dst, line []byte, level, ts, te int, tty,
+	dup bool) []byte {
+	start := len(dst)
+	pretty := l.pretty && tty && te > 0
+	plain := l.Format() == FormatPlain && te == 0
+	if plain && tty && plainPrefixes[level-LevelTrace] != "" {
+		idx := level - LevelTrace
+		dst = append(dst, "\x1b["...)
+		dst = append(dst, levelColors[idx]...)
+		dst = append(dst, 'm')
+		dst = append(dst, plainPrefixes[idx]...)
+		dst = append(dst, "\x1b[0m"...)
+		dst = append(dst, line[len(plainPrefixes[idx]):]...)
+	} else if !tty || te == 0 {
+		dst = append(dst, line...)
+	} else {
+		if pretty {
+			dst = append(dst, "\x1b[2m"...)
+		}
+		n := len(dst)
+		dst = append(dst, line[:te]...)
+		if dup {
+			for i := n + ts; i < n+te-1; i++ {
+				dst[i] = ' '
+			}
+			dst[n+te-1] = '"'
+		}
+		if pretty {
+			dst = append(dst, "\x1b[0m"...)
+		}
+		dst = append(dst, ' ')
+		idx := level - LevelTrace
+		color := levelColors[idx] != ""
+		if color {
+			dst = append(dst, "\x1b["...)
+			dst = append(dst, levelColors[idx]...)
+			dst = append(dst, 'm')
+		}
+		if pretty {
+			dst = append(dst, levelWords[idx]...)
+		} else {
+			dst = append(dst, levelChars[idx])
+		}
+		if color {
+			dst = append(dst, "\x1b[0m"...)
+		}
+		msg := line[te+2:]
+		if l.tag != nil && len(msg) > 0 && bytes.HasPrefix(msg[1:], l.tag) {
+			dst = append(dst, " \x1b[36m"...)
+			dst = append(dst, l.tag...)
+			dst = append(dst, "\x1b[0m"...)
+			msg = msg[1+len(l.tag):]
+		}
+		dst = append(dst, msg...)
+	}
+	if l.postFilter != nil {
+		s := strings.TrimSpace(l.postFilter(string(dst[start:]), tty))
+		dst = append(dst[:start], s...)
+	}
+	if tty && !pretty && !plain {
+		s := logPostFilter(string(dst[start:]))
+		dst = append(dst[:start], s...)
+	}
+	return dst
+}
+
+// writeLine writes a complete line to wr, holding a file lock when the
+// LockFile option is set.
+func (l *Logger) writeLine(wr io.Writer, p []byte) (int, error) {
+	if l.lockFile {
+		if f, ok := wr.(*os.File); ok {
+			if err := lockFile(f); err != nil {
+				return 0, err
+			}
+			defer unlockFile(f)
+		}
+	}
+	return wr.Write(p)
 }
 
 // HashicorpRaftFilter is used as a filter to convert a log message
 // from the hashicorp/raft package into redlog structured message.
-var HashicorpRaftFilter func(line string, tty bool) (msg string, app byte,
-	level int)
+var HashicorpRaftFilter Filter
 
 func init() {
 	HashicorpRaftFilter = func(line string, tty bool) (msg string, app byte,
@@ -364,20 +1514,22 @@ func init() {
 			msg = msg[idx+1:]
 		}
 		idx = strings.IndexByte(msg, ']')
-		if idx != -1 && msg[0] == '[' {
+		if idx > 0 && msg[0] == '[' {
 			switch msg[1] {
 			default: // -> verbose
-				level = levelVerbose
+				level = LevelVerbose
 			case 'W': // warning -> warning
-				level = levelWarning
+				level = LevelWarning
 			case 'E': // error -> warning
-				level = levelWarning
+				level = LevelWarning
+			case 'T': // trace -> trace
+				level = LevelTrace
 			case 'D': // debug -> debug
-				level = levelDebug
+				level = LevelDebug
 			case 'V': // verbose -> verbose
-				level = levelVerbose
+				level = LevelVerbose
 			case 'I': // info -> notice
-				level = levelNotice
+				level = LevelNotice
 			}
 			msg = msg[idx+1:]
 			for len(msg) > 0 && msg[0] == ' ' {
@@ -395,13 +1547,20 @@ func init() {
 		idx = strings.Index(msg, "raft: entering ")
 		if idx != -1 {
 			if strings.Index(msg[idx:], " state:") != -1 {
-				level = levelWarning
+				level = LevelWarning
 			}
 		}
 		return msg, app, level
 	}
 }
 
+// HashicorpRaftFilterDropping returns a HashicorpRaftFilter that drops the
+// messages containing any of the patterns, such as the chatty
+// "pipelining replication" messages.
+func HashicorpRaftFilterDropping(patterns ...string) Filter {
+	return ChainFilters(HashicorpRaftFilter, DropFilter(patterns...))
+}
+
 // RedisLogColorizer filters the Redis log output and colorizes it.
 func RedisLogColorizer(wr io.Writer) io.Writer {
 	if f, ok := wr.(*os.File); !ok || !terminal.IsTerminal(int(f.Fd())) {
@@ -415,45 +1574,76 @@ func RedisLogColorizer(wr io.Writer) io.Writer {
 			if err != nil {
 				return
 			}
-			parts := strings.Split(line, " ")
-			if len(parts) > 6 {
-				var color string
-				switch parts[5] {
-				case ".":
-					color = "\x1b[35m"
-				case "-":
-					color = ""
-				case "*":
-					color = "\x1b[1m"
-				case "#":
-					color = "\x1b[33m"
-				}
-				if color != "" {
-					parts[5] = color + parts[5] + "\x1b[0m"
-					line = strings.Join(parts, " ")
-				}
-			}
-			os.Stdout.Write([]byte(logPostFilter(line)))
+			os.Stdout.Write([]byte(colorizeLine(line)))
 			continue
 		}
 	}()
 	return pw
 }
 
+// colorizeLine colorizes a single Redis log line. Level characters that
+// are not known, such as the trace ',', are passed through untouched.
+func colorizeLine(line string) string {
+	parts := strings.Split(line, " ")
+	i := 5
+	if len(parts) > 1 && strings.IndexByte(parts[1], '-') != -1 {
+		i = 3 // TimeFormatRedisNumeric
+	}
+	if len(parts) > i+1 {
+		var color string
+		switch parts[i] {
+		case ".":
+			color = "\x1b[35m"
+		case "-":
+			color = ""
+		case "*":
+			color = "\x1b[1m"
+		case "#":
+			color = "\x1b[33m"
+		}
+		if color != "" {
+			parts[i] = color + parts[i] + "\x1b[0m"
+			line = strings.Join(parts, " ")
+		}
+	}
+	return logPostFilter(line)
+}
+
 // GoLogger returns a standard Go log.Logger which when used, will print
-// in the Redlog format.
+// in the Redlog format. Its lines are logged synchronously, so they are in
+// order with the lines logged directly.
 func (l *Logger) GoLogger() *log.Logger {
-	rd, wr := io.Pipe()
-	gl := log.New(wr, "", 0)
-	go func() {
-		brd := bufio.NewReader(rd)
-		for {
-			line, err := brd.ReadBytes('\n')
-			if err != nil {
-				continue
-			}
-			l.Printf("%s", line[:len(line)-1])
+	return l.GoLoggerLevel(LevelNotice)
+}
+
+// GoLoggerLevel is like GoLogger, but its lines are logged at level, such
+// as LevelWarning for the ErrorLog of an http.Server.
+func (l *Logger) GoLoggerLevel(level int) *log.Logger {
+	if level < LevelTrace || level > LevelWarning {
+		panic("invalid level")
+	}
+	if l.isClosed() {
+		return log.New(ioutil.Discard, "", 0)
+	}
+	return log.New(goWriter{l, level}, "", 0)
+}
+
+// goWriter logs each line written by a log.Logger at its level. Lines are
+// logged by the calling goroutine, in order with the other calls.
+type goWriter struct {
+	l     *Logger
+	level int
+}
+
+func (w goWriter) Write(p []byte) (int, error) {
+	for s := p; len(s) > 0; {
+		line := s
+		if i := bytes.IndexByte(s, '\n'); i != -1 {
+			line, s = s[:i], s[i+1:]
+		} else {
+			s = nil
 		}
-	}()
-	return gl
+		w.l.writef(w.level, "%s", []interface{}{line})
+	}
+	return len(p), nil
 }
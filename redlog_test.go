@@ -2,11 +2,632 @@ package redlog
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
+// nopWriter discards all writes, without being ioutil.Discard.
+type nopWriter struct{}
+
+func (nopWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+// capture returns a logger with the options that writes to memory, and a
+// func that returns the lines written since its last call, without the
+// pid, role, and time of their prefix, such as "* started port=6379".
+func capture(opts *Options) (*Logger, func() []string) {
+	var buf bytes.Buffer
+	l := New(&buf, opts)
+	return l, func() []string {
+		var lines []string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if line != "" {
+				lines = append(lines, strings.SplitN(line, " ", 6)[5])
+			}
+		}
+		buf.Reset()
+		return lines
+	}
+}
+
 func TestLog(t *testing.T) {
 	buf := &bytes.Buffer{}
 	l := New(buf, nil)
 	l.Printf("hello world\n")
 }
+
+func TestPretty(t *testing.T) {
+	now := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	var out []string
+	for _, pretty := range []bool{false, true} {
+		for _, color := range []int{ColorAlways, ColorNever} {
+			buf := &bytes.Buffer{}
+			l := New(buf, &Options{
+				Level:  LevelDebug,
+				Color:  color,
+				Pretty: pretty,
+				Now:    func() time.Time { return now },
+			})
+			l.pid = 1234
+			l.Debugf("debug")
+			l.Verbf("verbose\n")
+			l.Noticef("notice")
+			l.Warningf("warning")
+			l.Errorf("error")
+			out = append(out, buf.String())
+		}
+	}
+	plain := "" +
+		"1234:M 01 Jun 2024 15:04:05.000 . debug\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 - verbose\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 * notice\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 # warning\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 # error\n"
+	pretty := "" +
+		"\x1b[2m1234:M 01 Jun 2024 15:04:05.000\x1b[0m \x1b[35mDBG\x1b[0m debug\n" +
+		"\x1b[2m1234:M 01 Jun 2024 15:04:05.000\x1b[0m VRB verbose\n" +
+		"\x1b[2m1234:M 01 Jun 2024 15:04:05.000\x1b[0m \x1b[1mNTC\x1b[0m notice\n" +
+		"\x1b[2m1234:M 01 Jun 2024 15:04:05.000\x1b[0m \x1b[33mWRN\x1b[0m warning\n" +
+		"\x1b[2m1234:M 01 Jun 2024 15:04:05.000\x1b[0m \x1b[31mERR\x1b[0m error\n"
+	if out[1] != plain || out[3] != plain {
+		t.Fatalf("expected %q, got %q and %q", plain, out[1], out[3])
+	}
+	if out[2] != pretty {
+		t.Fatalf("expected %q, got %q", pretty, out[2])
+	}
+	if out[0] == pretty || !strings.Contains(out[0], "\x1b[35m1234:M\x1b[0m") {
+		t.Fatalf("unexpected color output %q", out[0])
+	}
+}
+
+func TestTrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, nil)
+	l.Tracef("trace %d", 1)
+	l.Trace("trace 2")
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output, got %q", buf.String())
+	}
+	l = New(buf, &Options{Level: LevelTrace})
+	l.Tracef("trace %d", 1)
+	l.Trace("trace 2")
+	l.Traceln("trace 3")
+	l.Debugf("debug")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.HasSuffix(lines[0], " , trace 1") ||
+		!strings.HasSuffix(lines[2], " , trace 3") ||
+		!strings.HasSuffix(lines[3], " . debug") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	buf.Reset()
+	l = New(buf, &Options{Level: LevelTrace, Filter: HashicorpRaftFilter})
+	l.Write([]byte("15:04:05 [TRACE] raft: sent packet\n"))
+	if !strings.HasSuffix(buf.String(), " , raft: sent packet\n") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	line := "1234:M 01 Jun 2024 15:04:05.000 , trace message\n"
+	if out := colorizeLine(line); !strings.HasSuffix(out,
+		" , trace message\n") {
+		t.Fatalf("unexpected colorized line %q", out)
+	}
+	if level, err := parseLevel("trace"); err != nil || level != LevelTrace {
+		t.Fatalf("unexpected level %v %v", level, err)
+	}
+}
+
+func TestFilterDrop(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{
+		Level: LevelTrace,
+		Filter: func(line string, tty bool) (string, byte, int) {
+			if strings.Contains(line, "noise") {
+				return line, 0, LevelDrop
+			}
+			return line, 0, LevelDebug
+		},
+	})
+	l.Write([]byte("some noise\n"))
+	l.Write([]byte("signal\n"))
+	if strings.Contains(buf.String(), "noise") ||
+		!strings.HasSuffix(buf.String(), " . signal\n") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	buf.Reset()
+	l = New(buf, &Options{
+		Level: LevelTrace,
+		Filter: HashicorpRaftFilterDropping("pipelining replication",
+			"heartbeat"),
+	})
+	l.Write([]byte("15:04:05 [DEBUG] raft: pipelining replication to " +
+		"peer 10.0.0.1\n"))
+	l.Write([]byte("15:04:05 [TRACE] raft: heartbeat sent\n"))
+	l.Write([]byte("15:04:05 [DEBUG] raft: votes needed: 2\n"))
+	if buf.String() == "" || strings.Count(buf.String(), "\n") != 1 ||
+		!strings.HasSuffix(buf.String(), " . raft: votes needed: 2\n") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestCondenseTimestamps(t *testing.T) {
+	clock := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	run := func(opts *Options) string {
+		buf := &bytes.Buffer{}
+		opts.Now = func() time.Time { return clock }
+		opts.CondenseTimestamps = true
+		l := New(buf, opts)
+		l.pid = 1234
+		l.Noticef("a")
+		l.Noticef("b")
+		l.Warningf("c")
+		clock = clock.Add(time.Millisecond)
+		l.Noticef("d")
+		l.Noticef("e")
+		clock = clock.Add(time.Microsecond)
+		l.Noticef("f")
+		clock = clock.Add(-time.Millisecond - time.Microsecond)
+		return buf.String()
+	}
+	color := "" +
+		"\x1b[35m1234:M\x1b[0m\x1b[2m 01 Jun 2024 15:04:05.000\x1b[0m \x1b[1m*\x1b[0m a\n" +
+		"\x1b[35m1234:M\x1b[0m\x1b[2m                        \"\x1b[0m \x1b[1m*\x1b[0m b\n" +
+		"\x1b[35m1234:M\x1b[0m\x1b[2m                        \"\x1b[0m \x1b[33m#\x1b[0m c\n" +
+		"\x1b[35m1234:M\x1b[0m\x1b[2m 01 Jun 2024 15:04:05.001\x1b[0m \x1b[1m*\x1b[0m d\n" +
+		"\x1b[35m1234:M\x1b[0m\x1b[2m                        \"\x1b[0m \x1b[1m*\x1b[0m e\n" +
+		"\x1b[35m1234:M\x1b[0m\x1b[2m                        \"\x1b[0m \x1b[1m*\x1b[0m f\n"
+	if out := run(&Options{Color: ColorAlways}); out != color {
+		t.Fatalf("expected %q, got %q", color, out)
+	}
+	pretty := "" +
+		"\x1b[2m1234:M 01 Jun 2024 15:04:05.000\x1b[0m \x1b[1mNTC\x1b[0m a\n" +
+		"\x1b[2m1234:M                        \"\x1b[0m \x1b[1mNTC\x1b[0m b\n"
+	out := run(&Options{Color: ColorAlways, Pretty: true})
+	if !strings.HasPrefix(out, pretty) {
+		t.Fatalf("expected prefix %q, got %q", pretty, out)
+	}
+	if out = run(&Options{Color: ColorNever}); strings.Count(out, "2024") != 6 {
+		t.Fatalf("expected full timestamps, got %q", out)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestReadFrom(t *testing.T) {
+	// a multi-megabyte file of already formatted lines
+	var src bytes.Buffer
+	for i := 0; src.Len() < 4<<20; i++ {
+		fmt.Fprintf(&src, "99:C 01 Jun 2024 15:04:05.000 * line %d\n", i)
+	}
+	n := int64(src.Len())
+	want := src.String()
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Level: LevelNotice, PassthroughFormatted: true})
+	if m, err := l.ReadFrom(&src); err != nil || m != n {
+		t.Fatalf("expected %d, nil, got %d, %v", n, m, err)
+	}
+	if buf.String() != want {
+		t.Fatalf("output does not match input")
+	}
+
+	// malformed lines, a huge line, and no trailing newline
+	huge := strings.Repeat("x", 1<<20)
+	in := "99:C 01 Jun 2024 15:04:05.000 # formatted\n" +
+		"not formatted\n" +
+		"99:C garbage * half formatted\r\n" +
+		"\n" +
+		huge + "\n" +
+		"last"
+	buf.Reset()
+	l = New(buf, &Options{
+		Level:                LevelNotice,
+		PassthroughFormatted: true,
+		MaxMessageSize:       100,
+	})
+	if m, err := l.ReadFrom(strings.NewReader(in)); err != nil ||
+		m != int64(len(in)) {
+		t.Fatalf("expected %d, nil, got %d, %v", len(in), m, err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 6 ||
+		lines[0] != "99:C 01 Jun 2024 15:04:05.000 # formatted" ||
+		!strings.HasSuffix(lines[1], " * not formatted") ||
+		!strings.HasSuffix(lines[2], " * 99:C garbage * half formatted") ||
+		!strings.HasSuffix(lines[3], " *") ||
+		!strings.HasSuffix(lines[4], " * "+huge[:100]) ||
+		!strings.HasSuffix(lines[5], " * last") {
+		t.Fatalf("unexpected output %q", lines)
+	}
+
+	errBoom := errors.New("boom")
+	if _, err := l.ReadFrom(errReader{errBoom}); err != errBoom {
+		t.Fatalf("expected %v, got %v", errBoom, err)
+	}
+}
+
+func TestPlain(t *testing.T) {
+	now := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	run := func(format, color int) string {
+		buf := &bytes.Buffer{}
+		var code int
+		l := New(buf, &Options{
+			Level:    LevelTrace,
+			Format:   format,
+			Color:    color,
+			Now:      func() time.Time { return now },
+			ExitFunc: func(c int) { code = c },
+		})
+		l.pid = 1234
+		l.Tracef("trace")
+		l.Debugf("debug")
+		l.Verbf("verbose\n")
+		l.Noticef("notice \t")
+		l.Warningf("warning")
+		l.Errorf("error")
+		l.Fatalf("fatal")
+		if code != 1 {
+			t.Fatalf("expected exit code 1, got %d", code)
+		}
+		return buf.String()
+	}
+	redis := "" +
+		"1234:M 01 Jun 2024 15:04:05.000 , trace\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 . debug\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 - verbose\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 * notice\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 # warning\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 # error\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 # fatal\n"
+	plain := "" +
+		"trace\n" +
+		"debug\n" +
+		"verbose\n" +
+		"notice\n" +
+		"warning: warning\n" +
+		"error: error\n" +
+		"error: fatal\n"
+	color := "" +
+		"trace\n" +
+		"debug\n" +
+		"verbose\n" +
+		"notice\n" +
+		"\x1b[33mwarning\x1b[0m: warning\n" +
+		"\x1b[31merror\x1b[0m: error\n" +
+		"\x1b[31merror\x1b[0m: fatal\n"
+	if out := run(FormatRedis, ColorNever); out != redis {
+		t.Fatalf("expected %q, got %q", redis, out)
+	}
+	if out := run(FormatPlain, ColorNever); out != plain {
+		t.Fatalf("expected %q, got %q", plain, out)
+	}
+	if out := run(FormatPlain, ColorAlways); out != color {
+		t.Fatalf("expected %q, got %q", color, out)
+	}
+}
+
+func TestGoLoggerOrder(t *testing.T) {
+	w := &slowWriter{}
+	l := New(w, &Options{Level: LevelNotice, MaxBatchBytes: 256})
+	gl := l.GoLogger()
+	done := make(chan bool)
+	go func() {
+		// noise from other goroutines
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				l.Noticef("noise")
+				gl.Printf("noise")
+			}
+		}
+	}()
+	for i := 0; i < 2000; i++ {
+		if i%2 == 0 {
+			gl.Printf("seq %d", i)
+		} else {
+			l.Noticef("seq %d", i)
+		}
+	}
+	gl.Printf("seq 2000\nseq 2001")
+	close(done)
+	l.Close()
+	var out bytes.Buffer
+	for _, p := range w.calls {
+		out.Write(p)
+	}
+	next := 0
+	for _, line := range strings.Split(out.String(), "\n") {
+		if i := strings.Index(line, " * seq "); i != -1 {
+			var n int
+			fmt.Sscanf(line[i+7:], "%d", &n)
+			if n != next {
+				t.Fatalf("expected seq %d, got %d", next, n)
+			}
+			next++
+		}
+	}
+	if next != 2002 {
+		t.Fatalf("expected 2002 lines, got %d", next)
+	}
+}
+
+func TestGoLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice})
+	srv := &http.Server{ErrorLog: l.GoLoggerLevel(LevelWarning)}
+	srv.ErrorLog.Printf("http: TLS handshake error from 10.0.0.1:5000: EOF")
+	l.GoLoggerLevel(LevelDebug).Printf("hidden")
+	if !strings.HasSuffix(buf.String(),
+		" # http: TLS handshake error from 10.0.0.1:5000: EOF\n") ||
+		strings.Count(buf.String(), "\n") != 1 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	if l.Stats().Warning != 1 {
+		t.Fatalf("expected one warning, got %+v", l.Stats())
+	}
+}
+
+func TestSetTimeFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice, Now: func() time.Time {
+		return time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	}})
+	l.pid = 1234
+	l.SetTimeFormat(time.RFC3339)
+	l.Noticef("rfc3339")
+	if l.TimeFormat() != time.RFC3339 {
+		t.Fatalf("unexpected layout %q", l.TimeFormat())
+	}
+	l.SetTimeFormat("")
+	l.Noticef("default")
+	want := "1234:M 2024-06-01T15:04:05Z * rfc3339\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 * default\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestLocation(t *testing.T) {
+	var buf bytes.Buffer
+	now := func() time.Time { return time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC) }
+	l := New(&buf, &Options{Level: LevelNotice, Now: now, UTC: true,
+		Location: time.FixedZone("UTC-5", -5*3600)})
+	l.pid = 1234
+	l.Noticef("zoned")
+	l = New(&buf, &Options{Level: LevelNotice, Now: now, UTC: true})
+	l.pid = 1234
+	l.Noticef("utc")
+	want := "1234:M 01 Jun 2024 10:04:05.000 * zoned\n" +
+		"1234:M 01 Jun 2024 15:04:05.000 * utc\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestTimePrecision(t *testing.T) {
+	var buf bytes.Buffer
+	now := func() time.Time {
+		return time.Date(2024, 6, 1, 15, 4, 5, 123456789, time.UTC)
+	}
+	for _, prec := range []time.Duration{time.Microsecond, time.Second,
+		time.Nanosecond, 0} {
+		l := New(&buf, &Options{Level: LevelNotice, Now: now,
+			TimePrecision: prec})
+		l.pid = 1234
+		l.Noticef("hello")
+	}
+	l := New(&buf, &Options{Level: LevelNotice, Now: now,
+		TimePrecision: time.Microsecond})
+	l.pid = 1234
+	l.SetTimeFormat("2006-01-02 15:04:05.999")
+	l.Noticef("custom")
+	want := "1234:M 01 Jun 2024 15:04:05.123456 * hello\n" +
+		"1234:M 01 Jun 2024 15:04:05 * hello\n" +
+		"1234:M 01 Jun 2024 15:04:05.123456789 * hello\n" +
+		"1234:M 01 Jun 2024 15:04:05.123 * hello\n" +
+		"1234:M 2024-06-01 15:04:05.123456 * custom\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	for _, line := range strings.Split(strings.TrimSpace(want), "\n")[:4] {
+		pl, ok := parseLine(line, DefaultOptions.TimeFormat)
+		if !ok || pl.msg != "hello" {
+			t.Fatalf("unexpected result %v %+v", ok, pl)
+		}
+		if out := logPostFilter(line); !strings.HasSuffix(out,
+			"\x1b[0m * hello") {
+			t.Fatalf("unexpected colorized line %q", out)
+		}
+	}
+}
+
+func TestSetApp(t *testing.T) {
+	var buf lockedBuffer
+	l := New(&buf, &Options{Level: LevelNotice})
+	l.pid = 1234
+	mod := l.WithModule("repl")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			l.SetApp("MS"[i%2])
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		l.Noticef("line")
+	}
+	wg.Wait()
+	l.SetApp('S')
+	mod.Noticef("demoted")
+	l.As('C').Noticef("child")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for _, line := range lines[:100] {
+		if !strings.HasPrefix(line, "1234:M ") &&
+			!strings.HasPrefix(line, "1234:S ") {
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+	if !strings.HasPrefix(lines[100], "1234:S ") ||
+		!strings.HasPrefix(lines[101], "1234:C ") || l.App() != 'S' {
+		t.Fatalf("unexpected lines %q", lines[100:])
+	}
+}
+
+func TestSequence(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice, Sequence: true,
+		Hostname: "node-3"})
+	l.pid = 1234
+	l.Noticef("first")
+	l.Debugf("hidden")
+	l.WithLabel("w1").Noticef("second")
+	l.SetFormat(FormatLogfmt)
+	l.Noticef("third")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "1234:M@node-3[#1] ") ||
+		!strings.HasPrefix(lines[1], "1234:M@node-3[w1][#2] ") ||
+		!strings.HasSuffix(lines[2], " msg=third host=node-3 seq=3") {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+	pl, ok := parseLine(lines[1], DefaultOptions.TimeFormat)
+	if !ok || pl.tags != "@node-3[w1][#2]" || pl.msg != "second" {
+		t.Fatalf("unexpected result %v %+v", ok, pl)
+	}
+}
+
+func TestSequenceOrder(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice, Sequence: true,
+		Multiline: MultilineSplit})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				l.Noticef("a\nb")
+			}
+		}()
+	}
+	wg.Wait()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 8*200*2 {
+		t.Fatalf("expected %d lines, got %d", 8*200*2, len(lines))
+	}
+	for i, line := range lines {
+		tag := "[#" + strconv.Itoa(i/2+1) + "] "
+		if !strings.Contains(line, tag) {
+			t.Fatalf("expected %q in line %d, got %q", tag, i, line)
+		}
+	}
+}
+
+func TestOnFatal(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	l := New(&buf, &Options{
+		OnFatal: func() {
+			calls = append(calls, "flush:"+strings.TrimSpace(buf.String()))
+		},
+		ExitFunc: func(code int) {
+			calls = append(calls, "exit:"+strconv.Itoa(code))
+		},
+	})
+	l.pid = 1234
+	l.Fatalf("cannot bind")
+	if len(calls) != 2 || !strings.HasSuffix(calls[0], " # cannot bind") ||
+		calls[1] != "exit:1" {
+		t.Fatalf("unexpected calls %q", calls)
+	}
+
+	// a panic in OnFatal still exits
+	calls = nil
+	l = New(&buf, &Options{
+		OnFatal: func() { panic("broken") },
+		ExitFunc: func(code int) {
+			calls = append(calls, "exit:"+strconv.Itoa(code))
+		},
+	})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected a panic")
+			}
+		}()
+		l.Fatal("down")
+	}()
+	if len(calls) != 1 || calls[0] != "exit:1" {
+		t.Fatalf("unexpected calls %q", calls)
+	}
+}
+
+func TestErrorNoExit(t *testing.T) {
+	var buf bytes.Buffer
+	var exits int
+	l := New(&buf, &Options{Color: ColorAlways,
+		ExitFunc: func(int) { exits++ }})
+	l.Errorf("failed %d", 1)
+	l.Error("failed ", 2)
+	l.Errorln("failed ", 3)
+	if exits != 0 || strings.Count(buf.String(), "\x1b[31m#\x1b[0m failed ") != 3 ||
+		l.Stats().Error != 3 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestMaxMessageLen(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Format: FormatPlain, MaxMessageLen: 10})
+	l.Noticef("%s", strings.Repeat("x", 1<<20))
+	l.With("key", "value").Noticef("012345678ééé")
+	l.Noticef("0123456789")
+	exp := "xxxxxxxxxx... (1048576 bytes)\n" +
+		"012345678... (15 bytes) key=value\n" +
+		"0123456789\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
+
+func TestMultiline(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+	l := New(&buf, &Options{Now: func() time.Time { return now }})
+	pid := strconv.Itoa(os.Getpid())
+	prefix := pid + ":M 01 Jun 2024 15:04:05.000 * "
+	l.Noticef("a\nb")
+	if exp := prefix + "a\nb\n"; buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+	buf.Reset()
+	l.multiline = MultilineSplit
+	l.With("k", "v").Noticef("a\n\nb\n")
+	exp := prefix + "a\n" + prefix + "\n" + prefix + "b k=v\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+	buf.Reset()
+	l.multiline = MultilineIndent
+	l.Noticef("a\nb")
+	indent := strings.Repeat(" ", len(prefix))
+	if exp := prefix + "a\n" + indent + "b\n"; buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+	buf.Reset()
+	l.SetFormat(FormatPlain)
+	l.Warningf("a\nb")
+	if exp := "warning: a\n         b\n"; buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/tidwall/redlog/v2/redlogr
+
+go 1.16
+
+require (
+	github.com/go-logr/logr v1.2.4
+	github.com/tidwall/redlog/v2 v2.0.0
+)
+
+replace github.com/tidwall/redlog/v2 => ../
@@ -0,0 +1,82 @@
+// Package redlogr adapts a redlog Logger to logr, in a module of its own
+// so that redlog does not depend on logr.
+package redlogr
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/tidwall/redlog/v2"
+)
+
+// logrSink adapts a Logger to logr.LogSink.
+type logrSink struct {
+	root *redlog.Logger // the logger passed to NewLogSink
+	l    *redlog.Logger // the module of the name
+	name string
+	kvs  []interface{} // from WithValues
+}
+
+// NewLogSink returns a logr.LogSink that logs to l, such as with
+// logr.New(redlogr.NewLogSink(l)). V(0) is logged as a notice, V(1) as
+// verbose, and higher verbosities as debug. Errors are logged at the error
+// level with the "error" key. Messages are prefixed with the name of the
+// logger, such as "controller/pods: ", which is also the module of the
+// messages for SetModuleLevel.
+func NewLogSink(l *redlog.Logger) logr.LogSink {
+	return &logrSink{root: l, l: l}
+}
+
+// logrLevel maps a V-level to a level of the logger.
+func logrLevel(level int) int {
+	switch {
+	case level <= 0:
+		return redlog.LevelNotice
+	case level == 1:
+		return redlog.LevelVerbose
+	}
+	return redlog.LevelDebug
+}
+
+func (s *logrSink) Init(info logr.RuntimeInfo) {}
+
+func (s *logrSink) Enabled(level int) bool {
+	return s.l.Enabled(logrLevel(level))
+}
+
+func (s *logrSink) Info(level int, msg string, kvs ...interface{}) {
+	s.log(logrLevel(level), msg, nil, kvs)
+}
+
+func (s *logrSink) Error(err error, msg string, kvs ...interface{}) {
+	s.log(redlog.LevelError, msg, []interface{}{"error", err}, kvs)
+}
+
+func (s *logrSink) log(level int, msg string, pre, kvs []interface{}) {
+	if !s.l.Enabled(level) {
+		return
+	}
+	if s.name != "" {
+		msg = s.name + ": " + msg
+	}
+	if len(pre) > 0 || len(s.kvs) > 0 {
+		all := make([]interface{}, 0, len(pre)+len(s.kvs)+len(kvs))
+		kvs = append(append(append(all, pre...), s.kvs...), kvs...)
+	}
+	s.l.Log(level, msg, kvs...)
+}
+
+func (s *logrSink) WithValues(kvs ...interface{}) logr.LogSink {
+	s2 := *s
+	s2.kvs = append(append([]interface{}(nil), s.kvs...), kvs...)
+	return &s2
+}
+
+// WithName appends a name, separated from the previous one by a slash.
+func (s *logrSink) WithName(name string) logr.LogSink {
+	s2 := *s
+	if s.name != "" {
+		name = s.name + "/" + name
+	}
+	s2.name = name
+	s2.l = s.root.WithModule(name)
+	return &s2
+}
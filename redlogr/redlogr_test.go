@@ -0,0 +1,49 @@
+package redlogr
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/tidwall/redlog/v2"
+)
+
+func TestLogSink(t *testing.T) {
+	var buf bytes.Buffer
+	l := redlog.New(&buf, &redlog.Options{Level: redlog.LevelVerbose})
+	log := logr.New(NewLogSink(l))
+	log.Info("starting", "workers", 4)
+	log.V(1).Info("syncing")
+	log.V(2).Info("hidden")
+	pods := log.WithName("controller").WithName("pods").WithValues("ns", "default")
+	pods.Error(errors.New("not found"), "reconcile failed", "pod", "web 1")
+	l.SetModuleLevel("controller/pods", redlog.LevelDebug)
+	pods.V(3).Info("shown")
+	if log.V(2).Enabled() || !pods.V(2).Enabled() {
+		t.Fatal("unexpected enabled levels")
+	}
+
+	want := []string{
+		`* starting workers=4`,
+		`- syncing`,
+		`# controller/pods: reconcile failed error="not found" ns=default pod="web 1"`,
+		`. controller/pods: shown ns=default`,
+	}
+	if got := messages(&buf); got != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", got)
+	}
+	if l.Stats().Error != 1 {
+		t.Fatalf("expected one error, got %+v", l.Stats())
+	}
+}
+
+// messages returns the lines of buf without the pid, role, and time.
+func messages(buf *bytes.Buffer) string {
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		msgs = append(msgs, strings.SplitN(line, " ", 6)[5])
+	}
+	return strings.Join(msgs, "\n")
+}
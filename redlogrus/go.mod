@@ -0,0 +1,10 @@
+module github.com/tidwall/redlog/v2/redlogrus
+
+go 1.15
+
+require (
+	github.com/sirupsen/logrus v1.9.3
+	github.com/tidwall/redlog/v2 v2.0.0
+)
+
+replace github.com/tidwall/redlog/v2 => ../
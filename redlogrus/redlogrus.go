@@ -0,0 +1,66 @@
+// Package redlogrus adapts a redlog Logger to logrus, in a module of its
+// own so that redlog does not depend on logrus.
+package redlogrus
+
+import (
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tidwall/redlog/v2"
+)
+
+// hook adapts a Logger to logrus.Hook.
+type hook struct {
+	l *redlog.Logger
+}
+
+// NewHook returns a logrus.Hook that logs the entries of a logrus logger
+// to l, so the logrus call sites of a program write the same lines as the
+// rest of it. The output of the logrus logger should be discarded:
+//
+//	log.AddHook(redlogrus.NewHook(l))
+//	log.SetOutput(ioutil.Discard)
+//
+// Info entries are logged as notices, and the levels from error to panic
+// at the error level. The data fields are appended as " key=value", in the
+// order of their keys.
+func NewHook(l *redlog.Logger) logrus.Hook {
+	return hook{l}
+}
+
+// level maps a logrus level to a level of the logger.
+func level(lv logrus.Level) int {
+	switch lv {
+	case logrus.TraceLevel:
+		return redlog.LevelTrace
+	case logrus.DebugLevel:
+		return redlog.LevelDebug
+	case logrus.InfoLevel:
+		return redlog.LevelNotice
+	case logrus.WarnLevel:
+		return redlog.LevelWarning
+	}
+	return redlog.LevelError
+}
+
+func (h hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h hook) Fire(ent *logrus.Entry) error {
+	lv := level(ent.Level)
+	if !h.l.Enabled(lv) {
+		return nil
+	}
+	keys := make([]string, 0, len(ent.Data))
+	for k := range ent.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]interface{}, 0, len(keys)*2)
+	for _, k := range keys {
+		kvs = append(kvs, k, ent.Data[k])
+	}
+	h.l.Log(lv, ent.Message, kvs...)
+	return nil
+}
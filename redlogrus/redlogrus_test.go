@@ -0,0 +1,35 @@
+package redlogrus
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tidwall/redlog/v2"
+)
+
+func TestHook(t *testing.T) {
+	var buf bytes.Buffer
+	l := redlog.New(&buf, &redlog.Options{Level: redlog.LevelNotice,
+		Format: redlog.FormatPlain})
+	log := logrus.New()
+	log.SetLevel(logrus.TraceLevel)
+	log.SetOutput(ioutil.Discard)
+	log.AddHook(NewHook(l))
+	log.Debug("hidden")
+	log.WithFields(logrus.Fields{"port": 6379, "name": "node 1"}).Info("started")
+	log.Warnf("slow %d", 2)
+	log.WithError(errors.New("eof")).Error("failed")
+
+	want := `started name="node 1" port=6379` + "\n" +
+		`warning: slow 2` + "\n" +
+		`error: failed error=eof` + "\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+	if l.Stats().Error != 1 {
+		t.Fatalf("expected one error, got %+v", l.Stats())
+	}
+}
@@ -0,0 +1,91 @@
+package redlogtest
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/redlog/v2"
+)
+
+// Capture is a logger that records its entries in memory, for tests that
+// check what was logged. The key value pairs of the entries are kept
+// separate from the message.
+type Capture struct {
+	*redlog.Logger
+	rec *recorder
+}
+
+// NewCapture returns a capturing logger. With nil options, every level is
+// captured. The Encoder and Formatter options are not used.
+func NewCapture(opts *redlog.Options) *Capture {
+	var o redlog.Options
+	if opts != nil {
+		o = *opts
+	} else {
+		o = *redlog.DefaultOptions
+		o.Level = redlog.LevelTrace
+	}
+	rec := &recorder{}
+	o.Encoder = rec
+	return &Capture{Logger: redlog.New(nopWriter{}, &o), rec: rec}
+}
+
+// Entries returns the entries, oldest first.
+func (c *Capture) Entries() []redlog.Record {
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	return append([]redlog.Record(nil), c.rec.entries...)
+}
+
+// LastWarning returns the last entry at the warning level or above, and
+// false when there is none.
+func (c *Capture) LastWarning() (redlog.Record, bool) {
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	for i := len(c.rec.entries) - 1; i >= 0; i-- {
+		if c.rec.entries[i].Level >= redlog.LevelWarning {
+			return c.rec.entries[i], true
+		}
+	}
+	return redlog.Record{}, false
+}
+
+// ContainsMessage returns true if the message of an entry contains substr.
+func (c *Capture) ContainsMessage(substr string) bool {
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	for _, e := range c.rec.entries {
+		if strings.Contains(e.Msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset removes the entries.
+func (c *Capture) Reset() {
+	c.rec.mu.Lock()
+	c.rec.entries = nil
+	c.rec.mu.Unlock()
+}
+
+// recorder is an Encoder that records the entries instead of writing them.
+type recorder struct {
+	mu      sync.Mutex
+	entries []redlog.Record
+}
+
+func (r *recorder) Encode(w io.Writer, rec redlog.Record) error {
+	rec.KVs = append([]interface{}(nil), rec.KVs...)
+	r.mu.Lock()
+	r.entries = append(r.entries, rec)
+	r.mu.Unlock()
+	return nil
+}
+
+// nopWriter discards all writes, without being ioutil.Discard, which
+// would disable the output of the logger.
+type nopWriter struct{}
+
+func (nopWriter) Write(p []byte) (int, error) { return len(p), nil }
@@ -0,0 +1,49 @@
+package redlogtest
+
+import (
+	"testing"
+
+	"github.com/tidwall/redlog/v2"
+)
+
+func TestCapture(t *testing.T) {
+	c := NewCapture(nil)
+	c.Tracef("trace")
+	c.Noticef("listening on %d", 6379)
+	c.Warningf("disk %d%% full", 90)
+	c.Timed(redlog.LevelNotice, "loading")()
+	c.As('C').Noticef("child")
+
+	entries := c.Entries()
+	if len(entries) != 6 || entries[0].Level != redlog.LevelTrace ||
+		entries[1].Msg != "listening on 6379" || entries[1].App != 'M' ||
+		entries[5].App != 'C' || entries[1].Time.IsZero() {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if kvs := entries[4].KVs; len(kvs) != 2 || kvs[0] != "duration" {
+		t.Fatalf("unexpected pairs %v", kvs)
+	}
+	if e, ok := c.LastWarning(); !ok || e.Msg != "disk 90% full" {
+		t.Fatalf("unexpected warning %+v", e)
+	}
+	if !c.ContainsMessage("6379") || c.ContainsMessage("6380") {
+		t.Fatal("unexpected ContainsMessage")
+	}
+	c.Reset()
+	if len(c.Entries()) != 0 || c.ContainsMessage("6379") {
+		t.Fatal("expected no entries")
+	}
+	if _, ok := c.LastWarning(); ok {
+		t.Fatal("expected no warning")
+	}
+	c.Errorf("failed")
+	if e, ok := c.LastWarning(); !ok || e.Level != redlog.LevelError {
+		t.Fatalf("unexpected error %+v", e)
+	}
+
+	c = NewCapture(&redlog.Options{Level: redlog.LevelWarning})
+	c.Noticef("hidden")
+	if len(c.Entries()) != 0 {
+		t.Fatalf("unexpected entries %+v", c.Entries())
+	}
+}
@@ -0,0 +1,63 @@
+// Package redlogtest provides loggers for the tests of programs that use
+// redlog.
+package redlogtest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tidwall/redlog/v2"
+)
+
+// NewTestLogger returns a logger that writes its lines to t.Log, so they
+// are shown with the test that logged them. With nil options, the level
+// is notice, or debug when the tests are verbose, such as with -v.
+//
+// Fatal and Fatalf do not exit, unless opts has an ExitFunc, so tests can
+// exercise fatal paths. The exit is logged instead. The logger is closed
+// when the test completes, and lines logged after that are discarded.
+func NewTestLogger(t testing.TB, opts *redlog.Options) *redlog.Logger {
+	var o redlog.Options
+	if opts != nil {
+		o = *opts
+	} else {
+		o = *redlog.DefaultOptions
+		if testing.Verbose() {
+			o.Level = redlog.LevelDebug
+		}
+	}
+	o.Color = redlog.ColorNever
+	if o.ExitFunc == nil {
+		o.ExitFunc = func(code int) {
+			t.Helper()
+			t.Logf("exit status %d (ignored by the test logger)", code)
+		}
+	}
+	w := &tbWriter{t: t}
+	l := redlog.New(w, &o)
+	t.Cleanup(func() {
+		l.Close()
+		w.mu.Lock()
+		w.done = true
+		w.mu.Unlock()
+	})
+	return l
+}
+
+// tbWriter writes lines to t.Log until the test is done.
+type tbWriter struct {
+	t    testing.TB
+	mu   sync.Mutex
+	done bool
+}
+
+func (w *tbWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.done {
+		w.t.Helper()
+		w.t.Log(strings.TrimRight(string(p), "\n"))
+	}
+	return len(p), nil
+}
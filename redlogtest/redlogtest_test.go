@@ -0,0 +1,52 @@
+package redlogtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/redlog/v2"
+)
+
+// fakeTB records the logs and cleanups of a test.
+type fakeTB struct {
+	testing.TB
+	logs     []string
+	cleanups []func()
+}
+
+func (t *fakeTB) Helper()                 {}
+func (t *fakeTB) Log(args ...interface{}) { t.logs = append(t.logs, fmt.Sprint(args...)) }
+func (t *fakeTB) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+func (t *fakeTB) Cleanup(fn func()) { t.cleanups = append(t.cleanups, fn) }
+
+func TestNewTestLogger(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	l := NewTestLogger(tb, &redlog.Options{Level: redlog.LevelNotice,
+		App: 'M'})
+	l.Debugf("hidden")
+	l.Noticef("hello")
+	l.Fatalf("fatal")
+	l.Noticef("still running")
+	for _, fn := range tb.cleanups {
+		fn()
+	}
+	l.Noticef("after the test")
+
+	out := strings.Join(tb.logs, "\n")
+	for _, s := range []string{" * hello", " # fatal",
+		"exit status 1 (ignored by the test logger)", " * still running"} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("expected %q in %q", s, out)
+		}
+	}
+	if strings.Contains(out, "hidden") || strings.Contains(out, "after") ||
+		strings.Contains(out, "\x1b[") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	// the real thing
+	NewTestLogger(t, nil).Noticef("logged by %s", t.Name())
+}
@@ -0,0 +1,18 @@
+module github.com/tidwall/redlog/v2/redzap
+
+go 1.19
+
+require (
+	github.com/tidwall/redlog/v2 v2.0.0
+	go.uber.org/zap v1.24.0
+)
+
+require (
+	go.uber.org/atomic v1.7.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+	golang.org/x/crypto v0.0.0-20201116153603-4be66e5b6582 // indirect
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1 // indirect
+)
+
+replace github.com/tidwall/redlog/v2 => ../
@@ -0,0 +1,103 @@
+// Package redzap adapts a redlog Logger to zap, in a module of its own so
+// that redlog does not depend on zap.
+package redzap
+
+import (
+	"sort"
+
+	"github.com/tidwall/redlog/v2"
+	"go.uber.org/zap/zapcore"
+)
+
+// core adapts a Logger to zapcore.Core.
+type core struct {
+	l      *redlog.Logger
+	prefix string        // namespace of the fields that follow
+	kvs    []interface{} // fields from With
+}
+
+// NewCore returns a zapcore.Core that logs to l, such as with
+// zap.New(redzap.NewCore(l)). Debug entries are logged as debug, info
+// as notices, warn as warnings, and the higher levels as errors. Fields
+// are appended as " key=value", with the keys of namespaces joined by
+// dots, and entries of a named zap logger are prefixed with the name.
+// Colors are used as for the other messages of the logger.
+func NewCore(l *redlog.Logger) zapcore.Core {
+	return &core{l: l}
+}
+
+// zapLevel maps a zap level to a level of the logger.
+func zapLevel(level zapcore.Level) int {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return redlog.LevelDebug
+	case level == zapcore.InfoLevel:
+		return redlog.LevelNotice
+	case level == zapcore.WarnLevel:
+		return redlog.LevelWarning
+	}
+	return redlog.LevelError
+}
+
+func (c *core) Enabled(level zapcore.Level) bool {
+	return c.l.Enabled(zapLevel(level))
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	c2 := *c
+	c2.kvs = append([]interface{}(nil), c.kvs...)
+	c2.kvs, c2.prefix = appendZapFields(c2.kvs, c.prefix, fields)
+	return &c2
+}
+
+func (c *core) Check(ent zapcore.Entry,
+	ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	msg := ent.Message
+	if ent.LoggerName != "" {
+		msg = ent.LoggerName + ": " + msg
+	}
+	kvs := c.kvs
+	if len(fields) > 0 {
+		kvs = append(make([]interface{}, 0, len(kvs)+len(fields)*2), kvs...)
+		kvs, _ = appendZapFields(kvs, c.prefix, fields)
+	}
+	c.l.Log(zapLevel(ent.Level), msg, kvs...)
+	return nil
+}
+
+// Sync waits for the lines being written by other goroutines.
+func (c *core) Sync() error {
+	c.l.Flush()
+	return nil
+}
+
+// appendZapFields appends fields as key value pairs, and returns the
+// namespace of the fields that follow them.
+func appendZapFields(kvs []interface{}, prefix string,
+	fields []zapcore.Field) ([]interface{}, string) {
+	for _, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			prefix += f.Key + "."
+			continue
+		}
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		// a field adds a single key, except for inline objects
+		keys := make([]string, 0, len(enc.Fields))
+		for k := range enc.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			kvs = append(kvs, prefix+k, enc.Fields[k])
+		}
+	}
+	return kvs, prefix
+}
@@ -0,0 +1,55 @@
+package redzap
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/redlog/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCore(t *testing.T) {
+	var buf bytes.Buffer
+	l := redlog.New(&buf, &redlog.Options{Level: redlog.LevelNotice,
+		Format: redlog.FormatPlain})
+	log := zap.New(NewCore(l))
+	log.Debug("hidden")
+	log.Info("started", zap.Int("port", 6379), zap.String("name", "node 1"))
+	log.Named("raft").With(zap.Namespace("peer"), zap.Int("id", 2)).
+		Warn("slow", zap.Duration("rtt", 1500000))
+	log.Error("failed", zap.Error(errors.New("eof")),
+		zap.Inline(zapcore.ObjectMarshalerFunc(
+			func(enc zapcore.ObjectEncoder) error {
+				enc.AddInt("b", 2)
+				enc.AddInt("a", 1)
+				return nil
+			})))
+	if err := log.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if log.Core().Enabled(zapcore.DebugLevel) ||
+		!log.Core().Enabled(zapcore.InfoLevel) {
+		t.Fatal("unexpected enabled levels")
+	}
+	want := `started port=6379 name="node 1"` + "\n" +
+		`warning: raft: slow peer.id=2 peer.rtt=1.5ms` + "\n" +
+		`error: failed error=eof a=1 b=2` + "\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+	if l.Stats().Error != 1 {
+		t.Fatalf("expected one error, got %+v", l.Stats())
+	}
+
+	// colors are those of the logger
+	buf.Reset()
+	l = redlog.New(&buf, &redlog.Options{Level: redlog.LevelNotice,
+		Color: redlog.ColorAlways})
+	zap.New(NewCore(l)).Warn("colored")
+	if !strings.Contains(buf.String(), "\x1b[33m#\x1b[0m colored") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
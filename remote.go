@@ -0,0 +1,300 @@
+package redlog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Framings of the lines sent by a RemoteWriter.
+const (
+	FramingNewline = 0 // each line ends with a newline
+	FramingLength  = 1 // each line, without newline, follows a 4-byte length
+)
+
+// RemoteOptions are the options of NewRemoteWriter.
+type RemoteOptions struct {
+	// Framing of the lines. Defaults to FramingNewline.
+	Framing int
+	// TLS enables TLS with the configuration.
+	TLS *tls.Config
+	// SpoolSize is the most bytes kept while the collector cannot be
+	// reached. The oldest lines are dropped first. Defaults to 1MB.
+	SpoolSize int
+	// MinBackoff and MaxBackoff bound the delay between reconnects, which
+	// doubles after every failure. Default to 100ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// DialTimeout defaults to 10s.
+	DialTimeout time.Duration
+	// WriteTimeout is the longest a write to the collector may take before
+	// the connection is considered lost, which also bounds how long Close
+	// waits for a stalled collector. Defaults to 10s.
+	WriteTimeout time.Duration
+	// Warn receives the changes of the connection state. It must not be
+	// a logger writing to the RemoteWriter. Defaults to os.Stderr.
+	Warn io.Writer
+}
+
+// RemoteWriter sends lines to a collector over the network. Lines are
+// spooled in memory and sent in the background, so Write never waits for
+// the network. The connection is reestablished when it fails, and the
+// lines that were not completely written are sent again, so a line that
+// was cut off by a failure is received again in full.
+type RemoteWriter struct {
+	network, addr string
+	opts          RemoteOptions
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	spool   [][]byte // lines not yet sent, oldest first
+	size    int      // bytes in spool
+	seq     uint64   // sequence number of spool[0]
+	dropped uint64
+	closing bool
+	quit    chan struct{} // closed by Close
+	done    chan struct{} // closed when run returns
+	conn    net.Conn
+}
+
+// errClosed is returned by writes after Close.
+var errClosed = errors.New("closed")
+
+// NewRemoteWriter returns a writer that sends lines to the collector at
+// addr, such as NewRemoteWriter("tcp", "logs:9000", RemoteOptions{}). It
+// can be the writer of a logger, or its Fallback.
+func NewRemoteWriter(network, addr string, opts RemoteOptions) *RemoteWriter {
+	if opts.SpoolSize <= 0 {
+		opts.SpoolSize = 1024 * 1024
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = 30 * time.Second
+		if opts.MaxBackoff < opts.MinBackoff {
+			opts.MaxBackoff = opts.MinBackoff
+		}
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 10 * time.Second
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = 10 * time.Second
+	}
+	if opts.Warn == nil {
+		opts.Warn = os.Stderr
+	}
+	w := &RemoteWriter{network: network, addr: addr, opts: opts,
+		quit: make(chan struct{}), done: make(chan struct{})}
+	w.cond = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// Write spools the lines in p. A line longer than the spool is dropped.
+func (w *RemoteWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closing {
+		return 0, errClosed
+	}
+	for s := p; len(s) > 0; {
+		n := bytes.IndexByte(s, '\n') + 1
+		if n == 0 {
+			n = len(s)
+		}
+		line := append(make([]byte, 0, n+1), s[:n]...)
+		if line[len(line)-1] != '\n' {
+			line = append(line, '\n')
+		}
+		s = s[n:]
+		if len(line) > w.opts.SpoolSize {
+			w.dropped++
+			continue
+		}
+		for w.size+len(line) > w.opts.SpoolSize {
+			w.size -= len(w.spool[0])
+			w.spool[0] = nil
+			w.spool = w.spool[1:]
+			w.seq++
+			w.dropped++
+		}
+		w.spool = append(w.spool, line)
+		w.size += len(line)
+	}
+	w.cond.Signal()
+	return len(p), nil
+}
+
+// Dropped returns the number of lines dropped because the spool was full.
+func (w *RemoteWriter) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Close sends the spooled lines when connected, and closes the connection.
+func (w *RemoteWriter) Close() error {
+	w.mu.Lock()
+	if w.closing {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closing = true
+	w.cond.Signal()
+	w.mu.Unlock()
+	close(w.quit)
+	<-w.done
+	return nil
+}
+
+// run sends the spooled lines, reconnecting as needed.
+func (w *RemoteWriter) run() {
+	defer close(w.done)
+	backoff := w.opts.MinBackoff
+	var down bool // warned about the connection
+	var buf []byte
+	var sizes []int // of the framed lines in buf
+	for {
+		w.mu.Lock()
+		for len(w.spool) == 0 && !w.closing {
+			w.cond.Wait()
+		}
+		if w.closing && (len(w.spool) == 0 || w.conn == nil) {
+			w.mu.Unlock()
+			break
+		}
+		conn, closing := w.conn, w.closing
+		w.mu.Unlock()
+
+		if conn == nil {
+			var err error
+			conn, err = w.dial()
+			if err != nil {
+				if !down {
+					w.warn("cannot connect: %v, spooling lines", err)
+					down = true
+				}
+				if !w.sleep(backoff) {
+					continue
+				}
+				if backoff *= 2; backoff > w.opts.MaxBackoff {
+					backoff = w.opts.MaxBackoff
+				}
+				continue
+			}
+			if down {
+				w.warn("connected")
+				down = false
+			}
+			backoff = w.opts.MinBackoff
+			w.mu.Lock()
+			w.conn = conn
+			w.mu.Unlock()
+		}
+
+		// send the spooled lines, which are removed only once written
+		w.mu.Lock()
+		seq := w.seq
+		buf, sizes = buf[:0], sizes[:0]
+		for _, line := range w.spool {
+			n := len(buf)
+			buf = w.frame(buf, line)
+			sizes = append(sizes, len(buf)-n)
+		}
+		w.mu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(w.opts.WriteTimeout))
+		n, err := conn.Write(buf)
+		w.remove(seq, sizes, n)
+		if err != nil {
+			w.warn("connection lost: %v, spooling lines", err)
+			down = true
+			conn.Close()
+			w.mu.Lock()
+			w.conn = nil
+			w.mu.Unlock()
+			if closing {
+				break
+			}
+			continue
+		}
+	}
+	w.mu.Lock()
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	w.mu.Unlock()
+}
+
+// remove removes the lines that were completely written, which are those
+// of the sizes that fit in the n bytes sent from the line with the
+// sequence number seq.
+func (w *RemoteWriter) remove(seq uint64, sizes []int, n int) {
+	end := seq
+	for _, size := range sizes {
+		if n < size {
+			break
+		}
+		n -= size
+		end++
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.seq < end && len(w.spool) > 0 {
+		w.size -= len(w.spool[0])
+		w.spool[0] = nil
+		w.spool = w.spool[1:]
+		w.seq++
+	}
+}
+
+// sleep waits for d, and returns false if the writer is closed first.
+func (w *RemoteWriter) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-w.quit:
+		return false
+	}
+}
+
+func (w *RemoteWriter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: w.opts.DialTimeout}
+	if w.opts.TLS != nil {
+		return tls.DialWithDialer(dialer, w.network, w.addr, w.opts.TLS)
+	}
+	conn, err := dialer.Dial(w.network, w.addr)
+	if err == nil && conn.LocalAddr().String() == conn.RemoteAddr().String() {
+		// a local port with no listener can connect to itself
+		conn.Close()
+		return nil, errors.New("connection refused")
+	}
+	return conn, err
+}
+
+// frame appends a line in the framing of the options.
+func (w *RemoteWriter) frame(dst, line []byte) []byte {
+	if w.opts.Framing != FramingLength {
+		return append(dst, line...)
+	}
+	line = line[:len(line)-1]
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(line)))
+	return append(append(dst, n[:]...), line...)
+}
+
+func (w *RemoteWriter) warn(format string, args ...interface{}) {
+	fmt.Fprintf(w.opts.Warn, "redlog: remote %s: %s\n", w.addr,
+		fmt.Sprintf(format, args...))
+}
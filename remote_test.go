@@ -0,0 +1,286 @@
+package redlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+// collector receives the lines sent to a listener.
+type collector struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	lines []string
+	conns []net.Conn
+	wg    sync.WaitGroup
+}
+
+func startCollector(t *testing.T, addr string, length bool) *collector {
+	var ln net.Listener
+	var err error
+	for i := 0; i < 50; i++ {
+		// the address of a stopped collector may take a moment to free up
+		if ln, err = net.Listen("tcp", addr); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &collector{ln: ln}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.mu.Lock()
+			c.conns = append(c.conns, conn)
+			c.mu.Unlock()
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				rd := bufio.NewReader(conn)
+				for {
+					var line string
+					if length {
+						var n [4]byte
+						if _, err := io.ReadFull(rd, n[:]); err != nil {
+							return
+						}
+						b := make([]byte, binary.BigEndian.Uint32(n[:]))
+						if _, err := io.ReadFull(rd, b); err != nil {
+							return
+						}
+						line = string(b)
+					} else {
+						s, err := rd.ReadString('\n')
+						if err != nil {
+							return
+						}
+						line = strings.TrimSuffix(s, "\n")
+					}
+					c.mu.Lock()
+					c.lines = append(c.lines, line)
+					c.mu.Unlock()
+				}
+			}()
+		}
+	}()
+	return c
+}
+
+func (c *collector) stop() {
+	c.ln.Close()
+	c.mu.Lock()
+	for _, conn := range c.conns {
+		conn.Close()
+	}
+	c.mu.Unlock()
+	c.wg.Wait()
+}
+
+// wait waits for the collector to have n lines, and returns them.
+func (c *collector) wait(t *testing.T, n int) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		lines := append([]string(nil), c.lines...)
+		c.mu.Unlock()
+		if len(lines) >= n {
+			return lines
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d lines", n)
+	return nil
+}
+
+func TestRemoteWriter(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	c := startCollector(t, "127.0.0.1:0", false)
+	addr := c.ln.Addr().String()
+	var warn lockedBuffer
+	w := NewRemoteWriter("tcp", addr, RemoteOptions{
+		MinBackoff: 5 * time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+		Warn:       &warn,
+	})
+	l := New(w, &Options{Level: LevelNotice})
+	for i := 0; i < 10; i++ {
+		l.Noticef("line %d", i)
+	}
+	c.wait(t, 10)
+
+	// lines logged while the collector is down are spooled and sent once
+	// it is back
+	c.stop()
+	for i := 10; i < 30; i++ {
+		l.Noticef("line %d", i)
+		time.Sleep(time.Millisecond)
+	}
+	c2 := startCollector(t, addr, false)
+	for i := 30; i < 40; i++ {
+		l.Noticef("line %d", i)
+	}
+	lines := c2.wait(t, 1)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	c2.stop()
+
+	// writes to a connection whose peer is gone may succeed before the
+	// error is seen, so lines in flight may be lost, but none are repeated
+	// and every line after the reconnect arrives
+	seen := make(map[string]bool)
+	for _, line := range append(c.lines, c2.lines...) {
+		msg := line[strings.Index(line, " * ")+3:]
+		if seen[msg] {
+			t.Fatalf("duplicate line %q", line)
+		}
+		seen[msg] = true
+	}
+	for i := 0; i < 10; i++ {
+		if !seen[fmt.Sprintf("line %d", i)] {
+			t.Fatalf("missing line %d", i)
+		}
+	}
+	for i := 30; i < 40; i++ {
+		if !seen[fmt.Sprintf("line %d", i)] {
+			t.Fatalf("missing line %d in %q", i, lines)
+		}
+	}
+	if w.Dropped() != 0 {
+		t.Fatalf("expected no dropped lines, got %d", w.Dropped())
+	}
+	if s := warn.String(); !strings.Contains(s, "redlog: remote "+addr+
+		": connection lost") && !strings.Contains(s, "cannot connect") ||
+		!strings.Contains(s, ": connected\n") {
+		t.Fatalf("unexpected warnings %q", s)
+	}
+	if _, err := w.Write([]byte("late\n")); err == nil {
+		t.Fatal("expected error after close")
+	}
+}
+
+func TestRemoteWriterSpool(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	var warn lockedBuffer
+	w := NewRemoteWriter("tcp", addr, RemoteOptions{
+		Framing:    FramingLength,
+		SpoolSize:  100,
+		MinBackoff: 5 * time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+		Warn:       &warn,
+	})
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(w, "line %02d\n", i)
+		w.mu.Lock()
+		size := w.size
+		w.mu.Unlock()
+		if size > 100 {
+			t.Fatalf("spool grew to %d bytes", size)
+		}
+	}
+	w.Write([]byte(strings.Repeat("x", 200) + "\n"))
+	if w.Dropped() != 100-12+1 {
+		t.Fatalf("unexpected dropped count %d", w.Dropped())
+	}
+
+	// the newest lines are sent once the collector is up
+	for !strings.Contains(warn.String(), "cannot connect") {
+		time.Sleep(time.Millisecond)
+	}
+	c := startCollector(t, addr, true)
+	lines := c.wait(t, 12)
+	if len(lines) != 12 || lines[0] != "line 88" || lines[11] != "line 99" {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+	w.Close()
+	c.stop()
+	if strings.Count(warn.String(), "cannot connect") != 1 {
+		t.Fatalf("expected one warning per outage, got %q", warn.String())
+	}
+}
+
+func TestRemoteWriterStalled(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		// accepts, and never reads
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+	var warn lockedBuffer
+	w := NewRemoteWriter("tcp", ln.Addr().String(), RemoteOptions{
+		SpoolSize:    64 << 20,
+		WriteTimeout: 50 * time.Millisecond,
+		Warn:         &warn,
+	})
+	line := strings.Repeat("x", 1023) + "\n"
+	for i := 0; i < 32<<10; i++ {
+		w.Write([]byte(line))
+	}
+	conn := <-accepted
+	defer conn.Close()
+	start := time.Now()
+	w.Close()
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("close took %s", d)
+	}
+	if !strings.Contains(warn.String(), "connection lost") {
+		t.Fatalf("expected a warning, got %q", warn.String())
+	}
+}
+
+func TestRemoteWriterPartial(t *testing.T) {
+	for _, framing := range []int{FramingNewline, FramingLength} {
+		w := &RemoteWriter{opts: RemoteOptions{Framing: framing}, seq: 7}
+		var buf []byte
+		var sizes []int
+		for _, s := range []string{"one\n", "two\n", "three\n"} {
+			w.spool = append(w.spool, []byte(s))
+			w.size += len(s)
+			n := len(buf)
+			buf = w.frame(buf, []byte(s))
+			sizes = append(sizes, len(buf)-n)
+		}
+		// the second line was cut off
+		w.remove(7, sizes, sizes[0]+sizes[1]-1)
+		if w.seq != 8 || len(w.spool) != 2 || string(w.spool[0]) != "two\n" ||
+			w.size != 10 {
+			t.Fatalf("unexpected spool %d %q %d", w.seq, w.spool, w.size)
+		}
+		// a line was dropped meanwhile
+		w.spool, w.seq, w.size = w.spool[1:], 9, 6
+		w.remove(8, sizes[1:], sizes[1]+sizes[2])
+		if w.seq != 10 || len(w.spool) != 0 || w.size != 0 {
+			t.Fatalf("unexpected spool %d %q %d", w.seq, w.spool, w.size)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package redlog
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// repeats collapses identical consecutive lines, see CollapseRepeats.
+type repeats struct {
+	mu    sync.Mutex
+	every time.Duration // CollapseRepeats
+	app   byte
+	level int
+	last  []byte // message of the last line written
+	n     int    // lines dropped since then
+	timer stopper
+	gen   int
+}
+
+// repeated reports whether the message is the same as the last line, in
+// which case it's dropped. Otherwise the summary of the dropped lines is
+// written before the message becomes the last line.
+func (l *Logger) repeated(app byte, level int, msg []byte) bool {
+	r := l.repeats
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.last != nil && r.app == app && r.level == level &&
+		string(r.last) == string(msg) {
+		if r.n++; r.n == 1 {
+			gen := r.gen
+			r.timer = l.afterFunc(r.every, func() { l.flushRepeats(gen) })
+		}
+		atomic.AddUint64(&l.suppressed, 1)
+		return true
+	}
+	l.writeRepeats()
+	r.app, r.level = app, level
+	r.last = append(r.last[:0], msg...)
+	return false
+}
+
+// flushRepeats writes the summary of the dropped lines, unless it was
+// already written since the timer of gen was started.
+func (l *Logger) flushRepeats(gen int) {
+	r := l.repeats
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if gen == r.gen {
+		l.writeRepeats()
+	}
+}
+
+// writeRepeats writes "last message repeated N times" at the level of the
+// last line. The caller holds the lock.
+func (l *Logger) writeRepeats() {
+	r := l.repeats
+	if r.n == 0 {
+		return
+	}
+	n := r.n
+	r.n = 0
+	r.gen++
+	r.timer.Stop()
+	r.timer = nil
+	var line []byte
+	var ts, te int
+	if l.Format() == FormatPlain {
+		if p := plainPrefixes[r.level-LevelTrace]; p != "" {
+			line = append(append(line, p...), ": "...)
+		}
+	} else {
+		line, ts, te = appendPrefix(line, l.pid, r.app, l.loadHostTag(),
+			l.now(), l.TimeFormat(), r.level)
+	}
+	line = append(line, "last message repeated "...)
+	line = strconv.AppendInt(line, int64(n), 10)
+	if n == 1 {
+		line = append(line, " time"...)
+	} else {
+		line = append(line, " times"...)
+	}
+	l.emit(r.level, line, true, ts, te, 0)
+}
@@ -0,0 +1,77 @@
+package redlog
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCollapseRepeats(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Format: FormatPlain, CollapseRepeats: time.Minute})
+	ft := &fakeTimers{now: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	ft.install(l)
+
+	// a different line writes the summary
+	for i := 0; i < 5; i++ {
+		l.Warningf("disk full")
+	}
+	l.Noticef("disk ok")
+	l.Noticef("disk ok")
+	l.Warningf("disk ok")
+	exp := "warning: disk full\n" +
+		"warning: last message repeated 4 times\n" +
+		"disk ok\n" +
+		"last message repeated 1 time\n" +
+		"warning: disk ok\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+
+	// the timer writes the summary
+	buf.Reset()
+	l.Warningf("disk ok")
+	l.Warningf("disk ok")
+	ft.advance(time.Minute)
+	l.Warningf("disk ok")
+	l.Warningf("disk ok")
+	exp = "warning: last message repeated 2 times\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+	if s := l.Stats(); s.Suppressed != 4+1+4 || s.Warning != 4 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	// named loggers and pairs are different lines
+	buf.Reset()
+	l.Named("a").Warningf("disk ok")
+	l.With("disk", 1).Warningf("disk ok")
+	l.With("disk", 1).Warningf("disk ok")
+	l.Close()
+	exp = "warning: last message repeated 2 times\n" +
+		"warning: [a] disk ok\n" +
+		"warning: disk ok disk=1\n" +
+		"warning: last message repeated 1 time\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
+
+func TestCollapseRepeatsPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{App: AppReplica, CollapseRepeats: time.Minute,
+		Now: func() time.Time {
+			return time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+		}})
+	l.Noticef("sync")
+	l.Noticef("sync")
+	l.Close()
+	exp := "sync\n" + strconv.Itoa(os.Getpid()) +
+		":S 01 Jun 2024 15:04:05.000 * last message repeated 1 time\n"
+	if got := buf.String(); got[len(got)-len(exp):] != exp {
+		t.Fatalf("expected %q, got %q", exp, got)
+	}
+}
@@ -0,0 +1,50 @@
+package redlog
+
+import (
+	"fmt"
+	"io"
+)
+
+// ring is a fixed size ring of recent log lines. The line buffers are
+// reused as the ring wraps around.
+type ring struct {
+	lines [][]byte
+	next  int
+	count int
+}
+
+func newRing(size int) *ring {
+	return &ring{lines: make([][]byte, size)}
+}
+
+func (r *ring) add(line []byte) {
+	r.lines[r.next] = append(r.lines[r.next][:0], line...)
+	r.next = (r.next + 1) % len(r.lines)
+	if r.count < len(r.lines) {
+		r.count++
+	}
+}
+
+// writeTo writes the last n lines, oldest first.
+func (r *ring) writeTo(w io.Writer, n int) {
+	if n > r.count {
+		n = r.count
+	}
+	start := (r.next - n + len(r.lines)) % len(r.lines)
+	for i := 0; i < n; i++ {
+		w.Write(append(r.lines[(start+i)%len(r.lines)], '\n'))
+	}
+}
+
+// DumpRing writes the lines in the crash ring to w, between a pair of
+// separator lines. It does nothing when the CrashRing option is not set.
+func (l *Logger) DumpRing(w io.Writer) {
+	if l.ring == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(w, "----- last %d log lines -----\n", l.ring.count)
+	l.ring.writeTo(w, l.ring.count)
+	fmt.Fprintf(w, "----- end of log lines -----\n")
+}
@@ -0,0 +1,78 @@
+package redlog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCrashRing(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var code int
+	l := New(buf, &Options{
+		Level:     LevelNotice,
+		CrashRing: 3,
+		ExitFunc:  func(c int) { code = c },
+	})
+	for i := 0; i < 5; i++ {
+		l.Debugf("debug %d", i)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output, got %q", buf.String())
+	}
+	l.Fatalf("fatal")
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	expect := []string{"# fatal", "----- last 3 log lines -----",
+		". debug 3", ". debug 4", "# fatal", "----- end of log lines -----"}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, expect[i]) {
+			t.Fatalf("line %d: expected %q, got %q", i, expect[i], line)
+		}
+	}
+}
+
+func TestCrashRingPanic(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Level: LevelNotice, CrashRing: 10})
+	l.Debugf("debug")
+	func() {
+		defer func() { recover() }()
+		l.Panicf("panic")
+	}()
+	if !strings.Contains(buf.String(), "----- last 2 log lines -----") ||
+		!strings.Contains(buf.String(), ". debug\n") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	var dump bytes.Buffer
+	l.DumpRing(&dump)
+	if strings.Count(dump.String(), "\n") != 4 {
+		t.Fatalf("unexpected dump %q", dump.String())
+	}
+	New(buf, nil).DumpRing(&dump)
+	if strings.Count(dump.String(), "\n") != 4 {
+		t.Fatalf("unexpected dump %q", dump.String())
+	}
+}
+
+func BenchmarkCrashRing(b *testing.B) {
+	for _, size := range []int{0, 1000} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			l := New(nopWriter{}, &Options{
+				Level:     LevelNotice,
+				CrashRing: size,
+			})
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.Noticef("hello world")
+			}
+		})
+	}
+}
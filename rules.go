@@ -0,0 +1,145 @@
+package redlog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rule actions
+const (
+	RuleDrop    = 0 // drop the message
+	RuleLevel   = 1 // log the message at the Level of the rule
+	RuleRewrite = 2 // replace the matches with the Rewrite of the rule
+)
+
+// Rule is an action for the messages that match a regular expression, for
+// the Rules option and SetRules. See ParseRules.
+type Rule struct {
+	Match   *regexp.Regexp
+	Action  int
+	Level   int    // for RuleLevel
+	Rewrite string // for RuleRewrite, with $1 for submatches
+}
+
+// SetRules replaces the rules of the logger and its child loggers, such as
+// to silence a noisy message without a restart. The rules are applied to
+// each message in order, and a rewritten message is matched by the rules
+// after it. Only messages enabled at their level are seen by the rules, so
+// they can lower the level of a message, but not log a disabled one. A
+// nil slice removes the rules.
+func (l *Logger) SetRules(rules []Rule) {
+	if len(rules) == 0 {
+		rules = nil
+	}
+	l.rules.Store(rules)
+}
+
+// Rules returns the rules of the logger.
+func (l *Logger) Rules() []Rule {
+	rules, _ := l.rules.Load().([]Rule)
+	return rules
+}
+
+// applyRules returns the message and level after the rules, or false when
+// the message is dropped.
+func applyRules(rules []Rule, msg string, level int) (string, int, bool) {
+	for _, r := range rules {
+		if r.Match == nil || !r.Match.MatchString(msg) {
+			continue
+		}
+		switch r.Action {
+		case RuleDrop:
+			return msg, level, false
+		case RuleLevel:
+			level = clampLevel(r.Level)
+		case RuleRewrite:
+			msg = r.Match.ReplaceAllString(msg, r.Rewrite)
+		}
+	}
+	return msg, level, true
+}
+
+// ParseRules parses rules, one per line, such as from a configuration
+// file. Empty lines and lines starting with '#' are skipped.
+//
+//	drop heartbeat (sent|received)
+//	level debug ^pipelining replication
+//	rewrite password=\S+ => password=***
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		rule, err := parseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRule(line string) (Rule, error) {
+	var rule Rule
+	action, expr := line, ""
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		action, expr = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	switch action {
+	case "drop":
+		rule.Action = RuleDrop
+	case "level":
+		rule.Action = RuleLevel
+		name := expr
+		expr = ""
+		if i := strings.IndexByte(name, ' '); i > 0 {
+			name, expr = name[:i], strings.TrimSpace(name[i+1:])
+		}
+		level, err := parseLevel(name)
+		if err != nil {
+			return rule, err
+		}
+		rule.Level = level
+	case "rewrite":
+		rule.Action = RuleRewrite
+		i := strings.LastIndex(expr, " => ")
+		if i == -1 {
+			return rule, fmt.Errorf("missing \" => \" in %q", line)
+		}
+		expr, rule.Rewrite = expr[:i], expr[i+4:]
+	default:
+		return rule, fmt.Errorf("invalid action %q", action)
+	}
+	if expr == "" {
+		return rule, fmt.Errorf("missing expression in %q", line)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return rule, err
+	}
+	rule.Match = re
+	return rule, nil
+}
+
+// formatRules formats rules as ParseRules parses them, on one line.
+func formatRules(rules []Rule) string {
+	parts := make([]string, len(rules))
+	for i, r := range rules {
+		var expr string
+		if r.Match != nil {
+			expr = r.Match.String()
+		}
+		switch r.Action {
+		case RuleDrop:
+			parts[i] = "drop " + expr
+		case RuleLevel:
+			parts[i] = fmt.Sprintf("level %s %s", Level(r.Level), expr)
+		default:
+			parts[i] = fmt.Sprintf("rewrite %s => %s", expr, r.Rewrite)
+		}
+	}
+	return "[" + strings.Join(parts, "; ") + "]"
+}
@@ -0,0 +1,59 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	rules, err := ParseRules(`
+		# raft noise
+		drop heartbeat (sent|received)
+		level debug ^pipelining replication
+		rewrite password=\S+ => password=***
+		rewrite ^user (\w+) => user=$1
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice, Format: FormatPlain,
+		Rules: rules})
+	l.Warningf("heartbeat sent to %s", "node-2")
+	l.Warningf("pipelining replication to node-2")
+	l.Warningf("login password=hunter2")
+	l.Noticef("user bob logged in")
+	exp := "warning: login password=***\n" + "user=bob logged in\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+	if l.Stats().Suppressed != 1 {
+		t.Fatalf("unexpected stats %+v", l.Stats())
+	}
+
+	// runtime changes
+	buf.Reset()
+	l.applyConfig(&Options{Level: LevelNotice, Format: FormatPlain,
+		Rules: []Rule{}})
+	l.Warningf("heartbeat sent")
+	out := buf.String()
+	if !strings.HasPrefix(out, "config: rules=[] (was [drop heartbeat "+
+		"(sent|received); level debug ^pipelining replication; ") ||
+		!strings.HasSuffix(out, ")\nwarning: heartbeat sent\n") ||
+		l.Rules() != nil {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	for _, s := range []string{
+		"ignore x", "drop", "level loud x", "level debug",
+		"rewrite x", "drop (",
+	} {
+		if _, err := ParseRules("drop ok\n" + s); err == nil ||
+			!strings.HasPrefix(err.Error(), "line 2: ") {
+			t.Fatalf("expected an error for %q, got %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package redlog
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// sampler decides which lines of a sampled logger are logged. The first
+// line is always logged.
+type sampler struct {
+	n         uint64 // lines seen, atomic, first for its alignment
+	every     uint64 // keep every nth line, or zero
+	threshold uint64 // keep a line when its random number is below
+	seed      uint64
+	limit     *limiter // see RateLimited
+}
+
+// Sampled returns a logger that logs about the fraction rate of its lines,
+// such as 0.01 for 1%, chosen at random. The first line is always logged.
+// Lines are dropped before they are formatted, and they are counted in
+// Stats as Suppressed.
+func (l *Logger) Sampled(rate float64) *Logger {
+	s := &sampler{seed: uint64(l.clock().UnixNano())}
+	switch {
+	case rate >= 1:
+		s.threshold = math.MaxUint64
+	case rate > 0:
+		s.threshold = uint64(math.Ldexp(rate, 64))
+	}
+	return l.withSampler(s)
+}
+
+// SampledEvery returns a logger that logs every nth line, starting with the
+// first one. Lines are dropped before they are formatted, and they are
+// counted in Stats as Suppressed.
+func (l *Logger) SampledEvery(n int) *Logger {
+	if n < 1 {
+		n = 1
+	}
+	return l.withSampler(&sampler{every: uint64(n)})
+}
+
+func (l *Logger) withSampler(s *sampler) *Logger {
+	child := l.clone()
+	child.sample = s
+	return child
+}
+
+// sampled returns true if the next line of the logger at level is logged.
+// The key is the message for the limits of RateLimit.PerMessage, or empty.
+func (l *Logger) sampled(level int, key string) bool {
+	if level < l.minLevel() {
+		// only for the crash ring, which is neither sampled nor limited
+		return true
+	}
+	keep := true
+	if s := l.sample; s != nil {
+		keep = s.keep(l.clock, key)
+	}
+	if keep && l.limit != nil {
+		keep = l.limit.allow(l.clock(), key)
+	}
+	if !keep {
+		atomic.AddUint64(&l.suppressed, 1)
+	}
+	return keep
+}
+
+// keep returns true if the next line is logged.
+func (s *sampler) keep(clock func() time.Time, key string) bool {
+	if s.limit != nil {
+		return s.limit.allow(clock(), key)
+	}
+	n := atomic.AddUint64(&s.n, 1) - 1
+	if s.every > 0 {
+		return n%s.every == 0
+	}
+	return n == 0 || splitmix64(s.seed+n) < s.threshold
+}
+
+// splitmix64 returns a well mixed random number for x.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// messageKey returns the message key of the arguments of a line, which is
+// the first argument when it is a string.
+func messageKey(args []interface{}) string {
+	if len(args) > 0 {
+		if s, ok := args[0].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSampledEvery(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Level: LevelNotice})
+	sl := l.SampledEvery(10)
+	for i := 0; i < 100; i++ {
+		sl.Noticef("line %d", i)
+		sl.Debugf("disabled")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 10 || !strings.HasSuffix(lines[0], " * line 0") ||
+		!strings.HasSuffix(lines[9], " * line 90") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	if st := l.Stats(); st.Suppressed != 90 || st.Notice != 10 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	// the parent is not sampled
+	buf.Reset()
+	l.Noticef("parent")
+	if !strings.Contains(buf.String(), "parent") {
+		t.Fatal("expected output")
+	}
+}
+
+func TestSampledEveryCrashRing(t *testing.T) {
+	// lines for the ring only are not counted
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice, Format: FormatPlain,
+		CrashRing: 10})
+	sl := l.SampledEvery(2)
+	for i := 0; i < 3; i++ {
+		sl.Noticef("line %d", i)
+		sl.Debugf("for the ring")
+	}
+	if buf.String() != "line 0\nline 2\n" || l.Stats().Suppressed != 1 {
+		t.Fatalf("unexpected output %q %+v", buf.String(), l.Stats())
+	}
+}
+
+func TestSampled(t *testing.T) {
+	for _, rate := range []float64{0.01, 0.1, 0.5} {
+		buf := &bytes.Buffer{}
+		l := New(buf, &Options{Level: LevelNotice})
+		sl := l.Sampled(rate)
+		const n = 100000
+		for i := 0; i < n; i++ {
+			sl.Noticef("line %d", i)
+		}
+		kept := float64(strings.Count(buf.String(), "\n"))
+		// well within 5 standard deviations
+		if exp := rate * n; kept < exp*0.9 || kept > exp*1.1 {
+			t.Fatalf("rate %v: expected about %v lines, got %v", rate, exp,
+				kept)
+		}
+		if st := l.Stats(); st.Suppressed+uint64(kept) != n {
+			t.Fatalf("unexpected stats %+v", st)
+		}
+	}
+	// the first line is always logged
+	for i := 0; i < 10; i++ {
+		buf := &bytes.Buffer{}
+		New(buf, nil).Sampled(0.000001).Noticef("first")
+		if !strings.Contains(buf.String(), "first") {
+			t.Fatal("expected the first line")
+		}
+	}
+	buf := &bytes.Buffer{}
+	sl := New(buf, nil).Sampled(1).WithModule("m").WithLabel("x")
+	for i := 0; i < 10; i++ {
+		sl.Noticef("all")
+	}
+	if strings.Count(buf.String(), "all") != 10 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestDebugSampleEvery(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelDebug, DebugSampleEvery: 3,
+		CrashRing: 32})
+	for i := 0; i < 9; i++ {
+		l.Debugf("debug %d", i)
+		l.Noticef("notice %d", i)
+	}
+	out := buf.String()
+	if strings.Count(out, "debug") != 3 || strings.Count(out, "notice") != 9 ||
+		!strings.Contains(out, "debug 3\n") || l.Stats().Suppressed != 6 {
+		t.Fatalf("unexpected output %q", out)
+	}
+	var dump bytes.Buffer
+	l.DumpRing(&dump)
+	if strings.Count(dump.String(), "debug") != 9 {
+		t.Fatalf("unexpected dump %q", dump.String())
+	}
+}
@@ -0,0 +1,62 @@
+package redlog
+
+import "unicode/utf8"
+
+// Sanitize modes, for messages with control characters
+const (
+	SanitizeNone    = 0 // write messages as they are
+	SanitizeControl = 1 // escape control characters, such as "\x1b"
+	SanitizeUTF8    = 2 // also escape bytes that are not valid UTF-8
+)
+
+// sanitize escapes the characters of line[from:] that could fake a line or
+// control a terminal, as set by the Sanitize option. Tabs are kept, and
+// newlines are kept when the Multiline option handles them.
+func (l *Logger) sanitize(line []byte, from int) []byte {
+	if l.sanitizeMode == SanitizeNone {
+		return line
+	}
+	i := from
+	for i < len(line) {
+		r, size := utf8.DecodeRune(line[i:])
+		if l.escaped(r, size) {
+			break
+		}
+		i += size
+	}
+	if i == len(line) {
+		return line
+	}
+	tail := append([]byte(nil), line[i:]...)
+	line = line[:i]
+	for len(tail) > 0 {
+		r, size := utf8.DecodeRune(tail)
+		switch {
+		case !l.escaped(r, size):
+			line = append(line, tail[:size]...)
+		case r >= 0x80 && r < 0xa0:
+			line = append(line, '\\', 'u', '0', '0', hexDigits[r>>4],
+				hexDigits[r&15])
+		default:
+			line = append(line, '\\', 'x', hexDigits[tail[0]>>4],
+				hexDigits[tail[0]&15])
+		}
+		tail = tail[size:]
+	}
+	return line
+}
+
+const hexDigits = "0123456789abcdef"
+
+// escaped reports whether the decoded rune is escaped by sanitize.
+func (l *Logger) escaped(r rune, size int) bool {
+	switch {
+	case r == utf8.RuneError && size == 1:
+		return l.sanitizeMode == SanitizeUTF8
+	case r == '\t':
+		return false
+	case r == '\n':
+		return l.multiline == MultilineKeep
+	}
+	return r < 0x20 || r >= 0x7f && r < 0xa0
+}
@@ -0,0 +1,28 @@
+package redlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Format: FormatPlain, Sanitize: SanitizeControl})
+	l.Noticef("a\tb\nc\r\x1b[31m\x7f\u009b\xff")
+	l.With("k\x1b", "v\x00").Noticef("")
+	l.Noticef("café")
+	exp := "a\tb\\x0ac\\x0d\\x1b[31m\\x7f\\u009b\xff\n" +
+		"k\\x1b=v\\x00\n" +
+		"café\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+
+	buf.Reset()
+	l.sanitizeMode = SanitizeUTF8
+	l.multiline = MultilineIndent
+	l.Noticef("a\n\xffb\xc3")
+	if exp := "a\n\\xffb\\xc3\n"; buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
@@ -0,0 +1,103 @@
+//go:build go1.21
+// +build go1.21
+
+package redlog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler is a slog.Handler that logs to a Logger. The attributes are
+// appended to the message as " key=value", with the keys of groups joined
+// by dots, and are also passed to the Encoder and Formatter options. The
+// time of a record is ignored in favor of the clock of the logger.
+type SlogHandler struct {
+	l      *Logger
+	prefix string        // group of the attributes that follow
+	kvs    []interface{} // attributes from WithAttrs
+}
+
+// NewSlogHandler returns a handler that logs to l, such as with
+// slog.New(redlog.NewSlogHandler(l)).
+func NewSlogHandler(l *Logger) *SlogHandler {
+	return &SlogHandler{l: l}
+}
+
+// slogLevel maps a slog level to a level of the logger. Levels below
+// slog.LevelInfo and above slog.LevelDebug are logged as verbose.
+func slogLevel(level slog.Level) int {
+	switch {
+	case level <= slog.LevelDebug:
+		return LevelDebug
+	case level < slog.LevelInfo:
+		return LevelVerbose
+	case level < slog.LevelWarn:
+		return LevelNotice
+	case level < slog.LevelError:
+		return LevelWarning
+	}
+	return levelError
+}
+
+// Enabled reports whether the logger logs the level.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.l.enabled(slogLevel(level))
+}
+
+// Handle logs the record.
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	level := slogLevel(r.Level)
+	if !h.l.enabled(level) || !h.l.sampled(level, r.Message) {
+		return nil
+	}
+	kvs := h.kvs
+	if r.NumAttrs() > 0 {
+		kvs = append(make([]interface{}, 0, len(kvs)+r.NumAttrs()*2), kvs...)
+		r.Attrs(func(a slog.Attr) bool {
+			kvs = appendAttr(kvs, h.prefix, a)
+			return true
+		})
+	}
+	writeKVs(h.l, level, r.Message, kvs)
+	return nil
+}
+
+// WithAttrs returns a handler that adds the attributes to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.kvs = append([]interface{}(nil), h.kvs...)
+	for _, a := range attrs {
+		h2.kvs = appendAttr(h2.kvs, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a handler that puts the attributes that follow in the
+// group.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// appendAttr appends an attribute as key value pairs, flattening groups.
+func appendAttr(kvs []interface{}, prefix string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			kvs = appendAttr(kvs, prefix, ga)
+		}
+		return kvs
+	}
+	return append(kvs, prefix+a.Key, a.Value.Any())
+}
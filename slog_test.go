@@ -0,0 +1,54 @@
+//go:build go1.21
+// +build go1.21
+
+package redlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	l, lines := capture(&Options{Level: LevelVerbose})
+	log := slog.New(NewSlogHandler(l))
+	log.Debug("hidden")
+	log.Log(context.Background(), slog.LevelDebug+1, "verbose")
+	log.Info("started", "port", 6379, "name", "node 1")
+	log.Warn("slow", slog.Group("req", "id", 7, slog.Group("", "inline", 1)),
+		slog.Group("empty"))
+	log.With("conn", 3).WithGroup("tx").With("id", 9).Error("failed",
+		"err", "eof")
+	log.WithGroup("").Info("ungrouped", "", nil)
+
+	got := lines()
+	want := []string{
+		`- verbose`,
+		`* started port=6379 name="node 1"`,
+		`# slow req.id=7 req.inline=1`,
+		`# failed conn=3 tx.id=9 tx.err=eof`,
+		`* ungrouped`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output\n%s", strings.Join(got, "\n"))
+	}
+	if l.Stats().Error != 1 {
+		t.Fatalf("expected one error, got %+v", l.Stats())
+	}
+
+	// the pairs are passed to the Formatter
+	var buf bytes.Buffer
+	var recs []Record
+	l = New(&buf, &Options{Level: LevelNotice,
+		Formatter: func(dst []byte, rec Record, tty bool) []byte {
+			recs = append(recs, rec)
+			return DefaultFormatter(dst, rec, tty)
+		}})
+	slog.New(NewSlogHandler(l)).Info("hello", "a", 1)
+	if len(recs) != 1 || recs[0].Msg != "hello" || len(recs[0].KVs) != 2 ||
+		!strings.HasSuffix(buf.String(), " * hello a=1\n") {
+		t.Fatalf("unexpected records %+v %q", recs, buf.String())
+	}
+}
@@ -0,0 +1,106 @@
+package redlog
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// pkgPath is the import path of this package, used for pruning its own
+// frames from stack traces.
+var pkgPath = reflect.TypeOf(Logger{}).PkgPath()
+
+// stackTrace returns the program counters of the first error in args that
+// carries a stack trace, such as the errors from github.com/pkg/errors, or
+// the stack of the log call otherwise.
+func stackTrace(args []interface{}) []uintptr {
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			if pcs := errorStack(err); pcs != nil {
+				return pcs
+			}
+		}
+	}
+	pcs := make([]uintptr, 64)
+	return pcs[:runtime.Callers(2, pcs)]
+}
+
+// errorStack returns the stack trace of err or of an error that it wraps.
+// An error has a stack trace when it has a StackTrace method returning a
+// slice of program counters, like the errors from github.com/pkg/errors.
+func errorStack(err error) []uintptr {
+	for ; err != nil; err = errors.Unwrap(err) {
+		m := reflect.ValueOf(err).MethodByName("StackTrace")
+		if !m.IsValid() || m.Type().NumIn() != 0 ||
+			m.Type().NumOut() != 1 {
+			continue
+		}
+		t := m.Type().Out(0)
+		if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Uintptr {
+			continue
+		}
+		v := m.Call(nil)[0]
+		pcs := make([]uintptr, v.Len())
+		for i := range pcs {
+			pcs[i] = uintptr(v.Index(i).Uint())
+		}
+		return pcs
+	}
+	return nil
+}
+
+// callerLocation returns the file and line of the first frame of the
+// caller that is not of this package, such as "server.go:42".
+func callerLocation() string {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for more := true; more; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+		if !isOwnFrame(frame) {
+			return filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+		}
+	}
+	return ""
+}
+
+// formatStack formats pcs as indented continuation lines. Leading frames
+// from this package are pruned.
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	leading := true
+	for more := true; more; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+		if leading && isOwnFrame(frame) {
+			continue
+		}
+		leading = false
+		if frame.Function != "" {
+			b.WriteString("\n    ")
+			b.WriteString(frame.Function)
+			b.WriteString("()\n        ")
+			b.WriteString(frame.File)
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(frame.Line))
+		}
+	}
+	return b.String()
+}
+
+// isOwnFrame returns true for frames of this package, or of the runtime,
+// but not for frames of the package tests.
+func isOwnFrame(frame runtime.Frame) bool {
+	if strings.HasPrefix(frame.Function, "runtime.") {
+		return true
+	}
+	if !strings.HasPrefix(frame.Function, pkgPath+".") &&
+		!strings.HasPrefix(frame.Function, pkgPath+"/") {
+		return false
+	}
+	return !strings.HasSuffix(frame.File, "_test.go")
+}
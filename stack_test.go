@@ -0,0 +1,156 @@
+package redlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testFrame uintptr
+
+type testStackTrace []testFrame
+
+// testStackErr mimics the errors from github.com/pkg/errors.
+type testStackErr struct {
+	msg   string
+	stack []uintptr
+}
+
+func (e *testStackErr) Error() string { return e.msg }
+
+func (e *testStackErr) StackTrace() testStackTrace {
+	st := make(testStackTrace, len(e.stack))
+	for i, pc := range e.stack {
+		st[i] = testFrame(pc)
+	}
+	return st
+}
+
+func newTestStackErr(msg string) error {
+	pcs := make([]uintptr, 32)
+	return &testStackErr{msg, pcs[:runtime.Callers(1, pcs)]}
+}
+
+// stackLines returns the lines of the first logged message.
+func stackLines(t *testing.T, out string) []string {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 3 {
+		t.Fatalf("expected a stack trace, got %q", out)
+	}
+	return lines
+}
+
+func TestStackTrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{StackTraceLevel: LevelWarning})
+	l.WarningErr(errors.New("boom"))
+	lines := stackLines(t, buf.String())
+	if !strings.HasSuffix(lines[0], " # boom") {
+		t.Fatalf("unexpected message %q", lines[0])
+	}
+	if lines[1] != "    "+pkgPath+".TestStackTrace()" {
+		t.Fatalf("unexpected top frame %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "        ") ||
+		!strings.Contains(lines[2], "stack_test.go:") {
+		t.Fatalf("unexpected top file %q", lines[2])
+	}
+
+	buf.Reset()
+	l.Errorf("failed: %v", 1)
+	lines = stackLines(t, buf.String())
+	if lines[1] != "    "+pkgPath+".TestStackTrace()" {
+		t.Fatalf("unexpected top frame %q", lines[1])
+	}
+
+	buf.Reset()
+	l.Noticef("hello")
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Fatalf("expected no stack trace, got %q", buf.String())
+	}
+}
+
+func TestStackTraceFromError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{StackTraceLevel: LevelWarning})
+	err := fmt.Errorf("wrapped: %w", newTestStackErr("boom"))
+	l.ErrorErr(err)
+	lines := stackLines(t, buf.String())
+	if !strings.HasSuffix(lines[0], " # wrapped: boom") {
+		t.Fatalf("unexpected message %q", lines[0])
+	}
+	if lines[1] != "    "+pkgPath+".newTestStackErr()" {
+		t.Fatalf("unexpected top frame %q", lines[1])
+	}
+}
+
+func TestStackTraceDisabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, nil)
+	l.WarningErr(errors.New("boom"))
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Fatalf("expected no stack trace, got %q", buf.String())
+	}
+}
+
+func TestStackTraceNoticeCost(t *testing.T) {
+	plain := New(nopWriter{}, nil)
+	stack := New(nopWriter{}, &Options{
+		Level:           LevelNotice,
+		StackTraceLevel: LevelWarning,
+	})
+	// take the best of a few runs to avoid noise from the race detector
+	allocs := func(l *Logger) float64 {
+		best := -1.0
+		for i := 0; i < 3; i++ {
+			n := testing.AllocsPerRun(100, func() { l.Noticef("hello") })
+			if best < 0 || n < best {
+				best = n
+			}
+		}
+		return best
+	}
+	if a, b := allocs(plain), allocs(stack); b > a {
+		t.Fatalf("expected %v allocs, got %v", a, b)
+	}
+}
+
+func TestCaller(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice, Caller: true})
+	_, _, line, _ := runtime.Caller(0)
+	l.Noticef("direct")
+	l.With("shard", 3).Named("raft").Notice("wrapped")
+	NewGRPCLogger(l).Warning("adapter")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, want := range []string{
+		" * direct caller=stack_test.go:" + strconv.Itoa(line+1),
+		" * [raft] wrapped shard=3 caller=stack_test.go:" +
+			strconv.Itoa(line+2),
+		" # adapter caller=stack_test.go:" + strconv.Itoa(line+3),
+	} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Fatalf("unexpected line %q", lines[i])
+		}
+	}
+}
+
+func TestStackTraceFatal(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var code int
+	l := New(buf, &Options{StackTraceLevel: LevelWarning,
+		ExitFunc: func(c int) { code = c }})
+	l.Fatalf("cannot bind: %v", "in use")
+	lines := stackLines(t, buf.String())
+	if !strings.HasSuffix(lines[0], " # cannot bind: in use") || code != 1 {
+		t.Fatalf("unexpected message %q or code %d", lines[0], code)
+	}
+	if lines[1] != "    "+pkgPath+".TestStackTraceFatal()" {
+		t.Fatalf("unexpected top frame %q", lines[1])
+	}
+}
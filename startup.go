@@ -0,0 +1,91 @@
+package redlog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StartupInfo describes a program for LogStartup. Empty fields are left
+// out of the line.
+type StartupInfo struct {
+	AppName string
+	Version string
+	GitSHA  string
+	Mode    string
+	Port    int
+}
+
+// LogStartup logs a notice describing the program, such as:
+//
+//	myapp version=1.2.0, bits=64, commit=3f2a1b9, pid=1234, mode=cluster,
+//	port=6379, tz=+02:00, just started
+//
+// The tz field is the offset of the timestamps. Files opened with OpenFile
+// then start every rotated file with a "log file rotated" line followed by
+// this line.
+func (l *Logger) LogStartup(info StartupInfo) {
+	var b strings.Builder
+	if info.AppName != "" {
+		b.WriteString(info.AppName)
+		b.WriteByte(' ')
+	}
+	field := func(name, value string) {
+		if value != "" {
+			b.WriteString(name)
+			b.WriteByte('=')
+			b.WriteString(value)
+			b.WriteString(", ")
+		}
+	}
+	field("version", info.Version)
+	field("bits", strconv.Itoa(strconv.IntSize))
+	field("commit", info.GitSHA)
+	field("pid", strconv.Itoa(l.pid))
+	field("mode", info.Mode)
+	if info.Port != 0 {
+		field("port", strconv.Itoa(info.Port))
+	}
+	field("tz", l.now().Format("-07:00"))
+	b.WriteString("just started")
+	msg := b.String()
+	l.startup.Store(msg)
+	l.Notice(msg)
+}
+
+// rotationHeader returns the lines written at the top of a rotated file,
+// which are a marker and the startup line, once LogStartup was called. They
+// are in the format of the logger. It's called by the file writer, without
+// the lock of the logger.
+func (l *Logger) rotationHeader() []byte {
+	startup, _ := l.startup.Load().(string)
+	if startup == "" {
+		return nil
+	}
+	now := l.now()
+	b := l.appendHeaderLine(nil, now, "log file rotated")
+	return l.appendHeaderLine(b, now, startup)
+}
+
+// appendHeaderLine appends a notice in the text format, or with the Encoder
+// or Formatter of the logger.
+func (l *Logger) appendHeaderLine(dst []byte, now time.Time,
+	msg string) []byte {
+	rec := Record{Pid: l.pid, App: l.App(), Time: now, Level: LevelNotice,
+		Msg: msg}
+	if enc := l.enc(); enc != nil {
+		b := (*buffer)(&dst)
+		if err := enc.Encode(b, rec); err != nil {
+			return dst
+		}
+		return *b
+	}
+	if l.fmtr != nil {
+		rec.TimeFormat = l.TimeFormat()
+		return append(l.fmtr(dst, rec, false), '\n')
+	}
+	dst, _, _ = appendPrefix(dst, l.pid, l.App(), nil, now, l.TimeFormat(),
+		LevelNotice)
+	dst = append(dst, msg...)
+	return append(dst, '\n')
+}
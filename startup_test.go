@@ -0,0 +1,106 @@
+package redlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogStartup(t *testing.T) {
+	buf := &bytes.Buffer{}
+	loc := time.FixedZone("", 2*60*60)
+	l := New(buf, &Options{
+		Level: LevelNotice,
+		Now:   func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, loc) },
+	})
+	l.pid = 1234
+	l.LogStartup(StartupInfo{AppName: "myapp", Version: "1.2.0",
+		GitSHA: "3f2a1b9", Mode: "cluster", Port: 6379})
+	want := "1234:M 01 Jun 2024 00:00:00.000 * myapp version=1.2.0, bits=" +
+		strconv.Itoa(strconv.IntSize) + ", commit=3f2a1b9, pid=1234, " +
+		"mode=cluster, port=6379, tz=+02:00, just started\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestLogStartupRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &testClock{t: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	l, err := OpenFile(path, &Options{
+		Level:   LevelNotice,
+		Now:     clock.Now,
+		UTC:     true,
+		MaxSize: 200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.LogStartup(StartupInfo{AppName: "myapp", Version: "1.2.0"})
+	for i := 0; i < 20; i++ {
+		clock.Set(clock.Now().Add(time.Second))
+		l.Noticef("line %d", i)
+	}
+	names := listDir(t, dir)
+	if len(names) < 4 {
+		t.Fatalf("expected several rotations, got %v", names)
+	}
+	var lines int
+	for i, name := range names {
+		data := readFile(t, filepath.Join(dir, name))
+		if n := strings.Count(data, "just started"); n != 1 {
+			t.Fatalf("%s: expected one startup line, got %d in %q", name,
+				n, data)
+		}
+		// the oldest backup is the only file without a marker
+		first := strings.SplitN(data, "\n", 2)[0]
+		if i == 0 && !strings.Contains(first, "just started") ||
+			i > 0 && !strings.HasSuffix(first, " * log file rotated") {
+			t.Fatalf("%s: unexpected first line %q", name, first)
+		}
+		if !strings.Contains(data, "tz=+00:00") {
+			t.Fatalf("%s: missing timezone in %q", name, data)
+		}
+		lines += strings.Count(data, " * line ")
+	}
+	if lines != 20 {
+		t.Fatalf("expected 20 lines, got %d", lines)
+	}
+}
+
+func TestLogStartupRotationJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	l, err := OpenFile(path, &Options{Level: LevelNotice, Format: FormatJSON,
+		MaxSize: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.LogStartup(StartupInfo{AppName: "myapp"})
+	for i := 0; i < 20; i++ {
+		l.Noticef("line %d", i)
+	}
+	l.Close()
+	names := listDir(t, dir)
+	if len(names) < 3 {
+		t.Fatalf("expected several rotations, got %v", names)
+	}
+	for _, name := range names {
+		data := readFile(t, filepath.Join(dir, name))
+		for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+			if !json.Valid([]byte(line)) {
+				t.Fatalf("%s: invalid line %q", name, line)
+			}
+		}
+		if !strings.Contains(data, "just started") {
+			t.Fatalf("%s: missing startup line in %q", name, data)
+		}
+	}
+}
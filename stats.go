@@ -0,0 +1,57 @@
+package redlog
+
+import "sync/atomic"
+
+// Stats are the counters of a logger.
+type Stats struct {
+	// Lines written per level
+	Trace   uint64
+	Debug   uint64
+	Verbose uint64
+	Notice  uint64
+	Warning uint64
+	Error   uint64
+	// Dropped is the number of lines that failed to write
+	Dropped uint64
+	// Writes is the number of calls to the writer passed to New, which
+	// wrote WrittenLines lines. See MaxBatchBytes.
+	Writes       uint64
+	WrittenLines uint64
+	// Failovers and Recoveries count the switches to the Fallback writer
+	// and back.
+	Failovers  uint64
+	Recoveries uint64
+	// Suppressed is the number of lines dropped by sampled loggers, rate
+	// limits, CollapseRepeats, and Rules
+	Suppressed uint64
+}
+
+// LinesPerWrite returns the average number of lines in a call to the
+// writer passed to New.
+func (s Stats) LinesPerWrite() float64 {
+	if s.Writes == 0 {
+		return 0
+	}
+	return float64(s.WrittenLines) / float64(s.Writes)
+}
+
+// Stats returns the counters of the logger.
+func (l *Logger) Stats() Stats {
+	count := func(level int) uint64 {
+		return atomic.LoadUint64(&l.counts[level-LevelTrace])
+	}
+	return Stats{
+		Trace:        count(LevelTrace),
+		Debug:        count(LevelDebug),
+		Verbose:      count(LevelVerbose),
+		Notice:       count(LevelNotice),
+		Warning:      count(LevelWarning),
+		Error:        count(levelError),
+		Dropped:      atomic.LoadUint64(&l.dropped),
+		Writes:       atomic.LoadUint64(&l.writes),
+		WrittenLines: atomic.LoadUint64(&l.written),
+		Failovers:    atomic.LoadUint64(&l.failovers),
+		Recoveries:   atomic.LoadUint64(&l.recoveries),
+		Suppressed:   atomic.LoadUint64(&l.suppressed),
+	}
+}
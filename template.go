@@ -0,0 +1,79 @@
+package redlog
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Tokens of the Template option.
+const (
+	tokenPid = iota
+	tokenRole
+	tokenTime
+	tokenLevel
+	tokenMsg
+	tokenText // literal text
+)
+
+var templateTokens = map[string]int{"{pid}": tokenPid, "{role}": tokenRole,
+	"{time}": tokenTime, "{level}": tokenLevel, "{msg}": tokenMsg}
+
+type templatePart struct {
+	token int
+	text  string
+}
+
+// templateFormatter returns a Formatter for the Template option, with
+// timestamps in the current layout. Unknown tokens are kept as text.
+func templateFormatter(tmpl string, layout func() string) Formatter {
+	var parts []templatePart
+	for tmpl != "" {
+		text := tmpl
+		if i := strings.IndexByte(tmpl, '{'); i > 0 {
+			text = tmpl[:i]
+		} else if i == 0 {
+			text = tmpl[:1]
+			if j := strings.IndexByte(tmpl, '}'); j != -1 {
+				if token, ok := templateTokens[tmpl[:j+1]]; ok {
+					parts = append(parts, templatePart{token: token})
+					tmpl = tmpl[j+1:]
+					continue
+				}
+			}
+		}
+		if n := len(parts); n > 0 && parts[n-1].token == tokenText {
+			parts[n-1].text += text
+		} else {
+			parts = append(parts, templatePart{tokenText, text})
+		}
+		tmpl = tmpl[len(text):]
+	}
+	return func(dst []byte, rec Record, tty bool) []byte {
+		for _, p := range parts {
+			switch p.token {
+			case tokenPid:
+				dst = strconv.AppendInt(dst, int64(rec.Pid), 10)
+			case tokenRole:
+				dst = append(dst, rec.App)
+			case tokenTime:
+				dst = rec.Time.AppendFormat(dst, layout())
+			case tokenLevel:
+				idx := clampLevel(rec.Level) - LevelTrace
+				if tty && levelColors[idx] != "" {
+					dst = append(dst, "\x1b["...)
+					dst = append(dst, levelColors[idx]...)
+					dst = append(dst, 'm', levelChars[idx])
+					dst = append(dst, "\x1b[0m"...)
+				} else {
+					dst = append(dst, levelChars[idx])
+				}
+			case tokenMsg:
+				dst = append(dst, rec.Msg...)
+				dst = appendKVs(dst, rec.KVs)
+			default:
+				dst = append(dst, p.text...)
+			}
+		}
+		return dst
+	}
+}
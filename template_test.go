@@ -0,0 +1,32 @@
+package redlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelNotice, TimeFormat: "15:04:05",
+		Template: "{time} [{role}/{pid}] {level} {msg} {unknown} {",
+		Now: func() time.Time {
+			return time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+		}})
+	l.pid = 1234
+	l.Noticef("hello")
+	l.Timed(LevelWarning, "loading")()
+	want := "15:04:05 [M/1234] * hello {unknown} {\n" +
+		"15:04:05 [M/1234] # loading done in 0s duration=0s {unknown} {\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	buf.Reset()
+	l = New(&buf, &Options{Level: LevelNotice, Template: "{level}{msg}",
+		Color: ColorAlways})
+	l.Warningf("colored")
+	if buf.String() != "\x1b[33m#\x1b[0mcolored\n" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
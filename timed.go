@@ -0,0 +1,28 @@
+package redlog
+
+// Timed logs "msg started" at the debug level, and returns a func that
+// logs "msg done in 12.3ms" at level. The duration is measured with the
+// clock of the logger, and is also added as the "duration" key for the
+// Encoder and Formatter options. The two lines are separate messages for
+// RateLimit.PerMessage. Sections may be nested, and the func may be
+// deferred to measure a section that panics:
+//
+//	defer l.Timed(LevelNotice, "loading snapshot")()
+func (l *Logger) Timed(level int, msg string) func() {
+	if level < LevelTrace || level > LevelWarning {
+		panic("invalid level")
+	}
+	start := l.now()
+	if l.enabled(LevelDebug) && l.sampled(LevelDebug, msg+" started") {
+		write(false, l, l.App(), LevelDebug, "",
+			[]interface{}{msg + " started"}, nil)
+	}
+	return func() {
+		d := l.now().Sub(start)
+		if l.enabled(level) && l.sampled(level, msg) {
+			write(false, l, l.App(), level, "",
+				[]interface{}{msg + " done in " + d.String()},
+				[]interface{}{"duration", d})
+		}
+	}
+}
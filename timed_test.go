@@ -0,0 +1,69 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	l := New(buf, &Options{
+		Level: LevelDebug,
+		Now:   func() time.Time { return now },
+		Formatter: func(dst []byte, rec Record, tty bool) []byte {
+			dst = append(dst, levelChars[rec.Level-LevelTrace], ' ')
+			dst = append(dst, rec.Msg...)
+			return appendKVs(dst, rec.KVs)
+		},
+	})
+	outer := l.Timed(LevelNotice, "loading")
+	now = now.Add(2 * time.Millisecond)
+	inner := l.Timed(LevelVerbose, "reading snapshot")
+	now = now.Add(12300 * time.Microsecond)
+	inner()
+	func() {
+		defer func() { recover() }()
+		defer l.Timed(LevelWarning, "replaying")()
+		now = now.Add(time.Second)
+		panic("oops")
+	}()
+	outer()
+	exp := ". loading started\n" +
+		". reading snapshot started\n" +
+		"- reading snapshot done in 12.3ms duration=12.3ms\n" +
+		". replaying started\n" +
+		"# replaying done in 1s duration=1s\n" +
+		"* loading done in 1.0143s duration=1.0143s\n"
+	if buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+
+	// the text format has no key value pairs, and debug is disabled
+	buf.Reset()
+	l = New(buf, &Options{Level: LevelNotice,
+		Now: func() time.Time { return now }})
+	done := l.Timed(LevelNotice, "loading")
+	now = now.Add(time.Minute)
+	done()
+	if lines := strings.Split(buf.String(), "\n"); len(lines) != 2 ||
+		!strings.HasSuffix(lines[0], " * loading done in 1m0s") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestTimedRateLimit(t *testing.T) {
+	// the done line does not share the token of the started line
+	var buf bytes.Buffer
+	l := New(&buf, &Options{Level: LevelDebug, Format: FormatPlain,
+		Now:       func() time.Time { return time.Time{} },
+		RateLimit: RateLimit{Every: time.Hour, Burst: 1, PerMessage: true}})
+	for i := 0; i < 2; i++ {
+		l.Timed(LevelNotice, "loading")()
+	}
+	if exp := "loading started\nloading done in 0s\n"; buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+}
@@ -0,0 +1,95 @@
+package redlog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// errWriteTimeout is returned for writes that were abandoned.
+var errWriteTimeout = errors.New("write timeout")
+
+// stderr is where problems of the logger itself are reported, such as
+// write timeouts.
+var stderr io.Writer = os.Stderr
+
+// timeoutWriter abandons writes that take longer than the timeout. The
+// writes are done by a single watchdog goroutine, and while a write is
+// stuck all following writes are dropped.
+type timeoutWriter struct {
+	wr      io.Writer
+	timeout time.Duration
+
+	mu     sync.Mutex
+	reqs   chan []byte
+	done   chan error
+	timer  *time.Timer
+	buf    []byte
+	busy   bool // a write has not completed
+	warned bool
+}
+
+func newTimeoutWriter(wr io.Writer, timeout time.Duration) *timeoutWriter {
+	w := &timeoutWriter{
+		wr:      wr,
+		timeout: timeout,
+		reqs:    make(chan []byte),
+		done:    make(chan error, 1),
+		timer:   time.NewTimer(time.Hour),
+	}
+	w.timer.Stop()
+	go w.run()
+	return w
+}
+
+func (w *timeoutWriter) run() {
+	for p := range w.reqs {
+		_, err := w.wr.Write(p)
+		w.done <- err
+	}
+}
+
+// close stops the watchdog. A write that is stuck is left behind.
+func (w *timeoutWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	close(w.reqs)
+}
+
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.busy {
+		select {
+		case <-w.done:
+			w.busy = false
+		default:
+			return 0, errWriteTimeout
+		}
+	}
+	// the watchdog is idle, so the buffer can be reused
+	w.buf = append(w.buf[:0], p...)
+	w.reqs <- w.buf
+	w.timer.Reset(w.timeout)
+	select {
+	case err := <-w.done:
+		if !w.timer.Stop() {
+			<-w.timer.C
+		}
+		if err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	case <-w.timer.C:
+		w.busy = true
+		if !w.warned {
+			w.warned = true
+			fmt.Fprintf(stderr, "redlog: write timed out after %s, "+
+				"dropping lines\n", w.timeout)
+		}
+		return 0, errWriteTimeout
+	}
+}
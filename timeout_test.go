@@ -0,0 +1,57 @@
+package redlog
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTimeout(t *testing.T) {
+	warn := &bytes.Buffer{}
+	stderr = warn
+	defer func() { stderr = os.Stderr }()
+
+	// nothing reads from the pipe until the end of the test
+	r, w := io.Pipe()
+	l := New(w, &Options{WriteTimeout: 50 * time.Millisecond})
+	defer l.Close()
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		l.Noticef("hello %d", i)
+		if d := time.Since(start); d > time.Second {
+			t.Fatalf("Noticef took %s", d)
+		}
+	}
+	if st := l.Stats(); st.Dropped != 3 {
+		t.Fatalf("expected 3 dropped, got %d", st.Dropped)
+	}
+	if n := strings.Count(warn.String(), "\n"); n != 1 ||
+		!strings.Contains(warn.String(), "write timed out") {
+		t.Fatalf("expected a single warning, got %q", warn.String())
+	}
+
+	// the stuck write completes, and later lines are written again
+	out := &bytes.Buffer{}
+	done := make(chan bool)
+	go func() {
+		io.Copy(out, r)
+		done <- true
+	}()
+	for len(l.tw.done) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	l.Noticef("hello again")
+	w.Close()
+	<-done
+	if st := l.Stats(); st.Dropped != 3 {
+		t.Fatalf("expected 3 dropped, got %d", st.Dropped)
+	}
+	if lines := strings.Split(out.String(), "\n"); len(lines) != 3 ||
+		!strings.HasSuffix(lines[0], " * hello 0") ||
+		!strings.HasSuffix(lines[1], " * hello again") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
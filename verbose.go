@@ -0,0 +1,77 @@
+package redlog
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// VerboseLogger logs messages of a verbosity at debug level. See V.
+type VerboseLogger struct {
+	l *Logger // nil when disabled
+	v int
+}
+
+// V returns a logger for messages of verbosity v, which are logged at
+// debug level when v is at most the verbosity of the logger. Otherwise
+// the messages are discarded without being formatted.
+func (l *Logger) V(v int) VerboseLogger {
+	if v > int(atomic.LoadInt64(&l.verbosity)) || !l.enabled(LevelDebug) {
+		return VerboseLogger{}
+	}
+	return VerboseLogger{l, v}
+}
+
+// SetVerbosity sets the verbosity used by V.
+func (l *Logger) SetVerbosity(v int) {
+	atomic.StoreInt64(&l.verbosity, int64(v))
+}
+
+// Verbosity returns the verbosity used by V.
+func (l *Logger) Verbosity() int {
+	return int(atomic.LoadInt64(&l.verbosity))
+}
+
+// Enabled returns true if the messages are logged.
+func (vl VerboseLogger) Enabled() bool {
+	return vl.l != nil
+}
+
+// Infof logs a formatted message at debug level.
+func (vl VerboseLogger) Infof(format string, args ...interface{}) {
+	if vl.l == nil {
+		return
+	}
+	if vl.l.vsuffix {
+		format += vl.suffix()
+	}
+	vl.l.writef(LevelDebug, format, args)
+}
+
+// Info logs a message at debug level.
+func (vl VerboseLogger) Info(args ...interface{}) {
+	if vl.l == nil {
+		return
+	}
+	if vl.l.vsuffix {
+		args = []interface{}{fmt.Sprint(args...) + vl.suffix()}
+	}
+	vl.l.write(LevelDebug, args)
+}
+
+// Infoln logs a message at debug level, with spaces between the args as
+// with fmt.Println, like the Infoln of glog.
+func (vl VerboseLogger) Infoln(args ...interface{}) {
+	if vl.l == nil {
+		return
+	}
+	msg := sprintln(args)
+	if vl.l.vsuffix {
+		msg += vl.suffix()
+	}
+	vl.l.write(LevelDebug, []interface{}{msg})
+}
+
+func (vl VerboseLogger) suffix() string {
+	return " (v" + strconv.Itoa(vl.v) + ")"
+}
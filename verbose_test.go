@@ -0,0 +1,77 @@
+package redlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestVerbosity(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Level: LevelDebug, Verbosity: 2})
+	for v := 0; v <= 4; v++ {
+		l.V(v).Infof("infof %d", v)
+		l.V(v).Info("info ", v)
+	}
+	want := []string{" . infof 0", " . info 0", " . infof 1", " . info 1",
+		" . infof 2", " . info 2"}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Fatalf("expected suffix %q, got %q", want[i], lines[i])
+		}
+	}
+	if !l.V(2).Enabled() || l.V(3).Enabled() {
+		t.Fatal("unexpected enabled state")
+	}
+
+	// changed at runtime
+	buf.Reset()
+	l.SetVerbosity(5)
+	l.V(5).Infof("five")
+	l.V(6).Infof("six")
+	if l.Verbosity() != 5 || strings.Count(buf.String(), "\n") != 1 ||
+		!strings.HasSuffix(buf.String(), " . five\n") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	// debug level disabled
+	buf.Reset()
+	l.SetLevel(LevelNotice)
+	l.V(0).Infof("zero")
+	if buf.Len() != 0 || l.V(0).Enabled() {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestVerbositySuffix(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{
+		Level:           LevelDebug,
+		Verbosity:       5,
+		VerbositySuffix: true,
+	})
+	l.V(5).Infof("hello %s", "world")
+	l.V(3).Info("hello", 1)
+	if out := buf.String(); !strings.Contains(out, " . hello world (v5)\n") ||
+		!strings.HasSuffix(out, " . hello1 (v3)\n") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestVerboseInfoln(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{Level: LevelDebug, Verbosity: 2})
+	l.V(2).Infoln("peers", 3)
+	l.V(4).Infoln("hidden")
+	l.SetVerbosity(4)
+	l.V(4).Infoln("shown")
+	if out := buf.String(); strings.Count(out, "\n") != 2 ||
+		!strings.Contains(out, " . peers 3\n") ||
+		!strings.HasSuffix(out, " . shown\n") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
@@ -0,0 +1,46 @@
+package redlog
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// syncFile is called after writes to the WarningFile with WarningSync.
+var syncFile = (*os.File).Sync
+
+// openWarningFile opens the WarningFile of the options. It's rotated by
+// size like the file of OpenFile, but independently of it.
+func (l *Logger) openWarningFile(opts *Options) {
+	w := &fileWriter{
+		path:       opts.WarningFile,
+		maxSize:    opts.MaxSize,
+		maxBackups: opts.MaxBackups,
+		maxAge:     opts.MaxAge,
+		compress:   opts.CompressBackups,
+		mode:       opts.FileMode,
+		dirMode:    opts.DirMode,
+		sync:       opts.WarningSync,
+		now:        l.now,
+		warn:       l.Warningf,
+	}
+	if w.mode == 0 {
+		w.mode = 0644
+	}
+	if w.dirMode == 0 {
+		w.dirMode = 0755
+	}
+	l.wf = w
+	l.owned = append(l.owned, w)
+}
+
+// writeWarning appends a line to the WarningFile. A failure is reported
+// once, and does not affect the other outputs. It's called with the mutex
+// held.
+func (l *Logger) writeWarning(p []byte) {
+	_, err := l.wf.Write(p)
+	if err != nil && atomic.CompareAndSwapUint32(&l.wfFailed, 0, 1) {
+		fmt.Fprintf(stderr, "redlog: writing %s failed: %v\n", l.wf.path,
+			err)
+	}
+}
@@ -0,0 +1,99 @@
+package redlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWarningFile(t *testing.T) {
+	var syncs int
+	syncFile = func(f *os.File) error {
+		syncs++
+		return f.Sync()
+	}
+	defer func() { syncFile = (*os.File).Sync }()
+	path := filepath.Join(t.TempDir(), "problems.log")
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{
+		Level:       LevelDebug,
+		Color:       ColorAlways,
+		WarningFile: path,
+		WarningSync: true,
+	})
+	defer l.Close()
+	l.Debugf("debug")
+	l.Noticef("notice")
+	l.Warningf("warning")
+	l.ErrorErr(os.ErrNotExist)
+	l.SetLevel(LevelWarning)
+	l.Warningf("still")
+	lines := strings.Split(strings.TrimSpace(readFile(t, path)), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], " # warning") ||
+		!strings.HasSuffix(lines[1], " # file does not exist") ||
+		!strings.HasSuffix(lines[2], " # still") ||
+		strings.Contains(lines[0], "\x1b") {
+		t.Fatalf("unexpected warning file %q", lines)
+	}
+	if syncs != 3 {
+		t.Fatalf("expected 3 syncs, got %d", syncs)
+	}
+	if strings.Count(buf.String(), "\n") != 5 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestWarningFileRotate(t *testing.T) {
+	dir := t.TempDir()
+	l, err := OpenFile(filepath.Join(dir, "app.log"), &Options{
+		Level:       LevelNotice,
+		WarningFile: filepath.Join(dir, "problems.log"),
+		MaxSize:     200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		l.Noticef("notice %d", i)
+	}
+	for i := 0; i < 5; i++ {
+		l.Warningf("warning %d", i)
+	}
+	l.Close()
+	// the warnings are about 250 bytes, and the log about 750
+	names, _ := filepath.Glob(filepath.Join(dir, "problems-*.log"))
+	if len(names) != 1 {
+		t.Fatalf("expected one rotated warning file, got %v", names)
+	}
+	names, _ = filepath.Glob(filepath.Join(dir, "app-*.log"))
+	if len(names) != 3 {
+		t.Fatalf("expected three rotated log files, got %v", names)
+	}
+	if !strings.Contains(readFile(t, filepath.Join(dir, "problems.log")),
+		"warning 4") {
+		t.Fatal("expected the last warning")
+	}
+}
+
+func TestWarningFileError(t *testing.T) {
+	warn := &bytes.Buffer{}
+	stderr = warn
+	defer func() { stderr = os.Stderr }()
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0644)
+	buf := &bytes.Buffer{}
+	l := New(buf, &Options{
+		WarningFile: filepath.Join(dir, "file", "problems.log"),
+	})
+	l.Warningf("one")
+	l.Warningf("two")
+	if strings.Count(warn.String(), "redlog: writing") != 1 {
+		t.Fatalf("expected one report, got %q", warn.String())
+	}
+	if strings.Count(buf.String(), "\n") != 2 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
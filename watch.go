@@ -0,0 +1,225 @@
+package redlog
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WatchConfig applies the settings of a configuration file, and applies
+// them again whenever the file changes, until stop is called or the logger
+// is closed. The parse func converts the contents of the file to options.
+// The file is polled every second, and a change is applied once the file
+// has been the same for two polls, so that a file that is being written is
+// not read half way.
+//
+// The Level, Verbosity, Format, App, Role, ModuleLevels, Rules, and LogFile
+// options are applied, and a notice lists what changed. A nil ModuleLevels
+// or Rules, or an empty LogFile, keeps the current ones. A new LogFile is
+// opened with the file options of the config, and the previous file, if
+// any, is closed once the queued lines are written to it. Other options
+// need a new logger. A file that fails to parse is reported as a warning,
+// keeping the previous settings.
+func (l *Logger) WatchConfig(path string,
+	parse func([]byte) (*Options, error)) (stop func(), err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := validConfig(opts); err != nil {
+		return nil, err
+	}
+	l.applyConfig(opts)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return func() {}, nil
+	}
+	l.owned = append(l.owned, closerFunc(stop))
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		l.watchConfig(path, parse, data, done)
+	}()
+	return stop, nil
+}
+
+// closerFunc is a func that is called by Close.
+type closerFunc func()
+
+func (f closerFunc) Close() error {
+	f()
+	return nil
+}
+
+func (l *Logger) watchConfig(path string,
+	parse func([]byte) (*Options, error), applied []byte,
+	done chan struct{}) {
+	ticker := time.NewTicker(l.watchInterval)
+	defer ticker.Stop()
+	var last []byte   // contents at the previous poll
+	var lastErr error // last reported read error
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if lastErr == nil || err.Error() != lastErr.Error() {
+				l.Warningf("config: %v", err)
+			}
+			lastErr, last = err, nil
+			continue
+		}
+		lastErr = nil
+		settled := bytes.Equal(data, last)
+		last = data
+		if !settled || bytes.Equal(data, applied) {
+			continue
+		}
+		applied = data
+		opts, err := parse(data)
+		if err == nil {
+			err = validConfig(opts)
+		}
+		if err != nil {
+			l.Warningf("config: %s: %v, keeping the previous settings",
+				path, err)
+			continue
+		}
+		l.applyConfig(opts)
+	}
+}
+
+// validConfig checks the options that are applied by WatchConfig.
+func validConfig(opts *Options) error {
+	if opts == nil {
+		return fmt.Errorf("no options")
+	}
+	if opts.Level < LevelTrace || opts.Level > LevelWarning {
+		return fmt.Errorf("invalid level %d", opts.Level)
+	}
+	if !validFormat(opts.Format) {
+		return fmt.Errorf("invalid format %d", opts.Format)
+	}
+	for name, level := range opts.ModuleLevels {
+		if level < LevelTrace || level > LevelWarning {
+			return fmt.Errorf("invalid level %d for module %s", level, name)
+		}
+	}
+	return nil
+}
+
+// applyConfig applies the options that differ from the current settings,
+// and logs a notice listing them.
+func (l *Logger) applyConfig(opts *Options) {
+	var changes []string
+	if level := l.Level(); opts.Level != level {
+		changes = append(changes, fmt.Sprintf("level=%s (was %s)",
+			levelNames[opts.Level-LevelTrace], levelNames[level-LevelTrace]))
+	}
+	if v := l.Verbosity(); opts.Verbosity != v {
+		changes = append(changes, fmt.Sprintf("verbosity=%d (was %d)",
+			opts.Verbosity, v))
+	}
+	if format := l.Format(); opts.Format != format {
+		changes = append(changes, fmt.Sprintf("format=%d (was %d)",
+			opts.Format, format))
+	}
+	if app := l.App(); opts.App != 0 && opts.App != app {
+		changes = append(changes, fmt.Sprintf("app=%c (was %c)", opts.App,
+			app))
+	}
+	if role := l.Role(); opts.Role != "" && sanitizeTag(opts.Role) != role {
+		changes = append(changes, fmt.Sprintf("role=%s (was %s)",
+			sanitizeTag(opts.Role), role))
+	}
+	if opts.ModuleLevels != nil &&
+		!reflect.DeepEqual(opts.ModuleLevels, l.ModuleLevels()) {
+		changes = append(changes, fmt.Sprintf("modules=%s (was %s)",
+			formatModuleLevels(opts.ModuleLevels),
+			formatModuleLevels(l.ModuleLevels())))
+	}
+	if opts.Rules != nil && formatRules(opts.Rules) != formatRules(l.Rules()) {
+		changes = append(changes, fmt.Sprintf("rules=%s (was %s)",
+			formatRules(opts.Rules), formatRules(l.Rules())))
+	}
+	var file *fileWriter
+	if path := l.filePath(); opts.LogFile != "" && opts.LogFile != path {
+		w := newFileWriter(opts.LogFile, opts)
+		if err := w.attach(l); err != nil {
+			l.Warningf("config: %v, keeping the previous log file", err)
+		} else {
+			if path == "" {
+				path = "none"
+			}
+			file = w
+			changes = append(changes, fmt.Sprintf("logfile=%s (was %s)",
+				opts.LogFile, path))
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	// logged before the changes, which may disable notices
+	l.Noticef("config: %s", strings.Join(changes, ", "))
+	if file != nil {
+		l.setFile(file)
+	}
+	// only the changes, as SetLevel ends a SetLevelFor window
+	if opts.Level != l.Level() {
+		l.SetLevel(opts.Level)
+	}
+	if opts.Verbosity != l.Verbosity() {
+		l.SetVerbosity(opts.Verbosity)
+	}
+	if opts.Format != l.Format() {
+		l.SetFormat(opts.Format)
+	}
+	if opts.App != 0 {
+		l.SetApp(opts.App)
+	}
+	if opts.Role != "" {
+		l.SetRole(opts.Role)
+	}
+	if opts.ModuleLevels != nil {
+		l.SetModuleLevels(opts.ModuleLevels)
+	}
+	if opts.Rules != nil {
+		l.SetRules(opts.Rules)
+	}
+}
+
+// formatModuleLevels formats module levels as ParseModuleLevels parses
+// them, in the order of the names.
+func formatModuleLevels(levels map[string]int) string {
+	names := make([]string, 0, len(levels))
+	for name := range levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = name + "=" + levelNames[levels[name]-LevelTrace]
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,197 @@
+package redlog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+// lockedBuffer is a buffer that is safe to read while logging.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func parseTestConfig(data []byte) (*Options, error) {
+	var c struct {
+		Level   string
+		Format  string
+		Role    string
+		LogFile string
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	level, err := parseLevel(c.Level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &Options{Level: level, Role: c.Role, LogFile: c.LogFile}
+	if c.Format == "plain" {
+		opts.Format = FormatPlain
+	}
+	return opts, nil
+}
+
+func TestWatchConfig(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	path := filepath.Join(t.TempDir(), "log.json")
+	write := func(s string) {
+		if err := ioutil.WriteFile(path, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		for i := 0; !cond(); i++ {
+			if i == 200 {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	buf := &lockedBuffer{}
+	l := New(buf, nil)
+	l.watchInterval = 5 * time.Millisecond
+	defer l.Close()
+
+	if _, err := l.WatchConfig(path, parseTestConfig); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+	write(`{"level":"debug"}`)
+	stop, err := l.WatchConfig(path, parseTestConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Level() != LevelDebug || !strings.Contains(buf.String(),
+		" * config: level=debug (was notice)\n") {
+		t.Fatalf("unexpected state %d %q", l.Level(), buf.String())
+	}
+
+	// rapid changes are debounced
+	write(`{"level":"trace"}`)
+	write(`{"level":"verbose"}`)
+	write(`{"level":"warning","role":"leader"}`)
+	waitFor("warning", func() bool { return l.Level() == LevelWarning })
+	if l.Role() != "leader" || strings.Count(buf.String(), "config:") != 2 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	// a broken config is reported and ignored
+	write(`{"level":`)
+	waitFor("the warning", func() bool {
+		return strings.Contains(buf.String(), "keeping the previous settings")
+	})
+	if l.Level() != LevelWarning {
+		t.Fatalf("unexpected level %d", l.Level())
+	}
+	write(`{"level":"loud"}`)
+	waitFor("the warning", func() bool {
+		return strings.Count(buf.String(), "keeping the previous") == 2
+	})
+	write(`{"level":"notice","format":"plain"}`)
+	waitFor("notice", func() bool { return l.Level() == LevelNotice })
+	if l.Format() != FormatPlain {
+		t.Fatal("expected the plain format")
+	}
+	stop()
+	stop()
+	write(`{"level":"debug"}`)
+	time.Sleep(20 * time.Millisecond)
+	if l.Level() != LevelNotice {
+		t.Fatal("expected no changes after stop")
+	}
+}
+
+func TestWatchConfigLogFile(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.json")
+	write := func(s string) {
+		if err := ioutil.WriteFile(path, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		for i := 0; !cond(); i++ {
+			if i == 200 {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	buf := &lockedBuffer{}
+	l := New(buf, &Options{Level: LevelNotice, Format: FormatPlain})
+	l.watchInterval = 5 * time.Millisecond
+	defer l.Close()
+
+	write(`{"level":"notice","format":"plain","logfile":"` +
+		filepath.Join(dir, "a.log") + `"}`)
+	stop, err := l.WatchConfig(path, parseTestConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	l.Noticef("to a")
+	if !strings.Contains(buf.String(), "config: logfile=") ||
+		read("a.log") != "to a\n" {
+		t.Fatalf("unexpected output %q %q", buf.String(), read("a.log"))
+	}
+
+	// a file that cannot be opened keeps the previous one
+	write(`{"level":"notice","format":"plain","logfile":"` +
+		filepath.Join(dir, "a.log", "x.log") + `"}`)
+	waitFor("the warning", func() bool {
+		return strings.Contains(read("a.log"), "keeping the previous log")
+	})
+
+	write(`{"level":"notice","format":"plain","logfile":"` +
+		filepath.Join(dir, "b.log") + `"}`)
+	waitFor("b.log", func() bool {
+		return filepath.Base(l.filePath()) == "b.log"
+	})
+	l.Noticef("to b")
+	if !strings.Contains(read("a.log"), "config: logfile=") ||
+		read("b.log") != "to b\n" {
+		t.Fatalf("unexpected files %q %q", read("a.log"), read("b.log"))
+	}
+}
+
+func TestApplyConfigUnchangedLevel(t *testing.T) {
+	// a change of the role keeps the window of SetLevelFor
+	l := New(ioutil.Discard, &Options{Level: LevelNotice})
+	l.SetLevelFor(LevelDebug, time.Hour, "")
+	defer l.SetLevel(LevelNotice)
+	n := len(l.LevelHistory())
+	l.applyConfig(&Options{Level: LevelDebug, Role: "leader"})
+	l.lvmu.Lock()
+	pending := l.revert != nil
+	l.lvmu.Unlock()
+	if l.Role() != "leader" || !pending || len(l.LevelHistory()) != n {
+		t.Fatalf("unexpected state %q %v %+v", l.Role(), pending,
+			l.LevelHistory())
+	}
+}